@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"pod-limit-checker/pkg/webhook"
+)
+
+// runAdmissionPolicy implements "pod-limit-checker admission-policy": it
+// prints the webhook's rules as ValidatingAdmissionPolicies with CEL
+// expressions, for clusters that do not allow admission webhooks.
+func runAdmissionPolicy(args []string) error {
+	fs := flag.NewFlagSet("admission-policy", flag.ExitOnError)
+	denyRisk := fs.String("deny-risk", "HIGH", "deny objects failing rules at or above this risk: LOW, MEDIUM, HIGH or NONE")
+	warnRisk := fs.String("warn-risk", "MEDIUM", "warn about objects failing rules at or above this risk: LOW, MEDIUM, HIGH or NONE")
+	ratioFlags(fs, &ratioPolicy)
+	boundsFlags(fs, &bounds)
+	selector := fs.String("namespace-selector", "pod-limit-checker.io/enforce=true", "bind the policies to namespaces with this key=value label (empty binds all namespaces)")
+	apiVersion := fs.String("api-version", "v1", "admissionregistration.k8s.io version: v1 (Kubernetes 1.30+) or v1beta1")
+	output := fs.String("output", "", "file to write the manifests to (default: stdout)")
+	fs.Parse(args)
+
+	if err := validateRatio(ratioPolicy); err != nil {
+		return err
+	}
+	if err := validateBounds(bounds); err != nil {
+		return err
+	}
+
+	rules := webhook.PolicyRules{Ratio: ratioPolicy, Bounds: bounds}
+	var err error
+	if rules.DenyRisk, err = webhook.ParseRisk(*denyRisk); err != nil {
+		return fmt.Errorf("invalid --deny-risk: %v", err)
+	}
+	if rules.WarnRisk, err = webhook.ParseRisk(*warnRisk); err != nil {
+		return fmt.Errorf("invalid --warn-risk: %v", err)
+	}
+	if *selector != "" {
+		key, value, ok := strings.Cut(*selector, "=")
+		if !ok || key == "" {
+			return fmt.Errorf("invalid --namespace-selector %q, expected key=value", *selector)
+		}
+		rules.NamespaceLabels = map[string]string{key: value}
+	}
+
+	manifests, err := webhook.GenerateAdmissionPolicies(rules, *apiVersion)
+	if err != nil {
+		return err
+	}
+	if *output == "" {
+		_, err = os.Stdout.Write(manifests)
+		return err
+	}
+	if err := os.WriteFile(*output, manifests, 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %v", *output, err)
+	}
+	return nil
+}
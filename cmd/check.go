@@ -4,38 +4,141 @@ import (
 	"context"
 	"flag"
 	"fmt"
+	"io"
 	"os"
+	"path/filepath"
 	"time"
 
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+
 	"pod-limit-checker/pkg/analyzer"
+	"pod-limit-checker/pkg/cost"
+	"pod-limit-checker/pkg/helm"
 	"pod-limit-checker/pkg/kubernetes"
+	"pod-limit-checker/pkg/kustomize"
 	"pod-limit-checker/pkg/reporter"
 )
 
+// Version is the tool version, overridden at build time via
+// -ldflags "-X pod-limit-checker/cmd.Version=...".
+var Version = "dev"
+
 var (
-	kubeconfig string
-	output     string
-	threshold  float64
-	showAll    bool
-	namespace  string
-	verbose    bool
-	noExamples bool
-	quiet      bool
+	kubeconfig  string
+	outputs     outputFlag
+	threshold   float64
+	showAll     bool
+	namespace   string
+	verbose     bool
+	noExamples  bool
+	quiet       bool
+	printSchema bool
+	rollup      bool
+	ownerLabel  string
+	rollupSort  string
+	costEst     bool
+	priceFile   string
+	showNodes   bool
+	overcommit  float64
+	qosPolicies qosPolicyFlag
+	ephemeral   bool
+	ratioPolicy analyzer.RatioPolicy
+	bounds      analyzer.BoundsPolicy
+	reqPolicy   = analyzer.DefaultRequestPolicy
+	compareVPA  bool
+	generateVPA string
+	checkHPA    bool
+	helmValues  string
+	writeCRs    bool
+	events      bool
+	// --kustomize-overlay and its base
+	kustomizeOverlay string
+	kustomizeBase    string
+	// --notify and its options
+	notifyTargets  notifyFlag
+	notifyTemplate string
+	notifyBaseline string
+	notifyTop      int
+	notifyRate     float64
 )
 
 func Execute() error {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "simulate":
+			return runSimulate(os.Args[2:])
+		case "resize":
+			return runResize(os.Args[2:])
+		case "remediate":
+			return runRemediate(os.Args[2:])
+		case "webhook":
+			return runWebhook(os.Args[2:])
+		case "admission-policy":
+			return runAdmissionPolicy(os.Args[2:])
+		}
+	}
+
 	flag.StringVar(&kubeconfig, "kubeconfig", "", "absolute path to the kubeconfig file")
-	flag.StringVar(&output, "output", "table", "output format: table, json, yaml")
+	flag.Var(&outputs, "output", "output format: table, json, yaml, html; use format=path to write to a file (repeatable, at most one to stdout)")
 	flag.Float64Var(&threshold, "threshold", 0.8, "usage threshold for suggestions (0.0-1.0)")
 	flag.BoolVar(&showAll, "all", false, "show all pods including those with limits")
 	flag.StringVar(&namespace, "namespace", "", "specific namespace to check (default: all namespaces)")
 	flag.BoolVar(&verbose, "verbose", false, "show all suggestions in table output")
 	flag.BoolVar(&noExamples, "no-examples", false, "don't show example YAML fixes")
 	flag.BoolVar(&quiet, "quiet", false, "suppress informational output (useful for JSON/YAML)") // New flag
+	flag.BoolVar(&rollup, "rollup", false, "show totals grouped by namespace and by owner label")
+	flag.StringVar(&ownerLabel, "owner-label", "team", "pod label identifying the owning team for --rollup")
+	flag.StringVar(&rollupSort, "rollup-sort", reporter.RollupSortWorst, "rollup row order: worst (lowest compliance first) or name")
+	flag.BoolVar(&costEst, "cost-estimate", false, "estimate monthly cost of current vs recommended requests (requires --price-file)")
+	flag.StringVar(&priceFile, "price-file", "", "YAML price table used by --cost-estimate (see config/pricing)")
+	flag.BoolVar(&showNodes, "nodes", false, "show node-level overcommit against allocatable")
+	flag.Float64Var(&overcommit, "overcommit-ratio", 1.5, "flag nodes whose summed limits exceed this multiple of allocatable")
+	policyFlags(flag.CommandLine)
+	flag.BoolVar(&ephemeral, "ephemeral-storage", false, "check ephemeral-storage requests, limits and emptyDir sizeLimits, with usage from the kubelet stats summary")
+	flag.BoolVar(&compareVPA, "vpa", false, "compare with existing VerticalPodAutoscaler recommendations")
+	flag.StringVar(&generateVPA, "generate-vpa", "", "write VerticalPodAutoscalers in Off mode for workloads without one to this file (implies --vpa)")
+	flag.BoolVar(&checkHPA, "hpa", false, "check HorizontalPodAutoscalers for missing requests and scaling changes caused by the recommendations")
+	flag.StringVar(&helmValues, "helm-values", "", "write a values override with the recommendations per Helm release to this directory")
+	flag.StringVar(&kustomizeOverlay, "kustomize-overlay", "", "write a kustomize overlay patching the recommendations onto --kustomize-base to this directory")
+	flag.StringVar(&kustomizeBase, "kustomize-base", "../base", "path of the kustomize base, relative to the --kustomize-overlay directory")
+	flag.BoolVar(&writeCRs, "write-reports", false, "write results to PodLimitReport custom resources (see k8s/crd.yaml)")
+	flag.BoolVar(&events, "events", false, "record a Warning Event on each workload with HIGH or MEDIUM risk containers, skipping unexpired duplicates")
+	flag.Var(&notifyTargets, "notify", "post a digest of the run as slack=URL, teams=URL or webhook=URL (repeatable)")
+	flag.StringVar(&notifyTemplate, "notify-template", "", "Go template file for the --notify message (default: built-in digest)")
+	flag.StringVar(&notifyBaseline, "notify-baseline", "", "JSON or YAML report of the previous run; findings not in it are reported as new")
+	flag.IntVar(&notifyTop, "notify-top", 10, "number of new and worst findings listed in notifications (0 lists all)")
+	flag.Float64Var(&notifyRate, "notify-rate", 1, "maximum posts per second to one notification URL")
+	flag.BoolVar(&printSchema, "print-schema", false, "print the JSON Schema for json/yaml output and exit")
 	flag.Parse()
 
+	if rollupSort != reporter.RollupSortWorst && rollupSort != reporter.RollupSortName {
+		return fmt.Errorf("invalid --rollup-sort %q (valid: worst, name)", rollupSort)
+	}
+
+	if err := validatePolicies(); err != nil {
+		return err
+	}
+
+	if notifyRate <= 0 {
+		return fmt.Errorf("--notify-rate must be positive")
+	}
+
+	if costEst && priceFile == "" {
+		return fmt.Errorf("--cost-estimate requires --price-file")
+	}
+
+	if printSchema {
+		schema, err := reporter.JSONSchema()
+		if err != nil {
+			return fmt.Errorf("failed to generate schema: %v", err)
+		}
+		fmt.Println(string(schema))
+		return nil
+	}
+
 	// Determine if we should be quiet
-	shouldBeQuiet := quiet || output == "json" || output == "yaml"
+	shouldBeQuiet := quiet || outputs.machineReadableStdout()
 
 	// Initialize Kubernetes client
 	client, err := kubernetes.NewClient(kubeconfig, shouldBeQuiet) // Pass quiet flag
@@ -71,22 +174,320 @@ func Execute() error {
 		}
 	}
 
+	if err := applyPolicies(ctx, podAnalyzer); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if ephemeral {
+		stats, err := podAnalyzer.GetStorageStats(ctx, nodeNames(pods))
+		if err != nil && !shouldBeQuiet {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+			fmt.Fprintln(os.Stderr, "Continuing without ephemeral-storage usage for those nodes...")
+		}
+		podAnalyzer.EnableEphemeralStorageChecks(stats)
+	}
+
+	if compareVPA || generateVPA != "" {
+		vpas, err := podAnalyzer.GetVPAs(ctx, namespace)
+		if err != nil && generateVPA != "" && !apierrors.IsNotFound(err) {
+			// Without the existing VPAs we would generate duplicates
+			fmt.Fprintf(os.Stderr, "Error: failed to list VerticalPodAutoscalers: %v\n", err)
+			os.Exit(1)
+		}
+		if err != nil {
+			if !shouldBeQuiet {
+				fmt.Fprintf(os.Stderr, "Warning: Could not list VerticalPodAutoscalers: %v\n", err)
+				fmt.Fprintln(os.Stderr, "Continuing without VPA comparison...")
+			}
+		} else {
+			podAnalyzer.SetVPAs(vpas)
+		}
+	}
+
+	if checkHPA {
+		hpas, err := podAnalyzer.GetHPAs(ctx, namespace)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: failed to list HorizontalPodAutoscalers: %v\n", err)
+			os.Exit(1)
+		}
+		podAnalyzer.SetHPAs(hpas)
+	}
+
+	if analyzer.UsesSpecialResources(pods) {
+		nodes, err := podAnalyzer.GetNodes(ctx)
+		if err != nil {
+			if !shouldBeQuiet {
+				fmt.Fprintf(os.Stderr, "Warning: Could not list nodes: %v\n", err)
+				fmt.Fprintln(os.Stderr, "Continuing without node capacity checks for extended resources...")
+			}
+		} else {
+			podAnalyzer.SetNodeCapacity(nodes)
+		}
+	}
+
+	// The baseline may be one of this run's outputs, so it is read first
+	baseline, err := loadBaseline(shouldBeQuiet)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
 	// Analyze pods and generate suggestions
 	results := podAnalyzer.AnalyzePods(pods, podMetrics, threshold)
 
-	// Create reporter and generate output
-	rep := reporter.NewReporter(output)
+	if generateVPA != "" {
+		manifests, err := podAnalyzer.GenerateVPAs(results)
+		if err == nil {
+			err = os.WriteFile(generateVPA, manifests, 0o644)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: failed to write VerticalPodAutoscalers: %v\n", err)
+			os.Exit(1)
+		}
+		if !shouldBeQuiet {
+			fmt.Fprintf(os.Stderr, "Wrote VerticalPodAutoscalers to %s\n", generateVPA)
+		}
+	}
+
+	if helmValues != "" {
+		if err := writeHelmValues(ctx, client, results, shouldBeQuiet); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: failed to write Helm values: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	if kustomizeOverlay != "" {
+		if err := writeKustomizeOverlay(results, shouldBeQuiet); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: failed to write kustomize overlay: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	var costEstimate *cost.Estimate
+	if costEst {
+		costEstimate, err = estimateCost(ctx, podAnalyzer, results, shouldBeQuiet)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	var nodeAnalysis []analyzer.NodeAnalysis
+	if showNodes {
+		nodeAnalysis, err = analyzeNodes(ctx, podAnalyzer, pods, shouldBeQuiet)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: failed to analyze nodes: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	// Render the single analysis into every requested sink
+	metadata := reporter.RunMetadata{
+		Timestamp:   time.Now().UTC(),
+		Cluster:     client.Host,
+		ToolVersion: Version,
+		Flags:       flagValues(),
+	}
+	for _, spec := range outputs.specs() {
+		if err := writeReport(spec, results, metadata, costEstimate, nodeAnalysis, shouldBeQuiet); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: failed to generate %s report: %v\n", spec.format, err)
+			os.Exit(1)
+		}
+	}
+
+	if writeCRs {
+		// Writes get their own deadline; one object per namespace can take a
+		// while on large clusters
+		writeCtx, writeCancel := context.WithTimeout(context.Background(), 2*time.Minute)
+		defer writeCancel()
+		rep := reporter.NewReporter("", io.Discard)
+		rep.SetMetadata(metadata)
+		if err := rep.WriteCustomResources(writeCtx, client.DynamicClient, results, namespace, showAll); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if !shouldBeQuiet {
+			fmt.Fprintln(os.Stderr, "Wrote PodLimitReport custom resources")
+		}
+	}
+
+	if events {
+		eventCtx, eventCancel := context.WithTimeout(context.Background(), 2*time.Minute)
+		defer eventCancel()
+		recorded, err := reporter.RecordEvents(eventCtx, client.Clientset, results)
+		if err != nil {
+			// Missed Events are recorded on the next run
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		}
+		if !shouldBeQuiet {
+			fmt.Fprintf(os.Stderr, "Recorded %d Events\n", recorded)
+		}
+	}
+
+	if len(notifyTargets) > 0 {
+		rep := reporter.NewReporter("", io.Discard)
+		rep.SetMetadata(metadata)
+		if err := sendNotifications(rep.BuildReport(results, showAll), baseline); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if !shouldBeQuiet {
+			fmt.Fprintf(os.Stderr, "Sent notifications to %s\n", notifyTargets.String())
+		}
+	}
+
+	return nil
+}
+
+// estimateCost prices the analysis with the configured price table. Node
+// labels select per-pool rates; without them every node gets the default.
+func estimateCost(ctx context.Context, podAnalyzer *analyzer.PodAnalyzer, results []analyzer.PodAnalysis, shouldBeQuiet bool) (*cost.Estimate, error) {
+	table, err := cost.LoadPriceTable(priceFile)
+	if err != nil {
+		return nil, err
+	}
+
+	nodes, err := podAnalyzer.GetNodes(ctx)
+	if err != nil && !shouldBeQuiet {
+		fmt.Fprintf(os.Stderr, "Warning: Could not list nodes: %v\n", err)
+		fmt.Fprintln(os.Stderr, "Pricing all nodes at the default rate...")
+	}
+
+	return cost.EstimateCost(results, nodes, table), nil
+}
+
+// writeHelmValues writes a values override per Helm release with
+// recommendations into the --helm-values directory.
+func writeHelmValues(ctx context.Context, client *kubernetes.Client, results []analyzer.PodAnalysis, shouldBeQuiet bool) error {
+	workloads, err := helm.FindWorkloads(ctx, client.Clientset, namespace)
+	if err != nil {
+		return err
+	}
+	files, err := helm.GenerateValues(results, workloads)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(helmValues, 0o755); err != nil {
+		return err
+	}
+	for _, file := range files {
+		if err := os.WriteFile(filepath.Join(helmValues, file.Name), file.Content, 0o644); err != nil {
+			return err
+		}
+	}
+	if !shouldBeQuiet {
+		fmt.Fprintf(os.Stderr, "Wrote values for %d Helm release(s) to %s\n", len(files), helmValues)
+	}
+	return nil
+}
+
+// writeKustomizeOverlay writes a kustomization.yaml and a patch per
+// workload with recommendations into the --kustomize-overlay directory.
+func writeKustomizeOverlay(results []analyzer.PodAnalysis, shouldBeQuiet bool) error {
+	files, err := kustomize.GenerateOverlay(results, kustomizeBase)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(kustomizeOverlay, 0o755); err != nil {
+		return err
+	}
+	for _, file := range files {
+		if err := os.WriteFile(filepath.Join(kustomizeOverlay, file.Name), file.Content, 0o644); err != nil {
+			return err
+		}
+	}
+	if !shouldBeQuiet {
+		fmt.Fprintf(os.Stderr, "Wrote kustomize overlay with %d patch(es) to %s\n", len(files)-1, kustomizeOverlay)
+	}
+	return nil
+}
+
+// analyzeNodes builds the node overcommit view. Node totals need every pod,
+// so pods are listed again across all namespaces when --namespace is set.
+func analyzeNodes(ctx context.Context, podAnalyzer *analyzer.PodAnalyzer, pods []v1.Pod, shouldBeQuiet bool) ([]analyzer.NodeAnalysis, error) {
+	nodes, err := podAnalyzer.GetNodes(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if namespace != "" {
+		pods, err = podAnalyzer.GetPodsWithoutLimits(ctx, "")
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	nodeMetrics, err := podAnalyzer.GetNodeMetrics(ctx)
+	if err != nil && !shouldBeQuiet {
+		fmt.Fprintf(os.Stderr, "Warning: Could not fetch node metrics: %v\n", err)
+	}
+
+	return podAnalyzer.AnalyzeNodes(nodes, pods, nodeMetrics, overcommit), nil
+}
+
+// writeReport renders results in the sink's format to stdout or to its file.
+func writeReport(spec outputSpec, results []analyzer.PodAnalysis, metadata reporter.RunMetadata, costEstimate *cost.Estimate, nodeAnalysis []analyzer.NodeAnalysis, shouldBeQuiet bool) (err error) {
+	out := io.Writer(os.Stdout)
+	if spec.path != "" {
+		f, err := os.Create(spec.path)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			if cerr := f.Close(); err == nil {
+				err = cerr
+			}
+		}()
+		out = f
+	}
+
+	rep := reporter.NewReporter(spec.format, out)
 	rep.SetVerbose(verbose)
 	rep.SetShowExamples(!noExamples)
 	rep.SetQuiet(shouldBeQuiet)
+	rep.SetMetadata(metadata)
+	if rollup {
+		rep.SetRollup(ownerLabel, rollupSort)
+	}
+	if costEstimate != nil {
+		rep.SetCostEstimate(costEstimate)
+	}
+	if nodeAnalysis != nil {
+		rep.SetNodeAnalysis(nodeAnalysis)
+	}
 	if err := rep.GenerateReport(results, showAll); err != nil {
-		fmt.Fprintf(os.Stderr, "Error: failed to generate report: %v\n", err)
-		os.Exit(1)
+		return err
 	}
 
+	if spec.path != "" && !shouldBeQuiet {
+		fmt.Fprintf(os.Stderr, "Wrote %s report to %s\n", spec.format, spec.path)
+	}
 	return nil
 }
 
+// nodeNames returns the distinct nodes the pods are scheduled on.
+func nodeNames(pods []v1.Pod) []string {
+	seen := map[string]bool{}
+	var names []string
+	for _, pod := range pods {
+		if pod.Spec.NodeName != "" && !seen[pod.Spec.NodeName] {
+			seen[pod.Spec.NodeName] = true
+			names = append(names, pod.Spec.NodeName)
+		}
+	}
+	return names
+}
+
+// flagValues returns the effective value of every command-line flag.
+func flagValues() map[string]string {
+	values := make(map[string]string)
+	flag.VisitAll(func(f *flag.Flag) {
+		values[f.Name] = f.Value.String()
+	})
+	return values
+}
+
 func homeDir() string {
 	if h := os.Getenv("HOME"); h != "" {
 		return h
@@ -0,0 +1,73 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"pod-limit-checker/pkg/notify"
+	"pod-limit-checker/pkg/reporter"
+)
+
+// notifyFlag collects repeated --notify flags of the form "kind=url".
+type notifyFlag []notify.Target
+
+func (n *notifyFlag) String() string {
+	if n == nil {
+		return ""
+	}
+	// URLs of chat webhooks are credentials, so only the kinds are shown,
+	// also in the run metadata of reports
+	kinds := make([]string, 0, len(*n))
+	for _, target := range *n {
+		kinds = append(kinds, target.Kind)
+	}
+	return strings.Join(kinds, ",")
+}
+
+func (n *notifyFlag) Set(value string) error {
+	kind, url, ok := strings.Cut(value, "=")
+	kind = strings.ToLower(strings.TrimSpace(kind))
+	if kind != "slack" && kind != "teams" && kind != "webhook" {
+		return fmt.Errorf("unknown notifier %q (valid: slack, teams, webhook)", kind)
+	}
+	if !ok || !strings.HasPrefix(url, "https://") && !strings.HasPrefix(url, "http://") {
+		return fmt.Errorf("expected %s=URL with an http or https URL", kind)
+	}
+	*n = append(*n, notify.Target{Kind: kind, URL: url})
+	return nil
+}
+
+// loadBaseline reads the report --notify-baseline points to. It is read
+// before the outputs are written, so the baseline can be a report this run
+// overwrites. A missing file, as on the first run, means no baseline.
+func loadBaseline(shouldBeQuiet bool) (*reporter.Report, error) {
+	if notifyBaseline == "" {
+		return nil, nil
+	}
+	if _, err := os.Stat(notifyBaseline); os.IsNotExist(err) {
+		if !shouldBeQuiet {
+			fmt.Fprintf(os.Stderr, "Warning: baseline %s does not exist yet, no findings are reported as new\n", notifyBaseline)
+		}
+		return nil, nil
+	}
+	return reporter.LoadReport(notifyBaseline)
+}
+
+// sendNotifications posts the digest of report to every --notify target.
+func sendNotifications(report reporter.Report, baseline *reporter.Report) error {
+	tmpl, err := notify.ParseTemplate(notifyTemplate)
+	if err != nil {
+		return err
+	}
+	notifiers, err := notify.New(notifyTargets, notify.Options{Template: tmpl, Rate: float32(notifyRate)})
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+	return notify.NotifyAll(ctx, notifiers, notify.BuildDigest(report, baseline, notifyTop))
+}
@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+)
+
+var validFormats = map[string]bool{
+	"table": true,
+	"json":  true,
+	"yaml":  true,
+	"html":  true,
+}
+
+// outputSpec is a single report sink: a format and an optional file path.
+// An empty path means stdout.
+type outputSpec struct {
+	format string
+	path   string
+}
+
+// outputFlag collects repeated --output flags of the form "format" or
+// "format=path".
+type outputFlag []outputSpec
+
+func (o *outputFlag) String() string {
+	if o == nil || len(*o) == 0 {
+		return "table"
+	}
+	parts := make([]string, 0, len(*o))
+	for _, spec := range *o {
+		if spec.path == "" {
+			parts = append(parts, spec.format)
+		} else {
+			parts = append(parts, spec.format+"="+spec.path)
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+func (o *outputFlag) Set(value string) error {
+	format, path, hasPath := strings.Cut(value, "=")
+	format = strings.ToLower(strings.TrimSpace(format))
+	if !validFormats[format] {
+		return fmt.Errorf("unknown output format %q (valid: table, json, yaml, html)", format)
+	}
+	if hasPath && path == "" {
+		return fmt.Errorf("empty path for output format %q", format)
+	}
+	// Two reports on stdout would interleave into something no parser reads
+	if !hasPath {
+		for _, spec := range *o {
+			if spec.path == "" {
+				return fmt.Errorf("only one output can go to stdout, already %q; use %s=path", spec.format, format)
+			}
+		}
+	}
+	*o = append(*o, outputSpec{format: format, path: path})
+	return nil
+}
+
+// specs returns the configured sinks, defaulting to a table on stdout.
+func (o outputFlag) specs() []outputSpec {
+	if len(o) == 0 {
+		return []outputSpec{{format: "table"}}
+	}
+	return o
+}
+
+// machineReadableStdout reports whether any sink writes a non-table format
+// to stdout, in which case informational messages must stay off stdout.
+func (o outputFlag) machineReadableStdout() bool {
+	for _, spec := range o.specs() {
+		if spec.path == "" && spec.format != "table" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestOutputFlag(t *testing.T) {
+	tests := []struct {
+		name   string
+		values []string
+		want   string
+		err    string
+	}{
+		{name: "default", want: "table"},
+		{name: "one stdout sink", values: []string{"JSON"}, want: "json"},
+		{name: "files and stdout", values: []string{"table", "json=/tmp/r.json", "html=/tmp/r.html"}, want: "table,json=/tmp/r.json,html=/tmp/r.html"},
+		{name: "two stdout sinks", values: []string{"table", "json"}, err: `only one output can go to stdout, already "table"; use json=path`},
+		{name: "unknown format", values: []string{"xml"}, err: `unknown output format "xml"`},
+		{name: "empty path", values: []string{"json="}, err: `empty path for output format "json"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var o outputFlag
+			var err error
+			for _, value := range tt.values {
+				if err = o.Set(value); err != nil {
+					break
+				}
+			}
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("error = %v, want %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := o.String(); got != tt.want {
+				t.Errorf("outputs = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMachineReadableStdout(t *testing.T) {
+	for _, tt := range []struct {
+		values []string
+		want   bool
+	}{
+		{nil, false},
+		{[]string{"table", "json=/tmp/r.json"}, false},
+		{[]string{"yaml"}, true},
+		{[]string{"html=/tmp/r.html", "json"}, true},
+	} {
+		var o outputFlag
+		for _, value := range tt.values {
+			if err := o.Set(value); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if got := o.machineReadableStdout(); got != tt.want {
+			t.Errorf("%v: machineReadableStdout = %v, want %v", tt.values, got, tt.want)
+		}
+	}
+}
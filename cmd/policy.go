@@ -0,0 +1,115 @@
+package cmd
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	"pod-limit-checker/pkg/analyzer"
+)
+
+// qosPolicyFlag collects repeated --require-qos flags.
+type qosPolicyFlag []analyzer.QoSPolicy
+
+func (q *qosPolicyFlag) String() string {
+	if q == nil {
+		return ""
+	}
+	parts := make([]string, 0, len(*q))
+	for _, policy := range *q {
+		parts = append(parts, policy.String())
+	}
+	return strings.Join(parts, ",")
+}
+
+func (q *qosPolicyFlag) Set(value string) error {
+	policy, err := analyzer.ParseQoSPolicy(value)
+	if err != nil {
+		return err
+	}
+	*q = append(*q, policy)
+	return nil
+}
+
+// policyFlags registers the flags of every policy the recommendations
+// follow, so check, resize and simulate use the same values.
+func policyFlags(fs *flag.FlagSet) {
+	fs.Var(&qosPolicies, "require-qos", "require a minimum pod QoS class in labelled namespaces, as key=value:Class (repeatable)")
+	ratioFlags(fs, &ratioPolicy)
+	boundsFlags(fs, &bounds)
+	fs.Float64Var(&reqPolicy.Underused, "request-underused", reqPolicy.Underused, "flag requests whose usage is below this fraction as oversized (0 disables)")
+	fs.Float64Var(&reqPolicy.Overused, "request-overused", reqPolicy.Overused, "flag requests whose usage is above this fraction as undersized (0 disables)")
+}
+
+// validatePolicies checks the values given to the policyFlags.
+func validatePolicies() error {
+	if err := validateRatio(ratioPolicy); err != nil {
+		return err
+	}
+	return validateBounds(bounds)
+}
+
+// applyPolicies configures podAnalyzer with the policyFlags, reading the
+// namespace labels when a QoS policy needs them.
+func applyPolicies(ctx context.Context, podAnalyzer *analyzer.PodAnalyzer) error {
+	if len(qosPolicies) > 0 {
+		namespaceLabels, err := podAnalyzer.GetNamespaceLabels(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to get namespaces for QoS policies: %v", err)
+		}
+		podAnalyzer.SetQoSPolicies(qosPolicies, namespaceLabels)
+	}
+	podAnalyzer.SetRatioPolicy(ratioPolicy)
+	podAnalyzer.SetBoundsPolicy(bounds)
+	podAnalyzer.SetRequestPolicy(reqPolicy)
+	return nil
+}
+
+// ratioFlags registers --max-cpu-ratio and --max-memory-ratio on fs,
+// filling policy.
+func ratioFlags(fs *flag.FlagSet, policy *analyzer.RatioPolicy) {
+	fs.Float64Var(&policy.MaxCPU, "max-cpu-ratio", 0, "maximum CPU limit-to-request ratio, e.g. 4 (0 disables the check)")
+	fs.Float64Var(&policy.MaxMemory, "max-memory-ratio", 0, "maximum memory limit-to-request ratio, 1 requires limit = request (0 disables the check)")
+}
+
+// validateRatio rejects ratios below 1, which no limit can satisfy.
+func validateRatio(policy analyzer.RatioPolicy) error {
+	if policy.MaxCPU != 0 && policy.MaxCPU < 1 || policy.MaxMemory != 0 && policy.MaxMemory < 1 {
+		return fmt.Errorf("--max-cpu-ratio and --max-memory-ratio must be 0 or at least 1")
+	}
+	return nil
+}
+
+// boundsFlags registers --min-cpu, --max-cpu, --min-memory and --max-memory
+// on fs, filling policy.
+func boundsFlags(fs *flag.FlagSet, policy *analyzer.BoundsPolicy) {
+	policy.Min, policy.Max = v1.ResourceList{}, v1.ResourceList{}
+	bound := func(flagName string, list v1.ResourceList, name v1.ResourceName, usage string) {
+		fs.Func(flagName, usage, func(value string) error {
+			quantity, err := resource.ParseQuantity(value)
+			if err != nil {
+				return err
+			}
+			list[name] = quantity
+			return nil
+		})
+	}
+	bound("min-cpu", policy.Min, v1.ResourceCPU, "minimum CPU request and limit per container, e.g. 50m")
+	bound("max-cpu", policy.Max, v1.ResourceCPU, "maximum CPU request and limit per container, e.g. 4")
+	bound("min-memory", policy.Min, v1.ResourceMemory, "minimum memory request and limit per container, e.g. 64Mi")
+	bound("max-memory", policy.Max, v1.ResourceMemory, "maximum memory request and limit per container, e.g. 8Gi")
+}
+
+// validateBounds rejects a minimum above its maximum.
+func validateBounds(policy analyzer.BoundsPolicy) error {
+	for name, min := range policy.Min {
+		if max, ok := policy.Max[name]; ok && min.Cmp(max) > 0 {
+			return fmt.Errorf("--min-%s must not exceed --max-%s", name, name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+package cmd
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"pod-limit-checker/pkg/analyzer"
+	"pod-limit-checker/pkg/gitops"
+	"pod-limit-checker/pkg/helm"
+	"pod-limit-checker/pkg/kubernetes"
+	"pod-limit-checker/pkg/reporter"
+)
+
+// runRemediate implements "pod-limit-checker remediate": it writes the
+// recommendations into the workload manifests of a GitOps checkout and,
+// with --commit, commits them on a new branch for review.
+func runRemediate(args []string) error {
+	fs := flag.NewFlagSet("remediate", flag.ExitOnError)
+	fs.StringVar(&kubeconfig, "kubeconfig", "", "absolute path to the kubeconfig file")
+	fs.StringVar(&namespace, "namespace", "", "specific namespace to remediate (default: all namespaces)")
+	fs.Float64Var(&threshold, "threshold", 0.8, "usage threshold for suggestions (0.0-1.0)")
+	fs.BoolVar(&quiet, "quiet", false, "suppress informational output")
+	repo := fs.String("repo", "", "path of the local Git checkout holding the manifests (required)")
+	commit := fs.Bool("commit", false, "edit the manifests and commit them on a new branch; without it only the plan is printed")
+	branch := fs.String("branch", "pod-limit-checker/rightsize-"+time.Now().UTC().Format("20060102"), "branch to commit on")
+	format := fs.String("output", "table", "output format: table, json, yaml")
+	fs.Parse(args)
+
+	if *repo == "" {
+		return fmt.Errorf("--repo is required")
+	}
+
+	shouldBeQuiet := quiet || *format == "json" || *format == "yaml"
+
+	client, err := kubernetes.NewClient(kubeconfig, shouldBeQuiet)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: failed to create Kubernetes client: %v\n", err)
+		os.Exit(1)
+	}
+
+	podAnalyzer := analyzer.NewPodAnalyzer(client)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	pods, err := podAnalyzer.GetPodsWithoutLimits(ctx, namespace)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: failed to get pods: %v\n", err)
+		os.Exit(1)
+	}
+
+	if !shouldBeQuiet {
+		fmt.Println("Fetching pod metrics...")
+	}
+	podMetrics, err := podAnalyzer.GetPodMetrics(ctx, namespace)
+	if err != nil {
+		// Recommendations come from usage, so there is nothing to write
+		fmt.Fprintf(os.Stderr, "Error: failed to fetch metrics: %v\n", err)
+		os.Exit(1)
+	}
+
+	results := podAnalyzer.AnalyzePods(pods, podMetrics, threshold)
+	// Helm releases are changed through their values, not the manifests
+	helmWorkloads, err := helm.FindWorkloads(ctx, client.Clientset, namespace)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	plan, err := gitops.PlanChanges(*repo, results, helmWorkloads)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	var commitErr error
+	if *commit {
+		commitErr = gitops.Apply(plan, *branch, gitops.CommitMessage(plan))
+	}
+
+	rep := reporter.NewReporter(*format, os.Stdout)
+	rep.SetQuiet(shouldBeQuiet)
+	if err := rep.GenerateRemediationReport(plan); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: failed to generate report: %v\n", err)
+		os.Exit(1)
+	}
+
+	if commitErr != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", commitErr)
+		os.Exit(1)
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+package cmd
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"pod-limit-checker/pkg/analyzer"
+	"pod-limit-checker/pkg/kubernetes"
+	"pod-limit-checker/pkg/reporter"
+	"pod-limit-checker/pkg/resize"
+)
+
+// runResize implements "pod-limit-checker resize": it plans applying the
+// recommendations to running pods through the resize subresource and, with
+// --apply, patches them without rolling the workloads.
+func runResize(args []string) error {
+	fs := flag.NewFlagSet("resize", flag.ExitOnError)
+	fs.StringVar(&kubeconfig, "kubeconfig", "", "absolute path to the kubeconfig file")
+	fs.StringVar(&namespace, "namespace", "", "specific namespace to resize (default: all namespaces)")
+	fs.Float64Var(&threshold, "threshold", 0.8, "usage threshold for suggestions (0.0-1.0)")
+	fs.BoolVar(&quiet, "quiet", false, "suppress informational output")
+	apply := fs.Bool("apply", false, "resize the pods; without it only the plan is printed")
+	format := fs.String("output", "table", "output format: table, json, yaml")
+	policyFlags(fs)
+	fs.Parse(args)
+
+	if err := validatePolicies(); err != nil {
+		return err
+	}
+
+	shouldBeQuiet := quiet || *format == "json" || *format == "yaml"
+
+	client, err := kubernetes.NewClient(kubeconfig, shouldBeQuiet)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: failed to create Kubernetes client: %v\n", err)
+		os.Exit(1)
+	}
+
+	podAnalyzer := analyzer.NewPodAnalyzer(client)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	supported, err := resize.Supported(client.Clientset.Discovery())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: failed to discover pod subresources: %v\n", err)
+		os.Exit(1)
+	}
+
+	pods, err := podAnalyzer.GetPodsWithoutLimits(ctx, namespace)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: failed to get pods: %v\n", err)
+		os.Exit(1)
+	}
+
+	if !shouldBeQuiet {
+		fmt.Println("Fetching pod metrics...")
+	}
+	podMetrics, err := podAnalyzer.GetPodMetrics(ctx, namespace)
+	if err != nil {
+		// Recommendations come from usage, so there is nothing to resize
+		fmt.Fprintf(os.Stderr, "Error: failed to fetch metrics: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Resize to the same values check recommends
+	if err := applyPolicies(ctx, podAnalyzer); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	results := podAnalyzer.AnalyzePods(pods, podMetrics, threshold)
+	plan := resize.PlanResize(pods, results, supported)
+
+	var applyErr error
+	if *apply {
+		if !supported {
+			fmt.Fprintln(os.Stderr, "Error: the cluster does not serve pods/resize")
+			os.Exit(1)
+		}
+		applyErr = resize.Apply(ctx, client.Clientset, plan)
+	}
+
+	rep := reporter.NewReporter(*format, os.Stdout)
+	rep.SetQuiet(shouldBeQuiet)
+	if err := rep.GenerateResizeReport(plan); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: failed to generate report: %v\n", err)
+		os.Exit(1)
+	}
+
+	if applyErr != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", applyErr)
+		os.Exit(1)
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+package cmd
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"pod-limit-checker/pkg/analyzer"
+	"pod-limit-checker/pkg/kubernetes"
+	"pod-limit-checker/pkg/reporter"
+	"pod-limit-checker/pkg/simulator"
+)
+
+// runSimulate implements "pod-limit-checker simulate": it analyzes the
+// cluster, replaces requests with the recommended values and checks whether
+// the pods would still fit on the current nodes.
+func runSimulate(args []string) error {
+	fs := flag.NewFlagSet("simulate", flag.ExitOnError)
+	fs.StringVar(&kubeconfig, "kubeconfig", "", "absolute path to the kubeconfig file")
+	fs.StringVar(&namespace, "namespace", "", "only apply recommendations to this namespace (packing always covers all pods)")
+	fs.Float64Var(&threshold, "threshold", 0.8, "usage threshold for suggestions (0.0-1.0)")
+	fs.BoolVar(&quiet, "quiet", false, "suppress informational output")
+	format := fs.String("output", "table", "output format: table, json, yaml")
+	policyFlags(fs)
+	fs.Parse(args)
+
+	if err := validatePolicies(); err != nil {
+		return err
+	}
+
+	shouldBeQuiet := quiet || *format == "json" || *format == "yaml"
+
+	client, err := kubernetes.NewClient(kubeconfig, shouldBeQuiet)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: failed to create Kubernetes client: %v\n", err)
+		os.Exit(1)
+	}
+
+	podAnalyzer := analyzer.NewPodAnalyzer(client)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	// Packing needs every pod on every node, regardless of --namespace
+	pods, err := podAnalyzer.GetPodsWithoutLimits(ctx, "")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: failed to get pods: %v\n", err)
+		os.Exit(1)
+	}
+
+	nodes, err := podAnalyzer.GetNodes(ctx)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: failed to get nodes: %v\n", err)
+		os.Exit(1)
+	}
+
+	if !shouldBeQuiet {
+		fmt.Println("Fetching pod metrics...")
+	}
+	podMetrics, err := podAnalyzer.GetPodMetrics(ctx, namespace)
+	if err != nil {
+		if !shouldBeQuiet {
+			fmt.Fprintf(os.Stderr, "Warning: Could not fetch metrics: %v\n", err)
+			fmt.Fprintln(os.Stderr, "Simulating with current requests only...")
+		}
+	}
+
+	// Pack the same values check recommends
+	if err := applyPolicies(ctx, podAnalyzer); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	scoped := pods
+	if namespace != "" {
+		scoped = nil
+		for _, pod := range pods {
+			if pod.Namespace == namespace {
+				scoped = append(scoped, pod)
+			}
+		}
+	}
+	results := podAnalyzer.AnalyzePods(scoped, podMetrics, threshold)
+
+	rep := reporter.NewReporter(*format, os.Stdout)
+	rep.SetQuiet(shouldBeQuiet)
+	if err := rep.GenerateSimulationReport(simulator.Simulate(pods, nodes, results)); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: failed to generate report: %v\n", err)
+		os.Exit(1)
+	}
+
+	return nil
+}
@@ -0,0 +1,112 @@
+package cmd
+
+import (
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	"pod-limit-checker/pkg/analyzer"
+	"pod-limit-checker/pkg/reporter"
+	"pod-limit-checker/pkg/webhook"
+)
+
+// runWebhook implements "pod-limit-checker webhook": it serves the
+// analyzer's rules as a validating admission webhook and injects missing
+// resources as a mutating admission webhook, over TLS.
+func runWebhook(args []string) error {
+	fs := flag.NewFlagSet("webhook", flag.ExitOnError)
+	addr := fs.String("addr", ":8443", "address to serve the webhook on")
+	certFile := fs.String("tls-cert-file", "", "TLS certificate file (required)")
+	keyFile := fs.String("tls-key-file", "", "TLS private key file (required)")
+	denyRisk := fs.String("deny-risk", "HIGH", "reject objects with a container at or above this risk: LOW, MEDIUM, HIGH or NONE")
+	warnRisk := fs.String("warn-risk", "MEDIUM", "return admission warnings for containers at or above this risk: LOW, MEDIUM, HIGH or NONE")
+	ratioFlags(fs, &ratioPolicy)
+	boundsFlags(fs, &bounds)
+	recommendations := fs.String("recommendations", "", "JSON or YAML report of a previous run whose recommendations are injected for the same workload")
+	defaults := map[string]*string{
+		"cpu-request":    fs.String("default-cpu-request", "", "CPU request injected into containers without one, e.g. 100m"),
+		"cpu-limit":      fs.String("default-cpu-limit", "", "CPU limit injected into containers without one, e.g. 500m"),
+		"memory-request": fs.String("default-memory-request", "", "memory request injected into containers without one, e.g. 128Mi"),
+		"memory-limit":   fs.String("default-memory-limit", "", "memory limit injected into containers without one, e.g. 512Mi"),
+	}
+	fs.Parse(args)
+
+	if *certFile == "" || *keyFile == "" {
+		return fmt.Errorf("--tls-cert-file and --tls-key-file are required")
+	}
+	if err := validateRatio(ratioPolicy); err != nil {
+		return err
+	}
+	if err := validateBounds(bounds); err != nil {
+		return err
+	}
+	deny, err := webhook.ParseRisk(*denyRisk)
+	if err != nil {
+		return fmt.Errorf("invalid --deny-risk: %v", err)
+	}
+	warn, err := webhook.ParseRisk(*warnRisk)
+	if err != nil {
+		return fmt.Errorf("invalid --warn-risk: %v", err)
+	}
+
+	mutator := &webhook.Mutator{Defaults: webhook.Defaults{Limits: v1.ResourceList{}, Requests: v1.ResourceList{}}}
+	for flagName, value := range defaults {
+		if *value == "" {
+			continue
+		}
+		quantity, err := resource.ParseQuantity(*value)
+		if err != nil {
+			return fmt.Errorf("invalid --default-%s: %v", flagName, err)
+		}
+		name, kind, _ := strings.Cut(flagName, "-")
+		if kind == "limit" {
+			mutator.Defaults.Limits[v1.ResourceName(name)] = quantity
+		} else {
+			mutator.Defaults.Requests[v1.ResourceName(name)] = quantity
+		}
+	}
+	for _, name := range []v1.ResourceName{v1.ResourceCPU, v1.ResourceMemory} {
+		limit, hasLimit := mutator.Defaults.Limits[name]
+		request, hasRequest := mutator.Defaults.Requests[name]
+		if hasLimit && hasRequest && request.Cmp(limit) > 0 {
+			return fmt.Errorf("--default-%s-request must not exceed --default-%s-limit", name, name)
+		}
+	}
+	if *recommendations != "" {
+		report, err := reporter.LoadReport(*recommendations)
+		if err != nil {
+			return err
+		}
+		mutator.SetRecommendations(report)
+	}
+
+	// Admission reviews carry the object, so no cluster access is needed
+	podAnalyzer := analyzer.NewPodAnalyzer(nil)
+	podAnalyzer.SetRatioPolicy(ratioPolicy)
+	podAnalyzer.SetBoundsPolicy(bounds)
+
+	mux := http.NewServeMux()
+	mux.Handle(webhook.ValidatePath, &webhook.Validator{
+		Analyzer: podAnalyzer,
+		DenyRisk: deny,
+		WarnRisk: warn,
+	})
+	mux.Handle(webhook.MutatePath, mutator)
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	})
+
+	server := &http.Server{
+		Addr:              *addr,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	fmt.Fprintf(os.Stderr, "Serving admission webhook on %s\n", *addr)
+	return server.ListenAndServeTLS(*certFile, *keyFile)
+}
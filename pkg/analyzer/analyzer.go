@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"time"
 
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -18,32 +19,79 @@ type PodAnalysis struct {
 	Namespace     string
 	PodName       string
 	ContainerName string
-	HasLimits     bool
-	HasRequests   bool
-	CurrentLimits v1.ResourceList
-	CurrentUsage  *ResourceUsage
-	Suggestions   []string
-	RiskLevel     string
-	Age           string
+	NodeName      string
+	WorkloadKind  string
+	WorkloadName  string
+	// HasLimits and HasRequests report whether any limit or request is
+	// set. They stay summary flags: per-resource presence is already in
+	// CurrentLimits and CurrentRequests, which the ratio, bounds and QoS
+	// checks read directly.
+	HasLimits   bool
+	HasRequests bool
+	// Limit-to-request ratios; 0 when the resource has no limit
+	CPULimitRatio    float64
+	MemoryLimitRatio float64
+	// Requested but unused capacity; set only when usage is known
+	IdleCPUMilli    int64
+	IdleMemoryBytes int64
+	Labels          map[string]string
+	CurrentLimits   v1.ResourceList
+	CurrentRequests v1.ResourceList
+	CurrentUsage    *ResourceUsage
+	Suggestions     []string
+	RiskLevel       string
+	Age             string
+	// QoS class of the pod now, after applying the recommendations, and as
+	// required by policy (empty when no policy applies)
+	QoSClass            string
+	RecommendedQoSClass string
+	RequiredQoSClass    string
+	QoSViolation        bool
+	// VPA recommendation for the container's workload, when one exists
+	VPA *VPARecommendation
+	// How an HPA on the workload is affected by the recommended requests
+	HPAImpacts []HPAImpact
 	// Add fields for specific recommendations
 	RecommendedCPULimit      string
 	RecommendedCPURequest    string
 	RecommendedMemoryLimit   string
 	RecommendedMemoryRequest string
-	ExampleYAML              string
+	// Set only when ephemeral-storage checks are enabled and usage is known
+	RecommendedEphemeralStorageLimit   string
+	RecommendedEphemeralStorageRequest string
+	// Language runtime detected from the spec, its memory setting and the
+	// setting recommended for the (recommended) memory limit
+	Runtime                   string
+	RuntimeSetting            string
+	RecommendedRuntimeSetting string
+	ExampleYAML               string
 }
 
 type ResourceUsage struct {
-	CPU    *resource.Quantity
-	Memory *resource.Quantity
+	CPU              *resource.Quantity
+	Memory           *resource.Quantity
+	EphemeralStorage *resource.Quantity
 }
 
 type PodAnalyzer struct {
-	client *kubernetes.Client
+	client          *kubernetes.Client
+	qosPolicies     []QoSPolicy
+	namespaceLabels map[string]map[string]string
+	checkEphemeral  bool
+	storageStats    *StorageStats
+	nodeCapacity    map[v1.ResourceName]resource.Quantity
+	ratioPolicy     RatioPolicy
+	boundsPolicy    BoundsPolicy
+	requestPolicy   RequestPolicy
+	vpas            map[string]VPA
+	hpas            map[string]autoscalingv2.HorizontalPodAutoscaler
 }
 
+// errNoMetrics is returned for usage when the client has no metrics API.
+var errNoMetrics = fmt.Errorf("metrics API client not available")
+
 func NewPodAnalyzer(client *kubernetes.Client) *PodAnalyzer {
-	return &PodAnalyzer{client: client}
+	return &PodAnalyzer{client: client, requestPolicy: DefaultRequestPolicy}
 }
 
 func (a *PodAnalyzer) GetPodsWithoutLimits(ctx context.Context, namespace string) ([]v1.Pod, error) {
@@ -56,6 +104,9 @@ func (a *PodAnalyzer) GetPodsWithoutLimits(ctx context.Context, namespace string
 }
 
 func (a *PodAnalyzer) GetPodMetrics(ctx context.Context, namespace string) ([]metricsv1beta1.PodMetrics, error) {
+	if a.client.MetricsClient == nil {
+		return nil, errNoMetrics
+	}
 	metrics, err := a.client.MetricsClient.MetricsV1beta1().PodMetricses(namespace).List(ctx, metav1.ListOptions{})
 	if err != nil {
 		return nil, err
@@ -64,6 +115,15 @@ func (a *PodAnalyzer) GetPodMetrics(ctx context.Context, namespace string) ([]me
 	return metrics.Items, nil
 }
 
+func (a *PodAnalyzer) GetNodes(ctx context.Context) ([]v1.Node, error) {
+	nodes, err := a.client.Clientset.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	return nodes.Items, nil
+}
+
 func (a *PodAnalyzer) AnalyzePods(pods []v1.Pod, podMetrics []metricsv1beta1.PodMetrics, threshold float64) []PodAnalysis {
 	var results []PodAnalysis
 
@@ -75,14 +135,22 @@ func (a *PodAnalyzer) AnalyzePods(pods []v1.Pod, podMetrics []metricsv1beta1.Pod
 
 	for _, pod := range pods {
 		podAge := duration.ShortHumanDuration(time.Since(pod.CreationTimestamp.Time))
+		workloadKind, workloadName := WorkloadOf(pod)
+		requiredQoS, qosReason := a.requiredQoS(pod.Namespace)
+		podStart := len(results)
 
 		for _, container := range pod.Spec.Containers {
 			analysis := PodAnalysis{
-				Namespace:     pod.Namespace,
-				PodName:       pod.Name,
-				ContainerName: container.Name,
-				Age:           podAge,
-				CurrentLimits: container.Resources.Limits,
+				Namespace:       pod.Namespace,
+				PodName:         pod.Name,
+				ContainerName:   container.Name,
+				NodeName:        pod.Spec.NodeName,
+				WorkloadKind:    workloadKind,
+				WorkloadName:    workloadName,
+				Age:             podAge,
+				Labels:          pod.Labels,
+				CurrentLimits:   container.Resources.Limits,
+				CurrentRequests: container.Resources.Requests,
 			}
 
 			// Check for limits and requests
@@ -91,6 +159,8 @@ func (a *PodAnalyzer) AnalyzePods(pods []v1.Pod, podMetrics []metricsv1beta1.Pod
 
 			analysis.HasLimits = hasLimits
 			analysis.HasRequests = hasRequests
+			analysis.CPULimitRatio = limitRequestRatio(container.Resources, v1.ResourceCPU)
+			analysis.MemoryLimitRatio = limitRequestRatio(container.Resources, v1.ResourceMemory)
 
 			// Get current usage from metrics
 			if pm, exists := metricsMap[fmt.Sprintf("%s/%s", pod.Namespace, pod.Name)]; exists {
@@ -105,20 +175,92 @@ func (a *PodAnalyzer) AnalyzePods(pods []v1.Pod, podMetrics []metricsv1beta1.Pod
 				}
 			}
 
+			// Get ephemeral-storage usage from the kubelet stats summary
+			if a.checkEphemeral {
+				if used := a.ephemeralUsage(pod, container); used != nil {
+					if analysis.CurrentUsage == nil {
+						analysis.CurrentUsage = &ResourceUsage{}
+					}
+					analysis.CurrentUsage.EphemeralStorage = used
+				}
+			}
+
 			// Generate suggestions and specific recommendations
 			analysis.Suggestions = a.generateSuggestions(container, analysis.CurrentUsage, threshold)
+			if a.checkEphemeral {
+				analysis.Suggestions = append(analysis.Suggestions,
+					a.ephemeralSuggestions(pod, container, analysis.CurrentUsage, threshold)...)
+			}
 			analysis.RiskLevel = a.calculateRiskLevel(container, analysis.CurrentUsage)
 
+			// Extended resources and hugepages cannot be overcommitted
+			if special, misconfigured := a.specialResourceSuggestions(container); len(special) > 0 {
+				analysis.Suggestions = append(analysis.Suggestions, special...)
+				if misconfigured && analysis.RiskLevel == "LOW" {
+					analysis.RiskLevel = "MEDIUM"
+				}
+			}
+
+			// Burstability beyond the configured ratio
+			if ratios, violated := a.ratioSuggestions(&analysis); violated {
+				analysis.Suggestions = append(analysis.Suggestions, ratios...)
+				if analysis.RiskLevel == "LOW" {
+					analysis.RiskLevel = "MEDIUM"
+				}
+			}
+
+			// Requests and limits outside the configured min/max
+			if bounds, violated := a.boundsSuggestions(container); violated {
+				analysis.Suggestions = append(analysis.Suggestions, bounds...)
+				if analysis.RiskLevel == "LOW" {
+					analysis.RiskLevel = "MEDIUM"
+				}
+			}
+
 			// Generate specific recommendations based on actual usage
 			a.generateSpecificRecommendations(&analysis, container)
 
+			// Coordinate the memory limit with the runtime's heap setting
+			if runtime, misconfigured := a.runtimeSuggestions(&analysis, container); len(runtime) > 0 {
+				analysis.Suggestions = append(analysis.Suggestions, runtime...)
+				if misconfigured && analysis.RiskLevel == "LOW" {
+					analysis.RiskLevel = "MEDIUM"
+				}
+			}
+
+			a.applyBoundsPolicy(&analysis)
+			a.applyRatioPolicy(&analysis)
+			a.ephemeralRecommendations(&analysis)
+
+			// A Guaranteed policy needs requests equal to limits
+			if requiredQoS == v1.PodQOSGuaranteed && analysis.RecommendedCPULimit != "" {
+				analysis.RecommendedCPURequest = analysis.RecommendedCPULimit
+				analysis.RecommendedMemoryRequest = analysis.RecommendedMemoryLimit
+			}
+
+			// Compare usage with requests now that recommendations are final
+			analysis.Suggestions = append(analysis.Suggestions, a.requestSuggestions(&analysis, container)...)
+
+			// Compare with an existing VerticalPodAutoscaler
+			analysis.Suggestions = append(analysis.Suggestions, a.vpaSuggestions(&analysis, container)...)
+
+			// Requests drive HPA utilization, so changing them changes scaling
+			if hpa, misconfigured := a.hpaSuggestions(&analysis, container); len(hpa) > 0 {
+				analysis.Suggestions = append(analysis.Suggestions, hpa...)
+				if misconfigured && analysis.RiskLevel == "LOW" {
+					analysis.RiskLevel = "MEDIUM"
+				}
+			}
+
 			// Generate example YAML if no limits
-			if !analysis.HasLimits && analysis.CurrentUsage != nil {
+			if !analysis.HasLimits && analysis.RecommendedCPULimit != "" {
 				analysis.ExampleYAML = a.generateExampleYAML(&analysis, container)
 			}
 
 			results = append(results, analysis)
 		}
+
+		a.applyQoS(pod, results[podStart:], requiredQoS, qosReason)
 	}
 
 	return results
@@ -165,22 +307,26 @@ func (a *PodAnalyzer) generateSpecificRecommendations(analysis *PodAnalysis, con
 }
 
 func (a *PodAnalyzer) generateExampleYAML(analysis *PodAnalysis, container v1.Container) string {
-	if analysis.CurrentUsage == nil {
+	if analysis.RecommendedCPULimit == "" {
 		return ""
 	}
 
+	limits := fmt.Sprintf(`            cpu: "%s"
+            memory: "%s"`, analysis.RecommendedCPULimit, analysis.RecommendedMemoryLimit)
+	requests := fmt.Sprintf(`            cpu: "%s"
+            memory: "%s"`, analysis.RecommendedCPURequest, analysis.RecommendedMemoryRequest)
+	if analysis.RecommendedEphemeralStorageLimit != "" {
+		limits += fmt.Sprintf(`
+            ephemeral-storage: "%s"`, analysis.RecommendedEphemeralStorageLimit)
+		requests += fmt.Sprintf(`
+            ephemeral-storage: "%s"`, analysis.RecommendedEphemeralStorageRequest)
+	}
+
 	return fmt.Sprintf(`        resources:
           limits:
-            cpu: "%s"
-            memory: "%s"
+%s
           requests:
-            cpu: "%s"
-            memory: "%s"`,
-		analysis.RecommendedCPULimit,
-		analysis.RecommendedMemoryLimit,
-		analysis.RecommendedCPURequest,
-		analysis.RecommendedMemoryRequest,
-	)
+%s`, limits, requests)
 }
 
 func (a *PodAnalyzer) generateSuggestions(container v1.Container, usage *ResourceUsage, threshold float64) []string {
@@ -266,5 +412,14 @@ func (a *PodAnalyzer) calculateRiskLevel(container v1.Container, usage *Resource
 		}
 	}
 
+	// Ephemeral storage close to its limit means imminent eviction
+	if usage != nil && usage.EphemeralStorage != nil {
+		if limitEph, ok := container.Resources.Limits[v1.ResourceEphemeralStorage]; ok {
+			if limitEph.Value() > 0 && float64(usage.EphemeralStorage.Value())/float64(limitEph.Value()) > 0.9 {
+				return "MEDIUM"
+			}
+		}
+	}
+
 	return "LOW"
 }
@@ -0,0 +1,593 @@
+package analyzer
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	metricsv1beta1 "k8s.io/metrics/pkg/apis/metrics/v1beta1"
+	metricsfake "k8s.io/metrics/pkg/client/clientset/versioned/fake"
+
+	"pod-limit-checker/pkg/kubernetes"
+)
+
+// newTestAnalyzer returns an analyzer backed by fake clientsets holding
+// objects; metrics objects go to the metrics clientset.
+func newTestAnalyzer(t *testing.T, objects ...runtime.Object) *PodAnalyzer {
+	t.Helper()
+	var core []runtime.Object
+	metrics := metricsfake.NewSimpleClientset()
+	for _, object := range objects {
+		var err error
+		switch m := object.(type) {
+		case *metricsv1beta1.PodMetrics:
+			// The fake lists PodMetrics as "pods", which NewSimpleClientset
+			// does not guess from the kind
+			err = metrics.Tracker().Create(metricsv1beta1.SchemeGroupVersion.WithResource("pods"), m, m.Namespace)
+		case *metricsv1beta1.NodeMetrics:
+			err = metrics.Tracker().Create(metricsv1beta1.SchemeGroupVersion.WithResource("nodes"), m, "")
+		default:
+			core = append(core, object)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	return NewPodAnalyzer(&kubernetes.Client{
+		Clientset:     fake.NewSimpleClientset(core...),
+		MetricsClient: metrics,
+	})
+}
+
+// resources builds requirements from "cpu", "memory", ... pairs; an empty
+// value leaves the resource out.
+func resources(requests, limits []string) v1.ResourceRequirements {
+	list := func(pairs []string) v1.ResourceList {
+		if len(pairs) == 0 {
+			return nil
+		}
+		l := v1.ResourceList{}
+		for i := 0; i+1 < len(pairs); i += 2 {
+			if pairs[i+1] != "" {
+				l[v1.ResourceName(pairs[i])] = resource.MustParse(pairs[i+1])
+			}
+		}
+		return l
+	}
+	return v1.ResourceRequirements{Requests: list(requests), Limits: list(limits)}
+}
+
+func cpuMemory(cpu, memory string) []string {
+	return []string{"cpu", cpu, "memory", memory}
+}
+
+func testPod(namespace, name string, containers ...v1.Container) *v1.Pod {
+	return &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+		Spec:       v1.PodSpec{NodeName: "node-1", Containers: containers},
+		Status:     v1.PodStatus{Phase: v1.PodRunning},
+	}
+}
+
+func testContainer(name string, res v1.ResourceRequirements) v1.Container {
+	return v1.Container{Name: name, Image: "example/" + name, Resources: res}
+}
+
+func testPodMetrics(namespace, name, container, cpu, memory string) *metricsv1beta1.PodMetrics {
+	return &metricsv1beta1.PodMetrics{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+		Containers: []metricsv1beta1.ContainerMetrics{{
+			Name:  container,
+			Usage: v1.ResourceList{v1.ResourceCPU: resource.MustParse(cpu), v1.ResourceMemory: resource.MustParse(memory)},
+		}},
+	}
+}
+
+func usage(cpu, memory string) *ResourceUsage {
+	c, m := resource.MustParse(cpu), resource.MustParse(memory)
+	return &ResourceUsage{CPU: &c, Memory: &m}
+}
+
+func hasSuggestion(suggestions []string, substr string) bool {
+	for _, s := range suggestions {
+		if strings.Contains(s, substr) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestGetPodsAndMetrics(t *testing.T) {
+	a := newTestAnalyzer(t,
+		testPod("web", "api", testContainer("app", v1.ResourceRequirements{})),
+		testPod("jobs", "batch", testContainer("app", v1.ResourceRequirements{})),
+		testPodMetrics("web", "api", "app", "100m", "64Mi"),
+	)
+	ctx := context.Background()
+
+	pods, err := a.GetPodsWithoutLimits(ctx, "web")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pods) != 1 || pods[0].Name != "api" {
+		t.Errorf("pods in web = %v, want [api]", pods)
+	}
+
+	metrics, err := a.GetPodMetrics(ctx, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(metrics) != 1 || metrics[0].Name != "api" {
+		t.Errorf("pod metrics = %v, want api", metrics)
+	}
+}
+
+func TestMetricsWithoutMetricsClient(t *testing.T) {
+	a := NewPodAnalyzer(&kubernetes.Client{Clientset: fake.NewSimpleClientset()})
+	ctx := context.Background()
+
+	if _, err := a.GetPodMetrics(ctx, ""); !errors.Is(err, errNoMetrics) {
+		t.Errorf("GetPodMetrics error = %v, want %v", err, errNoMetrics)
+	}
+	if _, err := a.GetNodeMetrics(ctx); !errors.Is(err, errNoMetrics) {
+		t.Errorf("GetNodeMetrics error = %v, want %v", err, errNoMetrics)
+	}
+}
+
+func TestAnalyzePods(t *testing.T) {
+	tests := []struct {
+		name      string
+		resources v1.ResourceRequirements
+		// usage is "cpu memory", empty for no metrics
+		usage []string
+		risk  string
+		// suggestions holds substrings expected in the suggestions
+		suggestions []string
+		// recommended is cpu limit, cpu request, memory limit, memory request
+		recommended []string
+		example     bool
+	}{
+		{
+			name:        "no resources without metrics",
+			risk:        "HIGH",
+			suggestions: []string{"No resource limits set", "No resource requests set", "Consider setting limits"},
+		},
+		{
+			name:        "no resources with metrics",
+			usage:       []string{"200m", "256Mi"},
+			risk:        "HIGH",
+			suggestions: []string{"No resource limits set", "No resource requests set"},
+			recommended: []string{"500m", "240m", "640Mi", "307Mi"},
+			example:     true,
+		},
+		{
+			name:        "minimum recommendations",
+			usage:       []string{"1m", "1Mi"},
+			risk:        "HIGH",
+			recommended: []string{"100m", "50m", "128Mi", "64Mi"},
+			example:     true,
+		},
+		{
+			name:        "memory limit only",
+			resources:   resources(nil, []string{"memory", "256Mi"}),
+			usage:       []string{"100m", "200Mi"},
+			risk:        "MEDIUM",
+			suggestions: []string{"No resource requests set"},
+			recommended: []string{"250m", "120m", "500Mi", "240Mi"},
+		},
+		{
+			name:        "cpu near limit",
+			resources:   resources(cpuMemory("500m", "512Mi"), cpuMemory("500m", "1Gi")),
+			usage:       []string{"475m", "600Mi"},
+			risk:        "MEDIUM",
+			suggestions: []string{"CPU usage at 95.0% of limit, consider increasing limit"},
+			recommended: []string{"1187m", "570m", "1500Mi", "720Mi"},
+		},
+		{
+			name:        "oversized limits",
+			resources:   resources(cpuMemory("100m", "128Mi"), cpuMemory("2", "2Gi")),
+			usage:       []string{"100m", "128Mi"},
+			risk:        "LOW",
+			suggestions: []string{"CPU usage at 5.0% of limit, consider decreasing limit", "Memory usage at 6.2% of limit, consider decreasing limit"},
+			recommended: []string{"250m", "120m", "320Mi", "153Mi"},
+		},
+		{
+			name:      "well sized",
+			resources: resources(cpuMemory("200m", "256Mi"), cpuMemory("400m", "512Mi")),
+			usage:     []string{"200m", "300Mi"},
+			risk:      "LOW",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pod := testPod("web", "api", testContainer("app", tt.resources))
+			var metrics []metricsv1beta1.PodMetrics
+			if tt.usage != nil {
+				metrics = append(metrics, *testPodMetrics("web", "api", "app", tt.usage[0], tt.usage[1]))
+			}
+
+			results := newTestAnalyzer(t).AnalyzePods([]v1.Pod{*pod}, metrics, 0.8)
+			if len(results) != 1 {
+				t.Fatalf("got %d results, want 1", len(results))
+			}
+			r := results[0]
+
+			if r.RiskLevel != tt.risk {
+				t.Errorf("risk = %s, want %s", r.RiskLevel, tt.risk)
+			}
+			for _, want := range tt.suggestions {
+				if !hasSuggestion(r.Suggestions, want) {
+					t.Errorf("suggestions %q lack %q", r.Suggestions, want)
+				}
+			}
+			if tt.recommended != nil {
+				got := []string{r.RecommendedCPULimit, r.RecommendedCPURequest, r.RecommendedMemoryLimit, r.RecommendedMemoryRequest}
+				if strings.Join(got, " ") != strings.Join(tt.recommended, " ") {
+					t.Errorf("recommended = %v, want %v", got, tt.recommended)
+				}
+			} else if tt.usage == nil && r.RecommendedCPULimit != "" {
+				t.Errorf("recommendation %s without usage", r.RecommendedCPULimit)
+			}
+			if (r.ExampleYAML != "") != tt.example {
+				t.Errorf("example YAML = %q, want present: %v", r.ExampleYAML, tt.example)
+			}
+			if r.HasLimits != (len(tt.resources.Limits) > 0) || r.HasRequests != (len(tt.resources.Requests) > 0) {
+				t.Errorf("HasLimits, HasRequests = %v, %v", r.HasLimits, r.HasRequests)
+			}
+		})
+	}
+}
+
+func TestAnalyzePodsWorkloadAndQoS(t *testing.T) {
+	pod := testPod("web", "api-7d9f8-x2x4z",
+		testContainer("app", resources(cpuMemory("100m", "128Mi"), cpuMemory("100m", "128Mi"))),
+		testContainer("sidecar", resources(cpuMemory("50m", "64Mi"), nil)),
+	)
+	pod.Labels = map[string]string{"pod-template-hash": "7d9f8"}
+	controller := true
+	pod.OwnerReferences = []metav1.OwnerReference{{Kind: "ReplicaSet", Name: "api-7d9f8", Controller: &controller}}
+
+	results := newTestAnalyzer(t).AnalyzePods([]v1.Pod{*pod}, nil, 0.8)
+	if len(results) != 2 {
+		t.Fatalf("got %d results, want 2", len(results))
+	}
+	for _, r := range results {
+		if r.WorkloadKind != "Deployment" || r.WorkloadName != "api" {
+			t.Errorf("%s: workload = %s/%s, want Deployment/api", r.ContainerName, r.WorkloadKind, r.WorkloadName)
+		}
+		if r.QoSClass != string(v1.PodQOSBurstable) {
+			t.Errorf("%s: QoS = %s, want Burstable", r.ContainerName, r.QoSClass)
+		}
+	}
+}
+
+func TestGenerateSuggestions(t *testing.T) {
+	a := newTestAnalyzer(t)
+	tests := []struct {
+		name      string
+		resources v1.ResourceRequirements
+		usage     *ResourceUsage
+		want      []string
+	}{
+		{
+			name: "nothing set, no usage",
+			want: []string{"❌ No resource limits set", "⚠️ No resource requests set", "📋 Consider setting limits based on application requirements"},
+		},
+		{
+			name:      "requests only, no usage",
+			resources: resources(cpuMemory("100m", "128Mi"), nil),
+			want:      []string{"❌ No resource limits set", "📋 Consider setting limits based on application requirements"},
+		},
+		{
+			name:      "limits set, no usage",
+			resources: resources(cpuMemory("100m", "128Mi"), cpuMemory("200m", "256Mi")),
+		},
+		{
+			name:      "above threshold",
+			resources: resources(cpuMemory("100m", "128Mi"), cpuMemory("200m", "256Mi")),
+			usage:     usage("180m", "240Mi"),
+			want: []string{
+				"⚠️ CPU usage at 90.0% of limit, consider increasing limit",
+				"⚠️ Memory usage at 93.8% of limit, consider increasing limit",
+			},
+		},
+		{
+			name:      "below decrease thresholds",
+			resources: resources(cpuMemory("100m", "128Mi"), cpuMemory("1", "1Gi")),
+			usage:     usage("200m", "256Mi"),
+			want: []string{
+				"💡 CPU usage at 20.0% of limit, consider decreasing limit",
+				"💡 Memory usage at 25.0% of limit, consider decreasing limit",
+			},
+		},
+		{
+			name:      "within range",
+			resources: resources(cpuMemory("100m", "128Mi"), cpuMemory("1", "1Gi")),
+			usage:     usage("500m", "600Mi"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := a.generateSuggestions(testContainer("app", tt.resources), tt.usage, 0.8)
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("suggestions =\n%q\nwant\n%q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCalculateRiskLevel(t *testing.T) {
+	a := newTestAnalyzer(t)
+	ephemeral := func(used string) *ResourceUsage {
+		u := usage("100m", "100Mi")
+		q := resource.MustParse(used)
+		u.EphemeralStorage = &q
+		return u
+	}
+	tests := []struct {
+		name      string
+		resources v1.ResourceRequirements
+		usage     *ResourceUsage
+		want      string
+	}{
+		{"no limits", resources(cpuMemory("100m", "128Mi"), nil), nil, "HIGH"},
+		{"cpu limit only", resources(nil, []string{"cpu", "1"}), nil, "MEDIUM"},
+		{"memory limit only", resources(nil, []string{"memory", "1Gi"}), nil, "MEDIUM"},
+		{"both limits", resources(nil, cpuMemory("1", "1Gi")), nil, "LOW"},
+		{"cpu near limit", resources(nil, cpuMemory("1", "1Gi")), usage("950m", "100Mi"), "MEDIUM"},
+		{"cpu at 90%", resources(nil, cpuMemory("1", "1Gi")), usage("900m", "100Mi"), "LOW"},
+		{"memory near limit", resources(nil, cpuMemory("1", "1Gi")), usage("100m", "1000Mi"), "LOW"},
+		{"ephemeral near limit", resources(nil, []string{"cpu", "1", "memory", "1Gi", "ephemeral-storage", "1Gi"}), ephemeral("950Mi"), "MEDIUM"},
+		{"ephemeral within limit", resources(nil, []string{"cpu", "1", "memory", "1Gi", "ephemeral-storage", "1Gi"}), ephemeral("100Mi"), "LOW"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := a.calculateRiskLevel(testContainer("app", tt.resources), tt.usage); got != tt.want {
+				t.Errorf("risk = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAnalyzeNodes(t *testing.T) {
+	node := &v1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "node-1"},
+		Status: v1.NodeStatus{
+			Allocatable: v1.ResourceList{v1.ResourceCPU: resource.MustParse("2"), v1.ResourceMemory: resource.MustParse("4Gi")},
+		},
+	}
+	pressured := node.DeepCopy()
+	pressured.Name = "node-2"
+	pressured.Status.Conditions = []v1.NodeCondition{{Type: v1.NodeDiskPressure, Status: v1.ConditionTrue}}
+
+	bounded := testPod("web", "bounded", testContainer("app", resources(cpuMemory("1", "1Gi"), cpuMemory("3", "2Gi"))))
+	unbounded := testPod("web", "unbounded", testContainer("app", resources(cpuMemory("500m", "512Mi"), []string{"cpu", "1"})))
+	done := testPod("web", "done", testContainer("app", resources(nil, cpuMemory("8", "8Gi"))))
+	done.Status.Phase = v1.PodSucceeded
+	pending := testPod("web", "pending", testContainer("app", resources(nil, cpuMemory("8", "8Gi"))))
+	pending.Spec.NodeName = ""
+
+	a := newTestAnalyzer(t, node, pressured, &metricsv1beta1.NodeMetrics{
+		ObjectMeta: metav1.ObjectMeta{Name: "node-1"},
+		Usage:      v1.ResourceList{v1.ResourceCPU: resource.MustParse("1"), v1.ResourceMemory: resource.MustParse("3900Mi")},
+	})
+	ctx := context.Background()
+	nodes, err := a.GetNodes(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	nodeMetrics, err := a.GetNodeMetrics(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(nodeMetrics) != 1 {
+		t.Fatalf("got %d node metrics, want 1", len(nodeMetrics))
+	}
+
+	results := a.AnalyzeNodes(nodes, []v1.Pod{*bounded, *unbounded, *done, *pending}, nodeMetrics, 1.5)
+	if len(results) != 2 {
+		t.Fatalf("got %d nodes, want 2", len(results))
+	}
+	byName := map[string]NodeAnalysis{}
+	for _, r := range results {
+		byName[r.Name] = r
+	}
+
+	n := byName["node-1"]
+	if n.PodCount != 2 || n.UnboundedPods != 1 {
+		t.Errorf("pods, unbounded = %d, %d, want 2, 1", n.PodCount, n.UnboundedPods)
+	}
+	if n.RequestsCPU != 1500 || n.LimitsCPU != 4000 || n.LimitsMemory != 2<<30 {
+		t.Errorf("requests CPU %d, limits CPU %d, limits memory %d", n.RequestsCPU, n.LimitsCPU, n.LimitsMemory)
+	}
+	if !n.Overcommitted || !n.EvictionProne {
+		t.Errorf("overcommitted, eviction prone = %v, %v, want true, true", n.Overcommitted, n.EvictionProne)
+	}
+	if want := []string{"CPU limits at 200% of allocatable", "memory usage near allocatable"}; strings.Join(n.Reasons, ", ") != strings.Join(want, ", ") {
+		t.Errorf("reasons = %q, want %q", n.Reasons, want)
+	}
+	if n.UsageCPU == nil || *n.UsageCPU != 1000 {
+		t.Errorf("CPU usage = %v, want 1000", n.UsageCPU)
+	}
+	if len(n.TopContributors) != 2 || n.TopContributors[0].PodName != "unbounded" {
+		t.Errorf("top contributors = %+v, want unbounded first", n.TopContributors)
+	}
+
+	p := byName["node-2"]
+	if p.PodCount != 0 || p.Overcommitted || !p.EvictionProne || p.UsageCPU != nil {
+		t.Errorf("node-2 = %+v, want empty, eviction prone, without usage", p)
+	}
+}
+
+func TestIsExtendedResource(t *testing.T) {
+	for name, want := range map[v1.ResourceName]bool{
+		"nvidia.com/gpu":                 true,
+		"example.com/fpga":               true,
+		"cpu":                            false,
+		"hugepages-2Mi":                  false,
+		"kubernetes.io/batch-cpu":        false,
+		"node.kubernetes.io/custom":      false,
+		"requests.nvidia.com/gpu":        false,
+		v1.DefaultResourceRequestsPrefix: false,
+	} {
+		if got := IsExtendedResource(name); got != want {
+			t.Errorf("IsExtendedResource(%s) = %v, want %v", name, got, want)
+		}
+	}
+	if !IsSpecialResource("hugepages-2Mi") || !IsSpecialResource("nvidia.com/gpu") || IsSpecialResource("memory") {
+		t.Error("IsSpecialResource does not cover extended and hugepages resources only")
+	}
+}
+
+// gpuNodes has a node advertising two GPUs and 1Gi of 2Mi hugepages, a node
+// with four GPUs and one without either.
+func gpuNodes() []runtime.Object {
+	node := func(name string, allocatable ...string) *v1.Node {
+		return &v1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Status:     v1.NodeStatus{Allocatable: resourceList(append(cpuMemory("8", "32Gi"), allocatable...)...)},
+		}
+	}
+	return []runtime.Object{
+		node("gpu-1", "nvidia.com/gpu", "2", "hugepages-2Mi", "1Gi"),
+		node("gpu-2", "nvidia.com/gpu", "4"),
+		node("cpu-1"),
+	}
+}
+
+func TestSetNodeCapacity(t *testing.T) {
+	a := newTestAnalyzer(t, gpuNodes()...)
+	nodes, err := a.GetNodes(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	a.SetNodeCapacity(nodes)
+
+	if len(a.nodeCapacity) != 2 {
+		t.Errorf("node capacity = %v, want only nvidia.com/gpu and hugepages-2Mi", a.nodeCapacity)
+	}
+	for name, want := range map[v1.ResourceName]string{"nvidia.com/gpu": "4", "hugepages-2Mi": "1Gi"} {
+		if got := a.nodeCapacity[name]; got.Cmp(resource.MustParse(want)) != 0 {
+			t.Errorf("capacity of %s = %s, want the largest node's %s", name, got.String(), want)
+		}
+	}
+}
+
+func TestSpecialResourceSuggestions(t *testing.T) {
+	tests := []struct {
+		name          string
+		resources     v1.ResourceRequirements
+		withNodes     bool
+		want          []string
+		misconfigured bool
+	}{
+		{
+			name:      "gpu limit only",
+			resources: resources(cpuMemory("1", "1Gi"), []string{"nvidia.com/gpu", "1"}),
+			withNodes: true,
+		},
+		{
+			name:      "gpu request equals limit",
+			resources: resources([]string{"nvidia.com/gpu", "2"}, []string{"nvidia.com/gpu", "2"}),
+			withNodes: true,
+		},
+		{
+			name:          "gpu request without limit",
+			resources:     resources([]string{"nvidia.com/gpu", "1"}, nil),
+			want:          []string{"🚫 nvidia.com/gpu is requested without a limit; it cannot be overcommitted, set limit = request"},
+			misconfigured: true,
+		},
+		{
+			name:          "gpu request below limit",
+			resources:     resources([]string{"nvidia.com/gpu", "1"}, []string{"nvidia.com/gpu", "2"}),
+			want:          []string{"🚫 nvidia.com/gpu request (1) must equal its limit (2)"},
+			misconfigured: true,
+		},
+		{
+			name:          "more gpus than any node",
+			resources:     resources(nil, []string{"nvidia.com/gpu", "8"}),
+			withNodes:     true,
+			want:          []string{"🚫 nvidia.com/gpu of 8 exceeds the largest node allocatable (4)"},
+			misconfigured: true,
+		},
+		{
+			name:          "resource no node advertises",
+			resources:     resources(nil, []string{"example.com/fpga", "1"}),
+			withNodes:     true,
+			want:          []string{"🚫 No node advertises example.com/fpga; the pod cannot be scheduled"},
+			misconfigured: true,
+		},
+		{
+			name:      "capacity unknown without nodes",
+			resources: resources(nil, []string{"example.com/fpga", "1"}),
+		},
+		{
+			name:      "hugepages with memory",
+			resources: resources(cpuMemory("", "512Mi"), []string{"hugepages-2Mi", "256Mi", "memory", "512Mi"}),
+			withNodes: true,
+		},
+		{
+			name:          "hugepages without memory or cpu",
+			resources:     resources(nil, []string{"hugepages-2Mi", "256Mi"}),
+			withNodes:     true,
+			want:          []string{"🚫 Hugepages require a memory or CPU request"},
+			misconfigured: true,
+		},
+		{
+			name:          "hugepages above node allocatable",
+			resources:     resources(cpuMemory("1", ""), []string{"hugepages-2Mi", "2Gi"}),
+			withNodes:     true,
+			want:          []string{"🚫 hugepages-2Mi of 2Gi exceeds the largest node allocatable (1Gi)"},
+			misconfigured: true,
+		},
+		{
+			name:      "no special resources",
+			resources: resources(cpuMemory("1", "1Gi"), cpuMemory("1", "1Gi")),
+			withNodes: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := newTestAnalyzer(t, gpuNodes()...)
+			if tt.withNodes {
+				nodes, err := a.GetNodes(context.Background())
+				if err != nil {
+					t.Fatal(err)
+				}
+				a.SetNodeCapacity(nodes)
+			}
+			got, misconfigured := a.specialResourceSuggestions(testContainer("app", tt.resources))
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") || misconfigured != tt.misconfigured {
+				t.Errorf("suggestions = %q, %v, want %q, %v", got, misconfigured, tt.want, tt.misconfigured)
+			}
+		})
+	}
+}
+
+// TestAnalyzePodsSpecialResources checks that a misconfigured GPU raises a
+// container with limits to MEDIUM risk.
+func TestAnalyzePodsSpecialResources(t *testing.T) {
+	a := newTestAnalyzer(t, gpuNodes()...)
+	nodes, err := a.GetNodes(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	a.SetNodeCapacity(nodes)
+
+	limits := append(cpuMemory("1", "1Gi"), "nvidia.com/gpu", "8")
+	pod := testPod("ml", "trainer", testContainer("trainer", resources(cpuMemory("1", "1Gi"), limits)))
+	results := a.AnalyzePods([]v1.Pod{*pod}, nil, 0.8)
+	if len(results) != 1 || results[0].RiskLevel != "MEDIUM" || !hasSuggestion(results[0].Suggestions, "exceeds the largest node allocatable") {
+		t.Errorf("results = %+v, want MEDIUM risk for 8 GPUs", results)
+	}
+}
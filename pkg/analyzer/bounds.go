@@ -0,0 +1,69 @@
+package analyzer
+
+import (
+	"fmt"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// BoundsPolicy sets the smallest and largest CPU and memory a container
+// may request or be limited to, like a LimitRange. Resources missing from
+// Min or Max are unbounded on that side.
+type BoundsPolicy struct {
+	Min v1.ResourceList
+	Max v1.ResourceList
+}
+
+// SetBoundsPolicy enables min/max checks and makes the generated
+// recommendations stay within the bounds.
+func (a *PodAnalyzer) SetBoundsPolicy(policy BoundsPolicy) {
+	a.boundsPolicy = policy
+}
+
+// boundsSuggestions reports requests and limits outside the policy bounds.
+func (a *PodAnalyzer) boundsSuggestions(container v1.Container) (suggestions []string, violated bool) {
+	check := func(kind string, list v1.ResourceList) {
+		for _, name := range []v1.ResourceName{v1.ResourceCPU, v1.ResourceMemory} {
+			value, ok := list[name]
+			if !ok {
+				continue
+			}
+			if min, ok := a.boundsPolicy.Min[name]; ok && value.Cmp(min) < 0 {
+				violated = true
+				suggestions = append(suggestions,
+					fmt.Sprintf("📏 %s %s %s is below the policy minimum %s", name, kind, value.String(), min.String()))
+			}
+			if max, ok := a.boundsPolicy.Max[name]; ok && value.Cmp(max) > 0 {
+				violated = true
+				suggestions = append(suggestions,
+					fmt.Sprintf("📏 %s %s %s exceeds the policy maximum %s", name, kind, value.String(), max.String()))
+			}
+		}
+	}
+	check("request", container.Resources.Requests)
+	check("limit", container.Resources.Limits)
+	return suggestions, violated
+}
+
+// applyBoundsPolicy clamps the recommended requests and limits into the
+// policy bounds.
+func (a *PodAnalyzer) applyBoundsPolicy(analysis *PodAnalysis) {
+	if analysis.RecommendedCPULimit == "" {
+		return
+	}
+
+	clamp := func(value *string, name v1.ResourceName) {
+		quantity := resource.MustParse(*value)
+		if min, ok := a.boundsPolicy.Min[name]; ok && quantity.Cmp(min) < 0 {
+			*value = min.String()
+		}
+		if max, ok := a.boundsPolicy.Max[name]; ok && quantity.Cmp(max) > 0 {
+			*value = max.String()
+		}
+	}
+	clamp(&analysis.RecommendedCPULimit, v1.ResourceCPU)
+	clamp(&analysis.RecommendedCPURequest, v1.ResourceCPU)
+	clamp(&analysis.RecommendedMemoryLimit, v1.ResourceMemory)
+	clamp(&analysis.RecommendedMemoryRequest, v1.ResourceMemory)
+}
@@ -0,0 +1,140 @@
+package analyzer
+
+import (
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+)
+
+func TestBoundsSuggestions(t *testing.T) {
+	policy := BoundsPolicy{
+		Min: resourceList("cpu", "100m", "memory", "128Mi"),
+		Max: resourceList("cpu", "2", "memory", "4Gi"),
+	}
+	tests := []struct {
+		name        string
+		policy      BoundsPolicy
+		resources   v1.ResourceRequirements
+		suggestions []string
+		violated    bool
+	}{
+		{
+			name:      "within bounds",
+			policy:    policy,
+			resources: resources(cpuMemory("100m", "128Mi"), cpuMemory("2", "4Gi")),
+		},
+		{
+			name:      "below the minimum",
+			policy:    policy,
+			resources: resources(cpuMemory("50m", "64Mi"), cpuMemory("500m", "512Mi")),
+			suggestions: []string{
+				"📏 cpu request 50m is below the policy minimum 100m",
+				"📏 memory request 64Mi is below the policy minimum 128Mi",
+			},
+			violated: true,
+		},
+		{
+			name:      "above the maximum",
+			policy:    policy,
+			resources: resources(cpuMemory("500m", "512Mi"), cpuMemory("4", "8Gi")),
+			suggestions: []string{
+				"📏 cpu limit 4 exceeds the policy maximum 2",
+				"📏 memory limit 8Gi exceeds the policy maximum 4Gi",
+			},
+			violated: true,
+		},
+		{
+			name:      "equal values in other units",
+			policy:    policy,
+			resources: resources(cpuMemory("0.1", "134217728"), cpuMemory("2000m", "4096Mi")),
+		},
+		{
+			name:      "unset resources are not checked",
+			policy:    policy,
+			resources: resources(nil, []string{"memory", "256Mi"}),
+		},
+		{
+			name:        "one-sided policy",
+			policy:      BoundsPolicy{Max: resourceList("memory", "1Gi")},
+			resources:   resources(cpuMemory("1m", "2Gi"), cpuMemory("64", "2Gi")),
+			suggestions: []string{"📏 memory request 2Gi exceeds the policy maximum 1Gi", "📏 memory limit 2Gi exceeds the policy maximum 1Gi"},
+			violated:    true,
+		},
+		{
+			name:      "other resources are ignored",
+			policy:    BoundsPolicy{Max: resourceList("ephemeral-storage", "1Gi")},
+			resources: resources([]string{"ephemeral-storage", "2Gi"}, []string{"ephemeral-storage", "2Gi"}),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := newTestAnalyzer(t)
+			a.SetBoundsPolicy(tt.policy)
+			suggestions, violated := a.boundsSuggestions(testContainer("app", tt.resources))
+
+			if violated != tt.violated {
+				t.Errorf("violated = %v, want %v", violated, tt.violated)
+			}
+			if len(suggestions) != len(tt.suggestions) {
+				t.Fatalf("suggestions = %q, want %q", suggestions, tt.suggestions)
+			}
+			for i := range suggestions {
+				if suggestions[i] != tt.suggestions[i] {
+					t.Errorf("suggestion %d = %q, want %q", i, suggestions[i], tt.suggestions[i])
+				}
+			}
+		})
+	}
+}
+
+func TestApplyBoundsPolicy(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy BoundsPolicy
+		// recommended and want are the CPU limit and request and the
+		// memory limit and request
+		recommended [4]string
+		want        [4]string
+	}{
+		{
+			name:        "clamped on both sides",
+			policy:      BoundsPolicy{Min: resourceList("cpu", "100m", "memory", "128Mi"), Max: resourceList("cpu", "1", "memory", "1Gi")},
+			recommended: [4]string{"1500m", "20m", "2Gi", "64Mi"},
+			want:        [4]string{"1", "100m", "1Gi", "128Mi"},
+		},
+		{
+			name:        "within bounds",
+			policy:      BoundsPolicy{Min: resourceList("cpu", "100m"), Max: resourceList("memory", "1Gi")},
+			recommended: [4]string{"500m", "250m", "512Mi", "256Mi"},
+			want:        [4]string{"500m", "250m", "512Mi", "256Mi"},
+		},
+		{
+			name:        "no policy",
+			recommended: [4]string{"8", "10m", "64Gi", "1Mi"},
+			want:        [4]string{"8", "10m", "64Gi", "1Mi"},
+		},
+		{
+			name:   "no recommendation",
+			policy: BoundsPolicy{Min: resourceList("cpu", "100m")},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := newTestAnalyzer(t)
+			a.SetBoundsPolicy(tt.policy)
+			analysis := PodAnalysis{
+				RecommendedCPULimit:      tt.recommended[0],
+				RecommendedCPURequest:    tt.recommended[1],
+				RecommendedMemoryLimit:   tt.recommended[2],
+				RecommendedMemoryRequest: tt.recommended[3],
+			}
+			a.applyBoundsPolicy(&analysis)
+
+			got := [4]string{analysis.RecommendedCPULimit, analysis.RecommendedCPURequest,
+				analysis.RecommendedMemoryLimit, analysis.RecommendedMemoryRequest}
+			if got != tt.want {
+				t.Errorf("recommendation = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,142 @@
+package analyzer
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// IsExtendedResource reports whether name is an extended resource such as
+// nvidia.com/gpu: a domain-qualified name outside kubernetes.io.
+func IsExtendedResource(name v1.ResourceName) bool {
+	s := string(name)
+	if !strings.Contains(s, "/") || strings.HasPrefix(s, v1.DefaultResourceRequestsPrefix) {
+		return false
+	}
+	domain := s[:strings.Index(s, "/")]
+	return domain != "kubernetes.io" && !strings.HasSuffix(domain, ".kubernetes.io")
+}
+
+// IsHugePages reports whether name is a hugepages-<size> resource.
+func IsHugePages(name v1.ResourceName) bool {
+	return strings.HasPrefix(string(name), v1.ResourceHugePagesPrefix)
+}
+
+// IsSpecialResource reports whether name is an extended or hugepages
+// resource, i.e. one that cannot be overcommitted.
+func IsSpecialResource(name v1.ResourceName) bool {
+	return IsExtendedResource(name) || IsHugePages(name)
+}
+
+// UsesSpecialResources reports whether any container of any pod requests
+// or limits an extended or hugepages resource.
+func UsesSpecialResources(pods []v1.Pod) bool {
+	for _, pod := range pods {
+		for _, container := range append(pod.Spec.InitContainers, pod.Spec.Containers...) {
+			for name := range container.Resources.Limits {
+				if IsSpecialResource(name) {
+					return true
+				}
+			}
+			for name := range container.Resources.Requests {
+				if IsSpecialResource(name) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// SetNodeCapacity records the largest allocatable amount of each extended
+// and hugepages resource across nodes, used to detect pods requesting
+// resources no node can provide.
+func (a *PodAnalyzer) SetNodeCapacity(nodes []v1.Node) {
+	a.nodeCapacity = map[v1.ResourceName]resource.Quantity{}
+	for _, node := range nodes {
+		for name, quantity := range node.Status.Allocatable {
+			if !IsSpecialResource(name) {
+				continue
+			}
+			if current, ok := a.nodeCapacity[name]; !ok || quantity.Cmp(current) > 0 {
+				a.nodeCapacity[name] = quantity
+			}
+		}
+	}
+}
+
+// specialResourceSuggestions validates extended resources and hugepages:
+// requests must equal limits, a request needs a limit, hugepages need a
+// memory or CPU request, and some node must offer the requested amount.
+// misconfigured is set when the container breaks one of these rules.
+func (a *PodAnalyzer) specialResourceSuggestions(container v1.Container) (suggestions []string, misconfigured bool) {
+	names := map[v1.ResourceName]bool{}
+	for name := range container.Resources.Limits {
+		if IsSpecialResource(name) {
+			names[name] = true
+		}
+	}
+	for name := range container.Resources.Requests {
+		if IsSpecialResource(name) {
+			names[name] = true
+		}
+	}
+
+	sorted := make([]string, 0, len(names))
+	for name := range names {
+		sorted = append(sorted, string(name))
+	}
+	sort.Strings(sorted)
+
+	usesHugePages := false
+	for _, s := range sorted {
+		name := v1.ResourceName(s)
+		limit, hasLimit := container.Resources.Limits[name]
+		request, hasRequest := container.Resources.Requests[name]
+
+		switch {
+		case hasRequest && !hasLimit:
+			suggestions = append(suggestions, fmt.Sprintf("🚫 %s is requested without a limit; it cannot be overcommitted, set limit = request", name))
+			misconfigured = true
+		case hasRequest && request.Cmp(limit) != 0:
+			suggestions = append(suggestions, fmt.Sprintf("🚫 %s request (%s) must equal its limit (%s)", name, request.String(), limit.String()))
+			misconfigured = true
+		}
+
+		if IsHugePages(name) {
+			usesHugePages = true
+		}
+
+		if a.nodeCapacity == nil {
+			continue
+		}
+		wanted := limit
+		if !hasLimit {
+			wanted = request
+		}
+		capacity, offered := a.nodeCapacity[name]
+		switch {
+		case !offered && !wanted.IsZero():
+			suggestions = append(suggestions, fmt.Sprintf("🚫 No node advertises %s; the pod cannot be scheduled", name))
+			misconfigured = true
+		case offered && wanted.Cmp(capacity) > 0:
+			suggestions = append(suggestions, fmt.Sprintf("🚫 %s of %s exceeds the largest node allocatable (%s)", name, wanted.String(), capacity.String()))
+			misconfigured = true
+		}
+	}
+
+	if usesHugePages {
+		_, hasMemRequest := container.Resources.Requests[v1.ResourceMemory]
+		_, hasCPURequest := container.Resources.Requests[v1.ResourceCPU]
+		_, hasMemLimit := container.Resources.Limits[v1.ResourceMemory]
+		if !hasMemRequest && !hasMemLimit && !hasCPURequest {
+			suggestions = append(suggestions, "🚫 Hugepages require a memory or CPU request")
+			misconfigured = true
+		}
+	}
+
+	return suggestions, misconfigured
+}
@@ -0,0 +1,137 @@
+package analyzer
+
+import (
+	"context"
+	"fmt"
+
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// HPAImpact describes how an HPA utilization target on one of the
+// container's resources translates into absolute usage, now and with the
+// recommended request. Utilization is relative to requests, so a smaller
+// request makes the HPA scale out at lower absolute usage.
+type HPAImpact struct {
+	HPA               string
+	Resource          v1.ResourceName
+	TargetUtilization int32
+	// CurrentThreshold and RecommendedThreshold are the per-container
+	// usages at which the target is reached; RecommendedThreshold is empty
+	// when there is no recommended request.
+	CurrentThreshold     string
+	RecommendedThreshold string
+	ScalesDifferently    bool
+}
+
+func (a *PodAnalyzer) GetHPAs(ctx context.Context, namespace string) ([]autoscalingv2.HorizontalPodAutoscaler, error) {
+	hpas, err := a.client.Clientset.AutoscalingV2().HorizontalPodAutoscalers(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	return hpas.Items, nil
+}
+
+// SetHPAs enables HPA conflict detection.
+func (a *PodAnalyzer) SetHPAs(hpas []autoscalingv2.HorizontalPodAutoscaler) {
+	a.hpas = map[string]autoscalingv2.HorizontalPodAutoscaler{}
+	for _, hpa := range hpas {
+		ref := hpa.Spec.ScaleTargetRef
+		a.hpas[workloadKey(hpa.Namespace, ref.Kind, ref.Name)] = hpa
+	}
+}
+
+// utilizationTargets returns the HPA's utilization targets that apply to
+// the container: pod-level Resource metrics and ContainerResource metrics
+// naming it. AverageValue targets do not depend on requests and are
+// skipped.
+func utilizationTargets(hpa autoscalingv2.HorizontalPodAutoscaler, containerName string) map[v1.ResourceName]int32 {
+	targets := map[v1.ResourceName]int32{}
+	for _, metric := range hpa.Spec.Metrics {
+		var name v1.ResourceName
+		var target autoscalingv2.MetricTarget
+		switch {
+		case metric.Type == autoscalingv2.ResourceMetricSourceType && metric.Resource != nil:
+			name, target = metric.Resource.Name, metric.Resource.Target
+		case metric.Type == autoscalingv2.ContainerResourceMetricSourceType && metric.ContainerResource != nil &&
+			metric.ContainerResource.Container == containerName:
+			name, target = metric.ContainerResource.Name, metric.ContainerResource.Target
+		default:
+			continue
+		}
+		if target.Type == autoscalingv2.UtilizationMetricType && target.AverageUtilization != nil {
+			targets[name] = *target.AverageUtilization
+		}
+	}
+	return targets
+}
+
+// hpaSuggestions checks the container against the HPA scaling its workload:
+// utilization metrics need a request, and changing the request moves the
+// absolute usage at which the HPA scales. misconfigured is set when a
+// metered resource has no request.
+func (a *PodAnalyzer) hpaSuggestions(analysis *PodAnalysis, container v1.Container) (suggestions []string, misconfigured bool) {
+	hpa, ok := a.hpas[workloadKey(analysis.Namespace, analysis.WorkloadKind, analysis.WorkloadName)]
+	if !ok {
+		return nil, false
+	}
+
+	targets := utilizationTargets(hpa, container.Name)
+	for _, name := range []v1.ResourceName{v1.ResourceCPU, v1.ResourceMemory} {
+		utilization, metered := targets[name]
+		if !metered {
+			continue
+		}
+
+		request, hasRequest := container.Resources.Requests[name]
+		if !hasRequest {
+			// A missing request defaults to the limit, if any
+			request, hasRequest = container.Resources.Limits[name]
+		}
+		if !hasRequest || request.IsZero() {
+			suggestions = append(suggestions,
+				fmt.Sprintf("🚫 HPA %s scales on %s utilization but the container has no %s request", hpa.Name, name, name))
+			misconfigured = true
+			continue
+		}
+
+		impact := HPAImpact{
+			HPA:               hpa.Name,
+			Resource:          name,
+			TargetUtilization: utilization,
+			CurrentThreshold:  utilizationThreshold(name, request, utilization),
+		}
+
+		recommended := analysis.RecommendedCPURequest
+		if name == v1.ResourceMemory {
+			recommended = analysis.RecommendedMemoryRequest
+		}
+		if recommended != "" {
+			if newRequest, err := resource.ParseQuantity(recommended); err == nil && !newRequest.IsZero() {
+				impact.RecommendedThreshold = utilizationThreshold(name, newRequest, utilization)
+				impact.ScalesDifferently = newRequest.Cmp(request) != 0
+			}
+		}
+		analysis.HPAImpacts = append(analysis.HPAImpacts, impact)
+
+		if impact.ScalesDifferently {
+			suggestions = append(suggestions,
+				fmt.Sprintf("📈 HPA %s targets %d%% %s: scales at %s per container now, %s with the recommended request",
+					hpa.Name, utilization, name, impact.CurrentThreshold, impact.RecommendedThreshold))
+		}
+	}
+
+	return suggestions, misconfigured
+}
+
+// utilizationThreshold is the usage at which utilization percent of request
+// is reached, in millicores for CPU and Mi for memory.
+func utilizationThreshold(name v1.ResourceName, request resource.Quantity, utilization int32) string {
+	if name == v1.ResourceCPU {
+		return fmt.Sprintf("%dm", request.MilliValue()*int64(utilization)/100)
+	}
+	return fmt.Sprintf("%dMi", request.Value()*int64(utilization)/100/(1024*1024))
+}
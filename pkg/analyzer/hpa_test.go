@@ -0,0 +1,176 @@
+package analyzer
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	metricsv1beta1 "k8s.io/metrics/pkg/apis/metrics/v1beta1"
+)
+
+func utilization(percent int32) autoscalingv2.MetricTarget {
+	return autoscalingv2.MetricTarget{Type: autoscalingv2.UtilizationMetricType, AverageUtilization: &percent}
+}
+
+func resourceMetric(name v1.ResourceName, target autoscalingv2.MetricTarget) autoscalingv2.MetricSpec {
+	return autoscalingv2.MetricSpec{
+		Type:     autoscalingv2.ResourceMetricSourceType,
+		Resource: &autoscalingv2.ResourceMetricSource{Name: name, Target: target},
+	}
+}
+
+func containerMetric(container string, name v1.ResourceName, target autoscalingv2.MetricTarget) autoscalingv2.MetricSpec {
+	return autoscalingv2.MetricSpec{
+		Type:              autoscalingv2.ContainerResourceMetricSourceType,
+		ContainerResource: &autoscalingv2.ContainerResourceMetricSource{Name: name, Container: container, Target: target},
+	}
+}
+
+func testHPA(namespace, name, kind, target string, metrics ...autoscalingv2.MetricSpec) *autoscalingv2.HorizontalPodAutoscaler {
+	return &autoscalingv2.HorizontalPodAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+		Spec: autoscalingv2.HorizontalPodAutoscalerSpec{
+			ScaleTargetRef: autoscalingv2.CrossVersionObjectReference{APIVersion: "apps/v1", Kind: kind, Name: target},
+			Metrics:        metrics,
+		},
+	}
+}
+
+// deploymentPod is a pod of the Deployment name, as created through its
+// ReplicaSet.
+func deploymentPod(namespace, name string, containers ...v1.Container) *v1.Pod {
+	pod := testPod(namespace, name+"-7d9f8-x2x4z", containers...)
+	pod.Labels = map[string]string{"pod-template-hash": "7d9f8"}
+	controller := true
+	pod.OwnerReferences = []metav1.OwnerReference{{Kind: "ReplicaSet", Name: name + "-7d9f8", Controller: &controller}}
+	return pod
+}
+
+func TestGetHPAs(t *testing.T) {
+	a := newTestAnalyzer(t,
+		testHPA("web", "api", "Deployment", "api", resourceMetric(v1.ResourceCPU, utilization(80))),
+		testHPA("jobs", "worker", "StatefulSet", "worker"),
+	)
+	ctx := context.Background()
+
+	hpas, err := a.GetHPAs(ctx, "web")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hpas) != 1 || hpas[0].Name != "api" {
+		t.Errorf("HPAs in web = %v, want [api]", hpas)
+	}
+	if hpas, err := a.GetHPAs(ctx, ""); err != nil || len(hpas) != 2 {
+		t.Errorf("got %d HPAs (err %v), want 2", len(hpas), err)
+	}
+}
+
+func TestUtilizationTargets(t *testing.T) {
+	averageValue := autoscalingv2.MetricTarget{Type: autoscalingv2.AverageValueMetricType}
+	hpa := testHPA("web", "api", "Deployment", "api",
+		resourceMetric(v1.ResourceCPU, utilization(80)),
+		containerMetric("app", v1.ResourceMemory, utilization(70)),
+		containerMetric("proxy", v1.ResourceMemory, utilization(90)),
+		// Does not depend on requests
+		resourceMetric(v1.ResourceMemory, averageValue),
+		autoscalingv2.MetricSpec{Type: autoscalingv2.PodsMetricSourceType},
+	)
+
+	for container, want := range map[string]map[v1.ResourceName]int32{
+		"app":     {v1.ResourceCPU: 80, v1.ResourceMemory: 70},
+		"proxy":   {v1.ResourceCPU: 80, v1.ResourceMemory: 90},
+		"sidecar": {v1.ResourceCPU: 80},
+	} {
+		if got := utilizationTargets(*hpa, container); !reflect.DeepEqual(got, want) {
+			t.Errorf("targets of %s = %v, want %v", container, got, want)
+		}
+	}
+}
+
+func TestHPASuggestions(t *testing.T) {
+	tests := []struct {
+		name      string
+		hpa       *autoscalingv2.HorizontalPodAutoscaler
+		resources v1.ResourceRequirements
+		want      []string
+		impacts   []HPAImpact
+		risk      string
+	}{
+		{
+			name:      "cpu utilization target",
+			hpa:       testHPA("web", "api", "Deployment", "api", resourceMetric(v1.ResourceCPU, utilization(80))),
+			resources: resources(cpuMemory("1", "512Mi"), cpuMemory("2", "1Gi")),
+			want:      []string{"📈 HPA api targets 80% cpu: scales at 800m per container now, 76m with the recommended request"},
+			impacts: []HPAImpact{{
+				HPA: "api", Resource: v1.ResourceCPU, TargetUtilization: 80,
+				CurrentThreshold: "800m", RecommendedThreshold: "76m", ScalesDifferently: true,
+			}},
+			risk: "LOW",
+		},
+		{
+			name:      "request defaults to the limit",
+			hpa:       testHPA("web", "api", "Deployment", "api", containerMetric("app", v1.ResourceMemory, utilization(50))),
+			resources: resources(nil, cpuMemory("2", "1Gi")),
+			want:      []string{"📈 HPA api targets 50% memory: scales at 512Mi per container now, 76Mi with the recommended request"},
+			impacts: []HPAImpact{{
+				HPA: "api", Resource: v1.ResourceMemory, TargetUtilization: 50,
+				CurrentThreshold: "512Mi", RecommendedThreshold: "76Mi", ScalesDifferently: true,
+			}},
+			risk: "LOW",
+		},
+		{
+			name:      "no cpu request",
+			hpa:       testHPA("web", "api", "Deployment", "api", resourceMetric(v1.ResourceCPU, utilization(80))),
+			resources: resources([]string{"memory", "512Mi"}, []string{"memory", "1Gi"}),
+			want:      []string{"🚫 HPA api scales on cpu utilization but the container has no cpu request"},
+			risk:      "MEDIUM",
+		},
+		{
+			name:      "HPA of another workload",
+			hpa:       testHPA("web", "checkout", "Deployment", "checkout", resourceMetric(v1.ResourceCPU, utilization(80))),
+			resources: resources(cpuMemory("1", "512Mi"), cpuMemory("2", "1Gi")),
+			risk:      "LOW",
+		},
+		{
+			name:      "no HPA",
+			resources: resources(cpuMemory("1", "512Mi"), cpuMemory("2", "1Gi")),
+			risk:      "LOW",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := newTestAnalyzer(t)
+			if tt.hpa != nil {
+				a.SetHPAs([]autoscalingv2.HorizontalPodAutoscaler{*tt.hpa})
+			}
+			pod := deploymentPod("web", "api", testContainer("app", tt.resources))
+			metrics := testPodMetrics("web", pod.Name, "app", "80m", "128Mi")
+
+			results := a.AnalyzePods([]v1.Pod{*pod}, []metricsv1beta1.PodMetrics{*metrics}, 0.8)
+			if len(results) != 1 {
+				t.Fatalf("got %d results, want 1", len(results))
+			}
+			r := results[0]
+			var got []string
+			for _, s := range r.Suggestions {
+				if strings.Contains(s, "HPA") {
+					got = append(got, s)
+				}
+			}
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("HPA suggestions = %q, want %q", got, tt.want)
+			}
+			if !reflect.DeepEqual(r.HPAImpacts, tt.impacts) {
+				t.Errorf("impacts = %+v, want %+v", r.HPAImpacts, tt.impacts)
+			}
+			if r.RiskLevel != tt.risk {
+				t.Errorf("risk = %s, want %s", r.RiskLevel, tt.risk)
+			}
+		})
+	}
+}
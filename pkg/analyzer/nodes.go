@@ -0,0 +1,217 @@
+package analyzer
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	metricsv1beta1 "k8s.io/metrics/pkg/apis/metrics/v1beta1"
+)
+
+// evictionProneMemoryUsage is the share of allocatable memory in use above
+// which a node is considered close to kubelet memory eviction.
+const evictionProneMemoryUsage = 0.9
+
+// maxNodeContributors bounds the pods reported per node.
+const maxNodeContributors = 5
+
+type NodeAnalysis struct {
+	Name string
+	// Allocatable, Requests, Limits and Usage are millicores for CPU and
+	// bytes for memory.
+	AllocatableCPU    int64
+	AllocatableMemory int64
+	RequestsCPU       int64
+	RequestsMemory    int64
+	LimitsCPU         int64
+	LimitsMemory      int64
+	UsageCPU          *int64
+	UsageMemory       *int64
+	PodCount          int
+	// UnboundedPods counts pods with at least one container lacking a CPU
+	// or memory limit; their real ceiling is the whole node.
+	UnboundedPods   int
+	Overcommitted   bool
+	EvictionProne   bool
+	Reasons         []string
+	TopContributors []NodeContributor
+}
+
+// NodeContributor is a pod's share of a node's committed resources.
+type NodeContributor struct {
+	Namespace      string
+	PodName        string
+	RequestsCPU    int64
+	RequestsMemory int64
+	LimitsCPU      int64
+	LimitsMemory   int64
+	Unbounded      bool
+}
+
+func (a *PodAnalyzer) GetNodeMetrics(ctx context.Context) ([]metricsv1beta1.NodeMetrics, error) {
+	if a.client.MetricsClient == nil {
+		return nil, errNoMetrics
+	}
+	metrics, err := a.client.MetricsClient.MetricsV1beta1().NodeMetricses().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	return metrics.Items, nil
+}
+
+// AnalyzeNodes sums the requests and limits of the pods scheduled on each
+// node against its allocatable capacity. A node is overcommitted when the
+// sum of CPU or memory limits exceeds overcommitRatio times allocatable.
+// pods must cover all namespaces for the sums to be meaningful.
+func (a *PodAnalyzer) AnalyzeNodes(nodes []v1.Node, pods []v1.Pod, nodeMetrics []metricsv1beta1.NodeMetrics, overcommitRatio float64) []NodeAnalysis {
+	metricsMap := make(map[string]metricsv1beta1.NodeMetrics)
+	for _, nm := range nodeMetrics {
+		metricsMap[nm.Name] = nm
+	}
+
+	podsByNode := make(map[string][]v1.Pod)
+	for _, pod := range pods {
+		if pod.Spec.NodeName == "" || pod.Status.Phase == v1.PodSucceeded || pod.Status.Phase == v1.PodFailed {
+			continue
+		}
+		podsByNode[pod.Spec.NodeName] = append(podsByNode[pod.Spec.NodeName], pod)
+	}
+
+	var results []NodeAnalysis
+	for _, node := range nodes {
+		analysis := NodeAnalysis{
+			Name:              node.Name,
+			AllocatableCPU:    node.Status.Allocatable.Cpu().MilliValue(),
+			AllocatableMemory: node.Status.Allocatable.Memory().Value(),
+		}
+
+		for _, pod := range podsByNode[node.Name] {
+			contributor := podContribution(pod)
+			analysis.PodCount++
+			analysis.RequestsCPU += contributor.RequestsCPU
+			analysis.RequestsMemory += contributor.RequestsMemory
+			analysis.LimitsCPU += contributor.LimitsCPU
+			analysis.LimitsMemory += contributor.LimitsMemory
+			if contributor.Unbounded {
+				analysis.UnboundedPods++
+			}
+			analysis.TopContributors = append(analysis.TopContributors, contributor)
+		}
+
+		if nm, exists := metricsMap[node.Name]; exists {
+			cpu := nm.Usage.Cpu().MilliValue()
+			mem := nm.Usage.Memory().Value()
+			analysis.UsageCPU = &cpu
+			analysis.UsageMemory = &mem
+		}
+
+		a.assessNode(&analysis, node, overcommitRatio)
+		sortContributors(&analysis)
+
+		results = append(results, analysis)
+	}
+
+	return results
+}
+
+func (a *PodAnalyzer) assessNode(analysis *NodeAnalysis, node v1.Node, overcommitRatio float64) {
+	if cpuRatio := ratio(analysis.LimitsCPU, analysis.AllocatableCPU); cpuRatio > overcommitRatio {
+		analysis.Overcommitted = true
+		analysis.Reasons = append(analysis.Reasons,
+			fmt.Sprintf("CPU limits at %.0f%% of allocatable", cpuRatio*100))
+	}
+	if memRatio := ratio(analysis.LimitsMemory, analysis.AllocatableMemory); memRatio > overcommitRatio {
+		analysis.Overcommitted = true
+		analysis.Reasons = append(analysis.Reasons,
+			fmt.Sprintf("memory limits at %.0f%% of allocatable", memRatio*100))
+	}
+
+	if analysis.UsageMemory != nil && ratio(*analysis.UsageMemory, analysis.AllocatableMemory) >= evictionProneMemoryUsage {
+		analysis.EvictionProne = true
+		analysis.Reasons = append(analysis.Reasons, "memory usage near allocatable")
+	}
+	for _, condition := range node.Status.Conditions {
+		if condition.Status != v1.ConditionTrue {
+			continue
+		}
+		switch condition.Type {
+		case v1.NodeMemoryPressure:
+			analysis.EvictionProne = true
+			analysis.Reasons = append(analysis.Reasons, "MemoryPressure condition")
+		case v1.NodeDiskPressure:
+			analysis.EvictionProne = true
+			analysis.Reasons = append(analysis.Reasons, "DiskPressure condition")
+		}
+	}
+}
+
+// podContribution returns the effective requests and limits of a pod: the
+// larger of the sum over app containers and the largest init container,
+// plus pod overhead, mirroring how the scheduler accounts for them.
+func podContribution(pod v1.Pod) NodeContributor {
+	contributor := NodeContributor{Namespace: pod.Namespace, PodName: pod.Name}
+
+	for _, container := range pod.Spec.Containers {
+		contributor.RequestsCPU += container.Resources.Requests.Cpu().MilliValue()
+		contributor.RequestsMemory += container.Resources.Requests.Memory().Value()
+		contributor.LimitsCPU += container.Resources.Limits.Cpu().MilliValue()
+		contributor.LimitsMemory += container.Resources.Limits.Memory().Value()
+
+		_, hasCPULimit := container.Resources.Limits[v1.ResourceCPU]
+		_, hasMemLimit := container.Resources.Limits[v1.ResourceMemory]
+		if !hasCPULimit || !hasMemLimit {
+			contributor.Unbounded = true
+		}
+	}
+
+	for _, container := range pod.Spec.InitContainers {
+		contributor.RequestsCPU = max(contributor.RequestsCPU, container.Resources.Requests.Cpu().MilliValue())
+		contributor.RequestsMemory = max(contributor.RequestsMemory, container.Resources.Requests.Memory().Value())
+		contributor.LimitsCPU = max(contributor.LimitsCPU, container.Resources.Limits.Cpu().MilliValue())
+		contributor.LimitsMemory = max(contributor.LimitsMemory, container.Resources.Limits.Memory().Value())
+	}
+
+	if pod.Spec.Overhead != nil {
+		contributor.RequestsCPU += pod.Spec.Overhead.Cpu().MilliValue()
+		contributor.RequestsMemory += pod.Spec.Overhead.Memory().Value()
+		contributor.LimitsCPU += pod.Spec.Overhead.Cpu().MilliValue()
+		contributor.LimitsMemory += pod.Spec.Overhead.Memory().Value()
+	}
+
+	return contributor
+}
+
+// sortContributors keeps the pods with the largest share of the node's
+// limits, unbounded pods first since their ceiling is the whole node.
+func sortContributors(analysis *NodeAnalysis) {
+	share := func(c NodeContributor) float64 {
+		cpu := ratio(c.LimitsCPU, analysis.AllocatableCPU)
+		mem := ratio(c.LimitsMemory, analysis.AllocatableMemory)
+		if cpu > mem {
+			return cpu
+		}
+		return mem
+	}
+
+	contributors := analysis.TopContributors
+	sort.SliceStable(contributors, func(i, j int) bool {
+		if contributors[i].Unbounded != contributors[j].Unbounded {
+			return contributors[i].Unbounded
+		}
+		return share(contributors[i]) > share(contributors[j])
+	})
+	if len(contributors) > maxNodeContributors {
+		contributors = contributors[:maxNodeContributors]
+	}
+	analysis.TopContributors = contributors
+}
+
+func ratio(value, total int64) float64 {
+	if total <= 0 {
+		return 0
+	}
+	return float64(value) / float64(total)
+}
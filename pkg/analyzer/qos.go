@@ -0,0 +1,178 @@
+package analyzer
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// QoSPolicy requires a minimum QoS class for pods in namespaces carrying
+// the label LabelKey=LabelValue.
+type QoSPolicy struct {
+	LabelKey   string
+	LabelValue string
+	Class      v1.PodQOSClass
+}
+
+// ParseQoSPolicy parses "key=value:Class", e.g. "tier=critical:Guaranteed".
+func ParseQoSPolicy(s string) (QoSPolicy, error) {
+	selector, class, ok := strings.Cut(s, ":")
+	if !ok {
+		return QoSPolicy{}, fmt.Errorf("invalid QoS policy %q, expected key=value:Class", s)
+	}
+	key, value, ok := strings.Cut(selector, "=")
+	if !ok || key == "" {
+		return QoSPolicy{}, fmt.Errorf("invalid namespace label selector %q in QoS policy", selector)
+	}
+
+	for _, known := range []v1.PodQOSClass{v1.PodQOSGuaranteed, v1.PodQOSBurstable, v1.PodQOSBestEffort} {
+		if strings.EqualFold(class, string(known)) {
+			return QoSPolicy{LabelKey: key, LabelValue: value, Class: known}, nil
+		}
+	}
+	return QoSPolicy{}, fmt.Errorf("unknown QoS class %q (valid: Guaranteed, Burstable, BestEffort)", class)
+}
+
+func (p QoSPolicy) String() string {
+	return fmt.Sprintf("%s=%s:%s", p.LabelKey, p.LabelValue, p.Class)
+}
+
+// SetQoSPolicies configures the QoS policies and the namespace labels they
+// are matched against.
+func (a *PodAnalyzer) SetQoSPolicies(policies []QoSPolicy, namespaceLabels map[string]map[string]string) {
+	a.qosPolicies = policies
+	a.namespaceLabels = namespaceLabels
+}
+
+func (a *PodAnalyzer) GetNamespaceLabels(ctx context.Context) (map[string]map[string]string, error) {
+	namespaces, err := a.client.Clientset.CoreV1().Namespaces().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	labels := make(map[string]map[string]string, len(namespaces.Items))
+	for _, ns := range namespaces.Items {
+		labels[ns.Name] = ns.Labels
+	}
+	return labels, nil
+}
+
+// requiredQoS returns the strictest QoS class required for a namespace, or
+// "" when no policy applies.
+func (a *PodAnalyzer) requiredQoS(namespace string) (v1.PodQOSClass, string) {
+	var required v1.PodQOSClass
+	var reason string
+	labels := a.namespaceLabels[namespace]
+	for _, policy := range a.qosPolicies {
+		if labels[policy.LabelKey] != policy.LabelValue {
+			continue
+		}
+		if qosRank(policy.Class) > qosRank(required) {
+			required = policy.Class
+			reason = fmt.Sprintf("namespaces labelled %s=%s", policy.LabelKey, policy.LabelValue)
+		}
+	}
+	return required, reason
+}
+
+func qosRank(class v1.PodQOSClass) int {
+	switch class {
+	case v1.PodQOSBestEffort:
+		return 1
+	case v1.PodQOSBurstable:
+		return 2
+	case v1.PodQOSGuaranteed:
+		return 3
+	}
+	return 0
+}
+
+// PodQOSClass computes a pod's QoS class from its containers' requests and
+// limits, following the kubelet's rules: only CPU and memory count, a
+// missing request defaults to the limit, and every container must have
+// equal requests and limits for both resources to be Guaranteed.
+func PodQOSClass(containers []v1.ResourceRequirements) v1.PodQOSClass {
+	isGuaranteed := true
+	hasAny := false
+
+	for _, res := range containers {
+		for _, name := range []v1.ResourceName{v1.ResourceCPU, v1.ResourceMemory} {
+			limit, hasLimit := res.Limits[name]
+			request, hasRequest := res.Requests[name]
+			if hasLimit && !limit.IsZero() || hasRequest && !request.IsZero() {
+				hasAny = true
+			}
+			if !hasLimit || limit.IsZero() {
+				isGuaranteed = false
+				continue
+			}
+			if hasRequest && request.Cmp(limit) != 0 {
+				isGuaranteed = false
+			}
+		}
+	}
+
+	switch {
+	case !hasAny:
+		return v1.PodQOSBestEffort
+	case isGuaranteed:
+		return v1.PodQOSGuaranteed
+	default:
+		return v1.PodQOSBurstable
+	}
+}
+
+// recommendedResources returns a container's resources after applying the
+// analyzer's recommendation, or its current resources when there is none.
+func recommendedResources(analysis *PodAnalysis, current v1.ResourceRequirements) v1.ResourceRequirements {
+	if analysis.RecommendedCPULimit == "" || analysis.RecommendedMemoryLimit == "" {
+		return current
+	}
+	return v1.ResourceRequirements{
+		Limits: v1.ResourceList{
+			v1.ResourceCPU:    resource.MustParse(analysis.RecommendedCPULimit),
+			v1.ResourceMemory: resource.MustParse(analysis.RecommendedMemoryLimit),
+		},
+		Requests: v1.ResourceList{
+			v1.ResourceCPU:    resource.MustParse(analysis.RecommendedCPURequest),
+			v1.ResourceMemory: resource.MustParse(analysis.RecommendedMemoryRequest),
+		},
+	}
+}
+
+// applyQoS records the pod's current and projected QoS class on each of its
+// container analyses and flags violations of the required class.
+func (a *PodAnalyzer) applyQoS(pod v1.Pod, analyses []PodAnalysis, required v1.PodQOSClass, reason string) {
+	var current, projected []v1.ResourceRequirements
+	for _, container := range pod.Spec.InitContainers {
+		current = append(current, container.Resources)
+		projected = append(projected, container.Resources)
+	}
+	for i, container := range pod.Spec.Containers {
+		current = append(current, container.Resources)
+		projected = append(projected, recommendedResources(&analyses[i], container.Resources))
+	}
+
+	currentClass := PodQOSClass(current)
+	projectedClass := PodQOSClass(projected)
+
+	for i := range analyses {
+		analyses[i].QoSClass = string(currentClass)
+		analyses[i].RecommendedQoSClass = string(projectedClass)
+		analyses[i].RequiredQoSClass = string(required)
+
+		if required == "" || qosRank(currentClass) >= qosRank(required) {
+			continue
+		}
+		analyses[i].QoSViolation = true
+		analyses[i].Suggestions = append(analyses[i].Suggestions,
+			fmt.Sprintf("🚫 Pod QoS is %s, %s require %s", currentClass, reason, required))
+		if analyses[i].RiskLevel == "LOW" {
+			analyses[i].RiskLevel = "MEDIUM"
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package analyzer
+
+import (
+	"fmt"
+	"math"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// RatioPolicy caps how far a limit may exceed its request. A maximum of 0
+// leaves that resource unchecked; 1 requires limit == request.
+type RatioPolicy struct {
+	MaxCPU    float64
+	MaxMemory float64
+}
+
+// SetRatioPolicy enables limit-to-request ratio checks and makes the
+// generated recommendations stay within the policy.
+func (a *PodAnalyzer) SetRatioPolicy(policy RatioPolicy) {
+	a.ratioPolicy = policy
+}
+
+// limitRequestRatio returns limit/request for a resource. A missing request
+// defaults to the limit, as the API server does; without a limit or with a
+// zero request there is no meaningful ratio and 0 is returned.
+func limitRequestRatio(resources v1.ResourceRequirements, name v1.ResourceName) float64 {
+	limit, hasLimit := resources.Limits[name]
+	if !hasLimit || limit.IsZero() {
+		return 0
+	}
+	request, hasRequest := resources.Requests[name]
+	if !hasRequest {
+		return 1
+	}
+	if request.IsZero() {
+		return 0
+	}
+	return float64(limit.MilliValue()) / float64(request.MilliValue())
+}
+
+// ratioSuggestions reports current ratios above the policy maximums.
+func (a *PodAnalyzer) ratioSuggestions(analysis *PodAnalysis) (suggestions []string, violated bool) {
+	check := func(label string, ratio, max float64) {
+		if max <= 0 || ratio <= max {
+			return
+		}
+		violated = true
+		if max == 1 {
+			suggestions = append(suggestions,
+				fmt.Sprintf("📐 %s limit is %.1fx request, policy requires limit = request", label, ratio))
+			return
+		}
+		suggestions = append(suggestions,
+			fmt.Sprintf("📐 %s limit is %.1fx request, policy allows at most %.1fx", label, ratio, max))
+	}
+	check("CPU", analysis.CPULimitRatio, a.ratioPolicy.MaxCPU)
+	check("Memory", analysis.MemoryLimitRatio, a.ratioPolicy.MaxMemory)
+	return suggestions, violated
+}
+
+// applyRatioPolicy raises recommended requests until limit/request is
+// within the policy. Requests are raised rather than limits lowered so the
+// recommendation never tightens the ceiling derived from usage.
+func (a *PodAnalyzer) applyRatioPolicy(analysis *PodAnalysis) {
+	if analysis.RecommendedCPULimit == "" {
+		return
+	}
+
+	if a.ratioPolicy.MaxCPU > 0 {
+		limit := resource.MustParse(analysis.RecommendedCPULimit)
+		request := resource.MustParse(analysis.RecommendedCPURequest)
+		if minRequest := int64(math.Ceil(float64(limit.MilliValue()) / a.ratioPolicy.MaxCPU)); request.MilliValue() < minRequest {
+			analysis.RecommendedCPURequest = fmt.Sprintf("%dm", minRequest)
+		}
+	}
+
+	if a.ratioPolicy.MaxMemory > 0 {
+		limit := resource.MustParse(analysis.RecommendedMemoryLimit)
+		request := resource.MustParse(analysis.RecommendedMemoryRequest)
+		if minRequest := int64(math.Ceil(float64(limit.Value()) / a.ratioPolicy.MaxMemory)); request.Value() < minRequest {
+			const mi = 1024 * 1024
+			analysis.RecommendedMemoryRequest = fmt.Sprintf("%dMi", (minRequest+mi-1)/mi)
+		}
+	}
+}
@@ -0,0 +1,191 @@
+package analyzer
+
+import (
+	"strings"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metricsv1beta1 "k8s.io/metrics/pkg/apis/metrics/v1beta1"
+)
+
+func TestLimitRequestRatio(t *testing.T) {
+	tests := []struct {
+		name      string
+		resources v1.ResourceRequirements
+		want      float64
+	}{
+		{"no limit", resources(cpuMemory("100m", "128Mi"), nil), 0},
+		{"limit without request", resources(nil, cpuMemory("500m", "256Mi")), 1},
+		{"equal", resources(cpuMemory("500m", "256Mi"), cpuMemory("500m", "256Mi")), 1},
+		{"burstable", resources(cpuMemory("100m", "128Mi"), cpuMemory("450m", "512Mi")), 4.5},
+		{"zero request", resources(cpuMemory("0", "0"), cpuMemory("500m", "256Mi")), 0},
+		{"zero limit", resources(cpuMemory("100m", "128Mi"), cpuMemory("0", "0")), 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := limitRequestRatio(tt.resources, v1.ResourceCPU); got != tt.want {
+				t.Errorf("limitRequestRatio = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRatioSuggestions(t *testing.T) {
+	a := newTestAnalyzer(t)
+	a.SetRatioPolicy(RatioPolicy{MaxCPU: 4, MaxMemory: 1})
+
+	suggestions, violated := a.ratioSuggestions(&PodAnalysis{CPULimitRatio: 8, MemoryLimitRatio: 2})
+	want := []string{
+		"📐 CPU limit is 8.0x request, policy allows at most 4.0x",
+		"📐 Memory limit is 2.0x request, policy requires limit = request",
+	}
+	if !violated || strings.Join(suggestions, "\n") != strings.Join(want, "\n") {
+		t.Errorf("ratioSuggestions = %q, %v, want %q, true", suggestions, violated, want)
+	}
+
+	// A missing limit has ratio 0 and is never a violation
+	if suggestions, violated := a.ratioSuggestions(&PodAnalysis{CPULimitRatio: 4, MemoryLimitRatio: 0}); violated {
+		t.Errorf("ratioSuggestions = %q for ratios within the policy", suggestions)
+	}
+	if _, violated := newTestAnalyzer(t).ratioSuggestions(&PodAnalysis{CPULimitRatio: 100}); violated {
+		t.Error("ratio violated without a policy")
+	}
+}
+
+// TestRecommendationPolicies checks how the bounds, ratio and Guaranteed
+// QoS policies combine on one recommendation. Bounds clamp first, the
+// ratio policy then raises requests against the clamped limits, and a
+// Guaranteed requirement finally sets requests to limits. Usage of
+// 200m/256Mi gives 500m/240m CPU and 640Mi/307Mi memory without policies.
+func TestRecommendationPolicies(t *testing.T) {
+	tests := []struct {
+		name       string
+		bounds     BoundsPolicy
+		ratio      RatioPolicy
+		guaranteed bool
+		// want is cpu limit, cpu request, memory limit, memory request
+		want []string
+		qos  v1.PodQOSClass
+	}{
+		{
+			name: "no policies",
+			want: []string{"500m", "240m", "640Mi", "307Mi"},
+			qos:  v1.PodQOSBurstable,
+		},
+		{
+			name:  "ratio raises requests",
+			ratio: RatioPolicy{MaxCPU: 1.5, MaxMemory: 1},
+			want:  []string{"500m", "334m", "640Mi", "640Mi"},
+			qos:   v1.PodQOSBurstable,
+		},
+		{
+			name:   "bounds clamp limits",
+			bounds: BoundsPolicy{Max: resourceList("cpu", "300m", "memory", "512Mi")},
+			want:   []string{"300m", "240m", "512Mi", "307Mi"},
+			qos:    v1.PodQOSBurstable,
+		},
+		{
+			// Raising before clamping would give a 300m request
+			name:   "ratio applies to clamped limits",
+			bounds: BoundsPolicy{Max: resourceList("cpu", "300m", "memory", "512Mi")},
+			ratio:  RatioPolicy{MaxCPU: 1.2, MaxMemory: 1},
+			want:   []string{"300m", "250m", "512Mi", "512Mi"},
+			qos:    v1.PodQOSBurstable,
+		},
+		{
+			name:   "minimum request already within ratio",
+			bounds: BoundsPolicy{Min: resourceList("cpu", "400m")},
+			ratio:  RatioPolicy{MaxCPU: 4},
+			want:   []string{"500m", "400m", "640Mi", "307Mi"},
+			qos:    v1.PodQOSBurstable,
+		},
+		{
+			name:       "guaranteed overrides ratio",
+			ratio:      RatioPolicy{MaxCPU: 4, MaxMemory: 2},
+			guaranteed: true,
+			want:       []string{"500m", "500m", "640Mi", "640Mi"},
+			qos:        v1.PodQOSGuaranteed,
+		},
+		{
+			name:       "guaranteed within bounds",
+			bounds:     BoundsPolicy{Max: resourceList("cpu", "300m", "memory", "512Mi")},
+			ratio:      RatioPolicy{MaxCPU: 1.2},
+			guaranteed: true,
+			want:       []string{"300m", "300m", "512Mi", "512Mi"},
+			qos:        v1.PodQOSGuaranteed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := newTestAnalyzer(t)
+			a.SetBoundsPolicy(tt.bounds)
+			a.SetRatioPolicy(tt.ratio)
+			if tt.guaranteed {
+				a.SetQoSPolicies(
+					[]QoSPolicy{{LabelKey: "tier", LabelValue: "critical", Class: v1.PodQOSGuaranteed}},
+					map[string]map[string]string{"web": {"tier": "critical"}},
+				)
+			}
+			pod := testPod("web", "api", testContainer("app", v1.ResourceRequirements{}))
+			metrics := []metricsv1beta1.PodMetrics{*testPodMetrics("web", "api", "app", "200m", "256Mi")}
+
+			r := a.AnalyzePods([]v1.Pod{*pod}, metrics, 0.8)[0]
+			got := []string{r.RecommendedCPULimit, r.RecommendedCPURequest, r.RecommendedMemoryLimit, r.RecommendedMemoryRequest}
+			if strings.Join(got, " ") != strings.Join(tt.want, " ") {
+				t.Errorf("recommended = %v, want %v", got, tt.want)
+			}
+			if r.RecommendedQoSClass != string(tt.qos) {
+				t.Errorf("recommended QoS = %s, want %s", r.RecommendedQoSClass, tt.qos)
+			}
+
+			// Whatever the combination, the result satisfies every policy
+			recommended := recommendedResources(&r, v1.ResourceRequirements{})
+			for name, max := range map[v1.ResourceName]float64{v1.ResourceCPU: tt.ratio.MaxCPU, v1.ResourceMemory: tt.ratio.MaxMemory} {
+				if ratio := limitRequestRatio(recommended, name); max > 0 && ratio > max {
+					t.Errorf("recommended %s ratio %.2f exceeds %.2f", name, ratio, max)
+				}
+			}
+			check := newTestAnalyzer(t)
+			check.SetBoundsPolicy(tt.bounds)
+			if suggestions, violated := check.boundsSuggestions(v1.Container{Resources: recommended}); violated {
+				t.Errorf("recommendation violates the bounds: %q", suggestions)
+			}
+		})
+	}
+}
+
+// TestRatioPolicyPerContainer checks that one container breaking the
+// ratio does not affect its neighbour.
+func TestRatioPolicyPerContainer(t *testing.T) {
+	a := newTestAnalyzer(t)
+	a.SetRatioPolicy(RatioPolicy{MaxCPU: 2})
+	pod := testPod("web", "api",
+		testContainer("app", resources(cpuMemory("100m", "128Mi"), cpuMemory("1", "128Mi"))),
+		testContainer("sidecar", resources(cpuMemory("100m", "64Mi"), cpuMemory("200m", "64Mi"))),
+	)
+
+	results := a.AnalyzePods([]v1.Pod{*pod}, nil, 0.8)
+	if len(results) != 2 {
+		t.Fatalf("got %d results, want 2", len(results))
+	}
+	if !hasSuggestion(results[0].Suggestions, "CPU limit is 10.0x request") || results[0].RiskLevel != "MEDIUM" {
+		t.Errorf("app: risk %s, suggestions %q", results[0].RiskLevel, results[0].Suggestions)
+	}
+	if hasSuggestion(results[1].Suggestions, "📐") {
+		t.Errorf("sidecar within the ratio has suggestions %q", results[1].Suggestions)
+	}
+	if results[0].CPULimitRatio != 10 || results[1].CPULimitRatio != 2 {
+		t.Errorf("ratios = %v, %v, want 10, 2", results[0].CPULimitRatio, results[1].CPULimitRatio)
+	}
+}
+
+// resourceList builds a list from "cpu", "100m", ... pairs.
+func resourceList(pairs ...string) v1.ResourceList {
+	list := v1.ResourceList{}
+	for i := 0; i+1 < len(pairs); i += 2 {
+		list[v1.ResourceName(pairs[i])] = resource.MustParse(pairs[i+1])
+	}
+	return list
+}
@@ -0,0 +1,83 @@
+package analyzer
+
+import (
+	"fmt"
+
+	v1 "k8s.io/api/core/v1"
+)
+
+// RequestPolicy sets the request-utilization thresholds. Usage below
+// Underused of the request marks the request as oversized; usage above
+// Overused marks it as too small for the scheduler to reserve enough.
+type RequestPolicy struct {
+	Underused float64
+	Overused  float64
+}
+
+// DefaultRequestPolicy flags requests used below 30% or above 100%.
+var DefaultRequestPolicy = RequestPolicy{Underused: 0.3, Overused: 1.0}
+
+// SetRequestPolicy overrides DefaultRequestPolicy.
+func (a *PodAnalyzer) SetRequestPolicy(policy RequestPolicy) {
+	a.requestPolicy = policy
+}
+
+// requestSuggestions compares usage with requests and records the reserved
+// but unused capacity on the analysis. It runs after the recommendations
+// are final so oversized requests can point at the shrunk value.
+func (a *PodAnalyzer) requestSuggestions(analysis *PodAnalysis, container v1.Container) []string {
+	usage := analysis.CurrentUsage
+	if usage == nil || usage.CPU == nil || usage.Memory == nil {
+		return nil
+	}
+
+	var suggestions []string
+
+	if request, ok := container.Resources.Requests[v1.ResourceCPU]; ok && request.MilliValue() > 0 {
+		requestMilli := request.MilliValue()
+		usageMilli := usage.CPU.MilliValue()
+		analysis.IdleCPUMilli = max(requestMilli-usageMilli, 0)
+
+		utilization := float64(usageMilli) / float64(requestMilli)
+		switch {
+		case utilization < a.requestPolicy.Underused:
+			suggestion := fmt.Sprintf("💤 CPU usage at %.1f%% of request (%s idle)", utilization*100, formatMilli(analysis.IdleCPUMilli))
+			if analysis.RecommendedCPURequest != "" {
+				suggestion += fmt.Sprintf(", consider shrinking request to %s", analysis.RecommendedCPURequest)
+			}
+			suggestions = append(suggestions, suggestion)
+		case a.requestPolicy.Overused > 0 && utilization > a.requestPolicy.Overused:
+			suggestions = append(suggestions,
+				fmt.Sprintf("⚠️ CPU usage at %.1f%% of request, the scheduler is under-reserving", utilization*100))
+		}
+	}
+
+	if request, ok := container.Resources.Requests[v1.ResourceMemory]; ok && request.Value() > 0 {
+		requestBytes := request.Value()
+		usageBytes := usage.Memory.Value()
+		analysis.IdleMemoryBytes = max(requestBytes-usageBytes, 0)
+
+		utilization := float64(usageBytes) / float64(requestBytes)
+		switch {
+		case utilization < a.requestPolicy.Underused:
+			suggestion := fmt.Sprintf("💤 Memory usage at %.1f%% of request (%s idle)", utilization*100, formatBytes(analysis.IdleMemoryBytes))
+			if analysis.RecommendedMemoryRequest != "" {
+				suggestion += fmt.Sprintf(", consider shrinking request to %s", analysis.RecommendedMemoryRequest)
+			}
+			suggestions = append(suggestions, suggestion)
+		case a.requestPolicy.Overused > 0 && utilization > a.requestPolicy.Overused:
+			suggestions = append(suggestions,
+				fmt.Sprintf("⚠️ Memory usage at %.1f%% of request, pod is an early eviction candidate", utilization*100))
+		}
+	}
+
+	return suggestions
+}
+
+func formatMilli(milli int64) string {
+	return fmt.Sprintf("%dm", milli)
+}
+
+func formatBytes(bytes int64) string {
+	return fmt.Sprintf("%dMi", bytes/(1024*1024))
+}
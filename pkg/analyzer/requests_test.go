@@ -0,0 +1,114 @@
+package analyzer
+
+import (
+	"strings"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	metricsv1beta1 "k8s.io/metrics/pkg/apis/metrics/v1beta1"
+)
+
+func TestRequestSuggestions(t *testing.T) {
+	tests := []struct {
+		name       string
+		resources  v1.ResourceRequirements
+		usage      *ResourceUsage
+		policy     RequestPolicy
+		want       []string
+		idleCPU    int64
+		idleMemory int64
+	}{
+		{
+			name:      "over-reserved",
+			resources: resources(cpuMemory("1", "1Gi"), cpuMemory("2", "2Gi")),
+			usage:     usage("100m", "128Mi"),
+			want: []string{
+				"💤 CPU usage at 10.0% of request (900m idle), consider shrinking request to 120m",
+				"💤 Memory usage at 12.5% of request (896Mi idle), consider shrinking request to 153Mi",
+			},
+			idleCPU:    900,
+			idleMemory: 896 << 20,
+		},
+		{
+			name:      "under-reserved",
+			resources: resources(cpuMemory("100m", "128Mi"), nil),
+			usage:     usage("200m", "256Mi"),
+			want: []string{
+				"⚠️ CPU usage at 200.0% of request, the scheduler is under-reserving",
+				"⚠️ Memory usage at 200.0% of request, pod is an early eviction candidate",
+			},
+		},
+		{
+			name:      "under-reserved check disabled",
+			resources: resources(cpuMemory("100m", "128Mi"), nil),
+			usage:     usage("200m", "256Mi"),
+			policy:    RequestPolicy{Underused: 0.3},
+		},
+		{
+			name:       "well reserved",
+			resources:  resources(cpuMemory("200m", "256Mi"), nil),
+			usage:      usage("150m", "200Mi"),
+			idleCPU:    50,
+			idleMemory: 56 << 20,
+		},
+		{
+			name:      "missing metrics",
+			resources: resources(cpuMemory("1", "1Gi"), nil),
+		},
+		{
+			name:      "missing memory metric",
+			resources: resources(cpuMemory("1", "1Gi"), nil),
+			usage:     &ResourceUsage{CPU: usage("100m", "0").CPU},
+		},
+		{
+			name:      "no requests",
+			usage:     usage("100m", "128Mi"),
+			resources: resources(nil, cpuMemory("1", "1Gi")),
+		},
+		{
+			name:      "cpu request only",
+			resources: resources([]string{"cpu", "500m"}, nil),
+			usage:     usage("50m", "128Mi"),
+			want:      []string{"💤 CPU usage at 10.0% of request (450m idle), consider shrinking request to 120m"},
+			idleCPU:   450,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := newTestAnalyzer(t)
+			if tt.policy != (RequestPolicy{}) {
+				a.SetRequestPolicy(tt.policy)
+			}
+			analysis := &PodAnalysis{CurrentUsage: tt.usage, RecommendedCPURequest: "120m", RecommendedMemoryRequest: "153Mi"}
+			got := a.requestSuggestions(analysis, testContainer("app", tt.resources))
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("suggestions = %q, want %q", got, tt.want)
+			}
+			if analysis.IdleCPUMilli != tt.idleCPU || analysis.IdleMemoryBytes != tt.idleMemory {
+				t.Errorf("idle = %dm, %d bytes, want %dm, %d bytes", analysis.IdleCPUMilli, analysis.IdleMemoryBytes, tt.idleCPU, tt.idleMemory)
+			}
+		})
+	}
+}
+
+// TestRequestSuggestionsInitContainers checks that init containers, which
+// only hold their requests while the pod starts, are not reported idle.
+func TestRequestSuggestionsInitContainers(t *testing.T) {
+	pod := testPod("web", "api", testContainer("app", resources(cpuMemory("1", "1Gi"), cpuMemory("1", "1Gi"))))
+	pod.Spec.InitContainers = []v1.Container{testContainer("migrate", resources(cpuMemory("4", "4Gi"), nil))}
+	metrics := testPodMetrics("web", "api", "app", "100m", "128Mi")
+	metrics.Containers = append(metrics.Containers, metricsv1beta1.ContainerMetrics{Name: "migrate", Usage: resourceList("cpu", "0", "memory", "0")})
+
+	results := newTestAnalyzer(t).AnalyzePods([]v1.Pod{*pod}, []metricsv1beta1.PodMetrics{*metrics}, 0.8)
+	if len(results) != 1 || results[0].ContainerName != "app" {
+		t.Fatalf("results = %+v, want the app container only", results)
+	}
+	r := results[0]
+	if r.IdleCPUMilli != 900 || r.IdleMemoryBytes != 896<<20 {
+		t.Errorf("idle = %dm, %d bytes, want the app container's 900m and 896Mi", r.IdleCPUMilli, r.IdleMemoryBytes)
+	}
+	if !hasSuggestion(r.Suggestions, "CPU usage at 10.0% of request (900m idle), consider shrinking request to") {
+		t.Errorf("suggestions %q lack the idle CPU request", r.Suggestions)
+	}
+}
@@ -0,0 +1,340 @@
+package analyzer
+
+import (
+	"fmt"
+	"math"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+const mebibyte = 1024 * 1024
+
+// runtimeRule describes how to recognise a language runtime in a container
+// spec and how its memory setting should relate to the memory limit.
+type runtimeRule struct {
+	Name string
+	// A container matches when its image repository name is one of Images,
+	// its entrypoint is one of Commands, or it sets one of EnvVars.
+	Images   []string
+	Commands []string
+	EnvVars  []string
+	// Settings are tried in order; the first match is the runtime's heap
+	// or memory setting.
+	Settings []runtimeSetting
+	// MaxFraction is the largest share of the memory limit the setting
+	// should claim, leaving the rest for non-heap memory. 0 disables the
+	// setting checks.
+	MaxFraction float64
+	// Reserves marks settings that size a heap the runtime may fill, so an
+	// absolute value must fit in the limit; soft targets such as GOMEMLIMIT
+	// are fixed by lowering the setting instead.
+	Reserves bool
+	// Unset is the advice given when the runtime has no setting.
+	Unset string
+	// Recommend formats the setting for a memory limit in bytes.
+	Recommend func(limitBytes int64) string
+	// Workers find the worker process count of pre-forking servers, whose
+	// memory scales with it; MinPerWorker is the memory each one needs.
+	Workers      []*regexp.Regexp
+	MinPerWorker int64
+}
+
+// runtimeSetting extracts a memory setting from env vars or, when Args is
+// set, from the command line.
+type runtimeSetting struct {
+	EnvVars []string
+	Args    bool
+	Pattern *regexp.Regexp
+	// Percent marks values that are a percentage of the memory limit;
+	// otherwise they are sizes, with Unit applied to bare numbers.
+	Percent bool
+	Unit    int64
+}
+
+var jvmSettings = []string{"JAVA_TOOL_OPTIONS", "JAVA_OPTS", "JDK_JAVA_OPTIONS"}
+
+// runtimeRules are matched in order; the first matching rule wins.
+var runtimeRules = []runtimeRule{
+	{
+		Name:     "JVM",
+		Images:   []string{"openjdk", "eclipse-temurin", "amazoncorretto", "zulu-openjdk", "ibm-semeru-runtimes", "java"},
+		Commands: []string{"java"},
+		EnvVars:  jvmSettings,
+		Settings: []runtimeSetting{
+			{EnvVars: jvmSettings, Args: true, Pattern: regexp.MustCompile(`-Xmx(\d+[kKmMgG]?)\b`), Unit: 1},
+			{EnvVars: jvmSettings, Args: true, Pattern: regexp.MustCompile(`-XX:MaxRAMPercentage=(\d+(?:\.\d+)?)`), Percent: true},
+		},
+		MaxFraction: 0.75,
+		Reserves:    true,
+		Unset:       "JVM heap defaults to 25% of the memory limit",
+		Recommend:   func(int64) string { return "-XX:MaxRAMPercentage=75" },
+	},
+	{
+		Name:     "Go",
+		Images:   []string{"golang"},
+		EnvVars:  []string{"GOMEMLIMIT", "GOGC", "GOMAXPROCS"},
+		Settings: []runtimeSetting{{EnvVars: []string{"GOMEMLIMIT"}, Pattern: regexp.MustCompile(`^(\S+)$`), Unit: 1}},
+		// GOMEMLIMIT is a soft limit on all Go-managed memory
+		MaxFraction: 0.9,
+		Unset:       "Go GC is unaware of the memory limit without GOMEMLIMIT",
+		Recommend: func(limitBytes int64) string {
+			return fmt.Sprintf("GOMEMLIMIT=%dMiB", limitBytes*9/10/mebibyte)
+		},
+	},
+	{
+		Name:        "Node.js",
+		Images:      []string{"node"},
+		Commands:    []string{"node", "npm", "yarn"},
+		EnvVars:     []string{"NODE_OPTIONS"},
+		Settings:    []runtimeSetting{{EnvVars: []string{"NODE_OPTIONS"}, Args: true, Pattern: regexp.MustCompile(`--max-old-space-size=(\d+)`), Unit: mebibyte}},
+		MaxFraction: 0.75,
+		Reserves:    true,
+		Unset:       "V8 sizes its heap from host memory, not the container limit",
+		Recommend: func(limitBytes int64) string {
+			return fmt.Sprintf("NODE_OPTIONS=--max-old-space-size=%d", limitBytes*3/4/mebibyte)
+		},
+	},
+	{
+		Name:     "Python",
+		Images:   []string{"python"},
+		Commands: []string{"python", "python3", "gunicorn", "uvicorn", "celery"},
+		EnvVars:  []string{"WEB_CONCURRENCY", "GUNICORN_CMD_ARGS"},
+		Workers: []*regexp.Regexp{
+			regexp.MustCompile(`(?:--workers|-w)[= ](\d+)`),
+			regexp.MustCompile(`(?:--concurrency|-c)[= ](\d+)`),
+			regexp.MustCompile(`WEB_CONCURRENCY=(\d+)`),
+		},
+		MinPerWorker: 128 * mebibyte,
+	},
+}
+
+// containerRuntime is what was detected about a container's runtime.
+type containerRuntime struct {
+	rule *runtimeRule
+	// setting is the matched text, value its size in bytes or percentage
+	setting string
+	value   float64
+	percent bool
+	workers int
+}
+
+// detectRuntime matches a container against runtimeRules.
+func detectRuntime(container v1.Container) *containerRuntime {
+	env := map[string]string{}
+	for _, e := range container.Env {
+		env[e.Name] = e.Value
+	}
+	commandLine := strings.Join(append(append([]string{}, container.Command...), container.Args...), " ")
+
+	entrypoint := ""
+	if len(container.Command) > 0 {
+		entrypoint = path.Base(container.Command[0])
+	} else if len(container.Args) > 0 {
+		entrypoint = path.Base(container.Args[0])
+	}
+
+	// Repository name without registry, tag or digest
+	image := container.Image
+	if i := strings.Index(image, "@"); i >= 0 {
+		image = image[:i]
+	}
+	if i := strings.LastIndex(image, "/"); i >= 0 {
+		image = image[i+1:]
+	}
+	if i := strings.Index(image, ":"); i >= 0 {
+		image = image[:i]
+	}
+
+	for i := range runtimeRules {
+		rule := &runtimeRules[i]
+		if !rule.matches(image, entrypoint, env) {
+			continue
+		}
+
+		detected := &containerRuntime{rule: rule}
+		for _, setting := range rule.Settings {
+			if detected.parseSetting(setting, env, commandLine) {
+				break
+			}
+		}
+
+		// Sorted so the first worker count found does not depend on map order
+		names := make([]string, 0, len(env))
+		for name := range env {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		envLine := commandLine
+		for _, name := range names {
+			envLine += " " + name + "=" + env[name]
+		}
+		for _, pattern := range rule.Workers {
+			if m := pattern.FindStringSubmatch(envLine); m != nil {
+				detected.workers, _ = strconv.Atoi(m[1])
+				break
+			}
+		}
+		return detected
+	}
+	return nil
+}
+
+func (r *runtimeRule) matches(image, entrypoint string, env map[string]string) bool {
+	for _, name := range r.EnvVars {
+		if _, ok := env[name]; ok {
+			return true
+		}
+	}
+	for _, command := range r.Commands {
+		if entrypoint == command {
+			return true
+		}
+	}
+	for _, name := range r.Images {
+		if image == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *containerRuntime) parseSetting(setting runtimeSetting, env map[string]string, commandLine string) bool {
+	type source struct{ name, text string }
+	var sources []source
+	for _, name := range setting.EnvVars {
+		if value, ok := env[name]; ok {
+			sources = append(sources, source{name, value})
+		}
+	}
+	if setting.Args {
+		sources = append(sources, source{"", commandLine})
+	}
+
+	for _, src := range sources {
+		m := setting.Pattern.FindStringSubmatch(src.text)
+		if m == nil {
+			continue
+		}
+		if setting.Percent {
+			percent, err := strconv.ParseFloat(m[1], 64)
+			if err != nil {
+				continue
+			}
+			c.value, c.percent = percent, true
+		} else {
+			bytes, ok := parseRuntimeSize(m[1], setting.Unit)
+			if !ok {
+				continue
+			}
+			c.value = float64(bytes)
+		}
+		c.setting = strings.TrimSpace(m[0])
+		// A bare env value such as GOMEMLIMIT reads better with its name
+		if src.name != "" && c.setting == strings.TrimSpace(src.text) && !strings.HasPrefix(c.setting, "-") {
+			c.setting = src.name + "=" + c.setting
+		}
+		return true
+	}
+	return false
+}
+
+// parseRuntimeSize parses JVM (512m, 2g), Go (900MiB, 1GiB) and bare
+// numeric sizes, multiplying bare numbers by unit.
+func parseRuntimeSize(s string, unit int64) (int64, bool) {
+	multipliers := []struct {
+		suffix string
+		factor int64
+	}{
+		{"TiB", 1 << 40}, {"GiB", 1 << 30}, {"MiB", 1 << 20}, {"KiB", 1 << 10}, {"B", 1},
+		{"t", 1 << 40}, {"g", 1 << 30}, {"m", 1 << 20}, {"k", 1 << 10},
+	}
+	for _, m := range multipliers {
+		if strings.HasSuffix(s, m.suffix) || len(m.suffix) == 1 && strings.HasSuffix(s, strings.ToUpper(m.suffix)) {
+			n, err := strconv.ParseInt(s[:len(s)-len(m.suffix)], 10, 64)
+			return n * m.factor, err == nil
+		}
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	return n * unit, err == nil
+}
+
+// runtimeSuggestions checks the runtime's memory setting against the
+// memory limit and tailors the recommended limit so an absolute heap size
+// still fits. It runs after the usage-based recommendations. misconfigured
+// is set when the setting leaves too little or no room below the limit.
+func (a *PodAnalyzer) runtimeSuggestions(analysis *PodAnalysis, container v1.Container) (suggestions []string, misconfigured bool) {
+	detected := detectRuntime(container)
+	if detected == nil {
+		return nil, false
+	}
+	rule := detected.rule
+	analysis.Runtime = rule.Name
+	analysis.RuntimeSetting = detected.setting
+
+	limit, hasLimit := container.Resources.Limits[v1.ResourceMemory]
+	limitBytes := limit.Value()
+
+	if rule.MaxFraction > 0 && detected.setting != "" && hasLimit && limitBytes > 0 {
+		share := detected.value / 100
+		if !detected.percent {
+			share = detected.value / float64(limitBytes)
+		}
+		switch {
+		case share >= 1:
+			suggestions = append(suggestions,
+				fmt.Sprintf("🧠 %s setting %s is at or above the %s memory limit, the container will be OOM-killed before the runtime reacts",
+					rule.Name, detected.setting, limit.String()))
+			misconfigured = true
+		case share > rule.MaxFraction:
+			suggestions = append(suggestions,
+				fmt.Sprintf("🧠 %s setting %s is %.0f%% of the %s memory limit, keep it at or below %.0f%% for non-heap memory",
+					rule.Name, detected.setting, share*100, limit.String(), rule.MaxFraction*100))
+			misconfigured = true
+		}
+	}
+
+	// An absolute heap size needs a limit large enough to hold it
+	if rule.Reserves && detected.setting != "" && !detected.percent && analysis.RecommendedMemoryLimit != "" {
+		recommended := resource.MustParse(analysis.RecommendedMemoryLimit)
+		needed := int64(math.Ceil(detected.value / rule.MaxFraction))
+		if needed > recommended.Value() {
+			analysis.RecommendedMemoryLimit = fmt.Sprintf("%dMi", (needed+mebibyte-1)/mebibyte)
+		}
+	}
+
+	if rule.Recommend != nil {
+		target := limitBytes
+		if analysis.RecommendedMemoryLimit != "" {
+			recommended := resource.MustParse(analysis.RecommendedMemoryLimit)
+			target = recommended.Value()
+		}
+		if target > 0 {
+			analysis.RecommendedRuntimeSetting = rule.Recommend(target)
+		}
+		if detected.setting == "" && rule.Unset != "" {
+			suggestion := fmt.Sprintf("🧠 %s detected: %s", rule.Name, rule.Unset)
+			if analysis.RecommendedRuntimeSetting != "" {
+				suggestion += fmt.Sprintf(", set %s", analysis.RecommendedRuntimeSetting)
+			}
+			suggestions = append(suggestions, suggestion)
+		}
+	}
+
+	if rule.MinPerWorker > 0 && detected.workers > 1 && hasLimit {
+		perWorker := limitBytes / int64(detected.workers)
+		if perWorker < rule.MinPerWorker {
+			suggestions = append(suggestions,
+				fmt.Sprintf("🧠 %s runs %d workers in a %s memory limit, %dMi each; allow at least %dMi per worker",
+					rule.Name, detected.workers, limit.String(), perWorker/mebibyte, rule.MinPerWorker/mebibyte))
+			misconfigured = true
+		}
+	}
+
+	return suggestions, misconfigured
+}
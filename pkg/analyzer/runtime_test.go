@@ -0,0 +1,250 @@
+package analyzer
+
+import (
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+)
+
+func env(pairs ...string) []v1.EnvVar {
+	var vars []v1.EnvVar
+	for i := 0; i+1 < len(pairs); i += 2 {
+		vars = append(vars, v1.EnvVar{Name: pairs[i], Value: pairs[i+1]})
+	}
+	return vars
+}
+
+func TestParseRuntimeSize(t *testing.T) {
+	tests := []struct {
+		in   string
+		unit int64
+		want int64
+		ok   bool
+	}{
+		{"512m", 1, 512 << 20, true},
+		{"2g", 1, 2 << 30, true},
+		{"2G", 1, 2 << 30, true},
+		{"64k", 1, 64 << 10, true},
+		{"900MiB", 1, 900 << 20, true},
+		{"1GiB", 1, 1 << 30, true},
+		{"1048576", 1, 1 << 20, true},
+		{"4096", mebibyte, 4 << 30, true},
+		{"lots", 1, 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := parseRuntimeSize(tt.in, tt.unit)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("parseRuntimeSize(%q, %d) = %d, %v, want %d, %v", tt.in, tt.unit, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestRuntimeSuggestions(t *testing.T) {
+	tests := []struct {
+		name      string
+		container v1.Container
+		limit     string
+		// recommendedLimit is the usage-based memory limit before the
+		// runtime adjusts it
+		recommendedLimit string
+		runtime          string
+		setting          string
+		// want is the memory limit and runtime setting recommended after
+		wantLimit     string
+		wantSetting   string
+		suggestion    string
+		misconfigured bool
+	}{
+		{
+			name:             "JVM Xmx above the limit",
+			container:        v1.Container{Image: "registry.example.com/shop/api:1.4", Env: env("JAVA_OPTS", "-server -Xmx2g")},
+			limit:            "1Gi",
+			recommendedLimit: "640Mi",
+			runtime:          "JVM",
+			setting:          "-Xmx2g",
+			wantLimit:        "2731Mi",
+			wantSetting:      "-XX:MaxRAMPercentage=75",
+			suggestion:       "JVM setting -Xmx2g is at or above the 1Gi memory limit",
+			misconfigured:    true,
+		},
+		{
+			name:             "JVM Xmx on the command line",
+			container:        v1.Container{Image: "eclipse-temurin:21-jre", Command: []string{"java", "-Xmx768m", "-jar", "app.jar"}},
+			limit:            "1Gi",
+			recommendedLimit: "640Mi",
+			runtime:          "JVM",
+			setting:          "-Xmx768m",
+			wantLimit:        "1024Mi",
+			wantSetting:      "-XX:MaxRAMPercentage=75",
+		},
+		{
+			name:             "JVM MaxRAMPercentage too high",
+			container:        v1.Container{Image: "openjdk:17", Env: env("JAVA_TOOL_OPTIONS", "-XX:MaxRAMPercentage=90.0")},
+			limit:            "1Gi",
+			recommendedLimit: "640Mi",
+			runtime:          "JVM",
+			setting:          "-XX:MaxRAMPercentage=90.0",
+			wantLimit:        "640Mi",
+			wantSetting:      "-XX:MaxRAMPercentage=75",
+			suggestion:       "is 90% of the 1Gi memory limit, keep it at or below 75%",
+			misconfigured:    true,
+		},
+		{
+			name:             "JVM without a heap setting",
+			container:        v1.Container{Image: "amazoncorretto:21"},
+			limit:            "1Gi",
+			recommendedLimit: "640Mi",
+			runtime:          "JVM",
+			wantLimit:        "640Mi",
+			wantSetting:      "-XX:MaxRAMPercentage=75",
+			suggestion:       "JVM heap defaults to 25% of the memory limit, set -XX:MaxRAMPercentage=75",
+		},
+		{
+			name:             "GOMEMLIMIT at the limit",
+			container:        v1.Container{Image: "example/api", Env: env("GOMEMLIMIT", "1GiB")},
+			limit:            "1Gi",
+			recommendedLimit: "640Mi",
+			runtime:          "Go",
+			setting:          "GOMEMLIMIT=1GiB",
+			// A soft limit is lowered, the memory limit is left alone
+			wantLimit:     "640Mi",
+			wantSetting:   "GOMEMLIMIT=576MiB",
+			suggestion:    "Go setting GOMEMLIMIT=1GiB is at or above the 1Gi memory limit",
+			misconfigured: true,
+		},
+		{
+			name:             "GOMEMLIMIT within the limit",
+			container:        v1.Container{Image: "example/api", Env: env("GOMEMLIMIT", "900MiB")},
+			limit:            "1Gi",
+			recommendedLimit: "640Mi",
+			runtime:          "Go",
+			setting:          "GOMEMLIMIT=900MiB",
+			wantLimit:        "640Mi",
+			wantSetting:      "GOMEMLIMIT=576MiB",
+		},
+		{
+			name:        "Go without GOMEMLIMIT",
+			container:   v1.Container{Image: "example/api", Env: env("GOGC", "100")},
+			limit:       "1Gi",
+			runtime:     "Go",
+			wantSetting: "GOMEMLIMIT=921MiB",
+			suggestion:  "Go GC is unaware of the memory limit without GOMEMLIMIT, set GOMEMLIMIT=921MiB",
+		},
+		{
+			name:             "Node heap above the limit",
+			container:        v1.Container{Image: "node:20-alpine", Env: env("NODE_OPTIONS", "--max-old-space-size=4096 --enable-source-maps")},
+			limit:            "2Gi",
+			recommendedLimit: "640Mi",
+			runtime:          "Node.js",
+			setting:          "--max-old-space-size=4096",
+			wantLimit:        "5462Mi",
+			wantSetting:      "NODE_OPTIONS=--max-old-space-size=4096",
+			suggestion:       "Node.js setting --max-old-space-size=4096 is at or above the 2Gi memory limit",
+			misconfigured:    true,
+		},
+		{
+			name:             "Node heap on the command line",
+			container:        v1.Container{Image: "example/web", Command: []string{"node"}, Args: []string{"--max-old-space-size=512", "server.js"}},
+			limit:            "1Gi",
+			recommendedLimit: "640Mi",
+			runtime:          "Node.js",
+			setting:          "--max-old-space-size=512",
+			wantLimit:        "683Mi",
+			wantSetting:      "NODE_OPTIONS=--max-old-space-size=512",
+		},
+		{
+			name:          "gunicorn workers starved",
+			container:     v1.Container{Image: "example/app", Command: []string{"gunicorn"}, Args: []string{"--workers", "8", "app:wsgi"}},
+			limit:         "512Mi",
+			runtime:       "Python",
+			suggestion:    "Python runs 8 workers in a 512Mi memory limit, 64Mi each; allow at least 128Mi per worker",
+			misconfigured: true,
+		},
+		{
+			name:      "WEB_CONCURRENCY within the limit",
+			container: v1.Container{Image: "python:3.12-slim", Env: env("WEB_CONCURRENCY", "2")},
+			limit:     "512Mi",
+			runtime:   "Python",
+		},
+		{
+			name:      "no known runtime",
+			container: v1.Container{Image: "nginx:1.25"},
+			limit:     "512Mi",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			container := tt.container
+			container.Name = "app"
+			container.Resources = resources(nil, []string{"memory", tt.limit})
+			analysis := PodAnalysis{RecommendedMemoryLimit: tt.recommendedLimit}
+
+			suggestions, misconfigured := newTestAnalyzer(t).runtimeSuggestions(&analysis, container)
+
+			if analysis.Runtime != tt.runtime || analysis.RuntimeSetting != tt.setting {
+				t.Errorf("runtime = %q, setting %q, want %q, %q", analysis.Runtime, analysis.RuntimeSetting, tt.runtime, tt.setting)
+			}
+			if analysis.RecommendedMemoryLimit != tt.wantLimit {
+				t.Errorf("recommended memory limit = %q, want %q", analysis.RecommendedMemoryLimit, tt.wantLimit)
+			}
+			if analysis.RecommendedRuntimeSetting != tt.wantSetting {
+				t.Errorf("recommended setting = %q, want %q", analysis.RecommendedRuntimeSetting, tt.wantSetting)
+			}
+			if misconfigured != tt.misconfigured {
+				t.Errorf("misconfigured = %v, want %v", misconfigured, tt.misconfigured)
+			}
+			if tt.suggestion == "" {
+				if len(suggestions) > 0 {
+					t.Errorf("suggestions = %q, want none", suggestions)
+				}
+			} else if !hasSuggestion(suggestions, tt.suggestion) {
+				t.Errorf("suggestions %q lack %q", suggestions, tt.suggestion)
+			}
+		})
+	}
+}
+
+// TestDetectRuntimeWorkersStable checks that the worker count does not
+// depend on map iteration order when several env vars carry one.
+func TestDetectRuntimeWorkersStable(t *testing.T) {
+	container := v1.Container{
+		Image: "python:3.12",
+		Env:   env("GUNICORN_CMD_ARGS", "--workers=8", "CELERY_OPTS", "--workers=2", "WEB_CONCURRENCY", "4"),
+	}
+	for i := 0; i < 50; i++ {
+		// CELERY_OPTS sorts first
+		if detected := detectRuntime(container); detected.workers != 2 {
+			t.Fatalf("run %d: workers = %d, want 2", i, detected.workers)
+		}
+	}
+
+	// The command line is searched before the environment
+	container.Command = []string{"gunicorn", "-w", "3"}
+	if detected := detectRuntime(container); detected.workers != 3 {
+		t.Errorf("workers = %d, want 3", detected.workers)
+	}
+}
+
+func TestDetectRuntimeImage(t *testing.T) {
+	for image, want := range map[string]string{
+		"openjdk:17":                          "JVM",
+		"docker.io/library/node:20@sha256:ab": "Node.js",
+		"ghcr.io/acme/golang:1.22":            "Go",
+		"python":                              "Python",
+		"nodejs-app:1.0":                      "",
+		"registry:5000/team/java":             "JVM",
+	} {
+		detected := detectRuntime(v1.Container{Image: image})
+		got := ""
+		if detected != nil {
+			got = detected.rule.Name
+		}
+		if got != want {
+			t.Errorf("detectRuntime(%q) = %q, want %q", image, got, want)
+		}
+	}
+	if detectRuntime(v1.Container{Image: "example/app", Command: []string{"/usr/bin/java"}}) == nil {
+		t.Error("java entrypoint not detected")
+	}
+}
@@ -0,0 +1,212 @@
+package analyzer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// StorageStats holds ephemeral-storage usage gathered from the kubelet
+// stats summary API, keyed by namespace/pod/container for container
+// writable layers and logs, and by namespace/pod/volume for volumes.
+type StorageStats struct {
+	containers map[string]int64
+	volumes    map[string]int64
+}
+
+func NewStorageStats() *StorageStats {
+	return &StorageStats{
+		containers: map[string]int64{},
+		volumes:    map[string]int64{},
+	}
+}
+
+// statsSummary is the subset of the kubelet /stats/summary response used
+// for ephemeral-storage analysis.
+type statsSummary struct {
+	Pods []struct {
+		PodRef struct {
+			Name      string `json:"name"`
+			Namespace string `json:"namespace"`
+		} `json:"podRef"`
+		Containers []struct {
+			Name   string   `json:"name"`
+			Rootfs *fsStats `json:"rootfs"`
+			Logs   *fsStats `json:"logs"`
+		} `json:"containers"`
+		Volumes []struct {
+			Name string `json:"name"`
+			fsStats
+		} `json:"volume"`
+	} `json:"pods"`
+}
+
+type fsStats struct {
+	UsedBytes *int64 `json:"usedBytes"`
+}
+
+func (f *fsStats) used() int64 {
+	if f == nil || f.UsedBytes == nil {
+		return 0
+	}
+	return *f.UsedBytes
+}
+
+// AddSummary merges one node's stats summary JSON into the stats.
+func (s *StorageStats) AddSummary(data []byte) error {
+	var summary statsSummary
+	if err := json.Unmarshal(data, &summary); err != nil {
+		return fmt.Errorf("failed to parse stats summary: %v", err)
+	}
+
+	for _, pod := range summary.Pods {
+		for _, c := range pod.Containers {
+			key := fmt.Sprintf("%s/%s/%s", pod.PodRef.Namespace, pod.PodRef.Name, c.Name)
+			s.containers[key] = c.Rootfs.used() + c.Logs.used()
+		}
+		for _, vol := range pod.Volumes {
+			key := fmt.Sprintf("%s/%s/%s", pod.PodRef.Namespace, pod.PodRef.Name, vol.Name)
+			s.volumes[key] = vol.used()
+		}
+	}
+	return nil
+}
+
+func (s *StorageStats) container(namespace, pod, container string) (int64, bool) {
+	if s == nil {
+		return 0, false
+	}
+	used, ok := s.containers[fmt.Sprintf("%s/%s/%s", namespace, pod, container)]
+	return used, ok
+}
+
+func (s *StorageStats) volume(namespace, pod, volume string) (int64, bool) {
+	if s == nil {
+		return 0, false
+	}
+	used, ok := s.volumes[fmt.Sprintf("%s/%s/%s", namespace, pod, volume)]
+	return used, ok
+}
+
+// GetStorageStats fetches the kubelet stats summary of each node through
+// the API server's node proxy. Nodes that cannot be reached are skipped and
+// reported in the returned error; the stats gathered so far are still
+// returned.
+func (a *PodAnalyzer) GetStorageStats(ctx context.Context, nodeNames []string) (*StorageStats, error) {
+	stats := NewStorageStats()
+	var failed []string
+
+	for _, node := range nodeNames {
+		data, err := a.client.Clientset.CoreV1().RESTClient().Get().
+			Resource("nodes").
+			Name(node).
+			SubResource("proxy").
+			Suffix("stats/summary").
+			DoRaw(ctx)
+		if err == nil {
+			err = stats.AddSummary(data)
+		}
+		if err != nil {
+			failed = append(failed, fmt.Sprintf("%s: %v", node, err))
+		}
+	}
+
+	if len(failed) > 0 {
+		return stats, fmt.Errorf("could not read stats summary from %d node(s): %v", len(failed), failed)
+	}
+	return stats, nil
+}
+
+// EnableEphemeralStorageChecks turns on ephemeral-storage suggestions and,
+// when stats is non-nil, usage-based ephemeral-storage recommendations.
+func (a *PodAnalyzer) EnableEphemeralStorageChecks(stats *StorageStats) {
+	a.checkEphemeral = true
+	a.storageStats = stats
+}
+
+func (a *PodAnalyzer) ephemeralUsage(pod v1.Pod, container v1.Container) *resource.Quantity {
+	used, ok := a.storageStats.container(pod.Namespace, pod.Name, container.Name)
+	if !ok {
+		return nil
+	}
+	return resource.NewQuantity(used, resource.BinarySI)
+}
+
+// ephemeralSuggestions checks a container's ephemeral-storage request and
+// limit and the emptyDir volumes it mounts.
+func (a *PodAnalyzer) ephemeralSuggestions(pod v1.Pod, container v1.Container, usage *ResourceUsage, threshold float64) []string {
+	var suggestions []string
+
+	limit, hasLimit := container.Resources.Limits[v1.ResourceEphemeralStorage]
+	if !hasLimit {
+		suggestions = append(suggestions, "💾 No ephemeral-storage limit set")
+	}
+	if _, hasRequest := container.Resources.Requests[v1.ResourceEphemeralStorage]; !hasRequest {
+		suggestions = append(suggestions, "💾 No ephemeral-storage request set")
+	}
+
+	if hasLimit && usage != nil && usage.EphemeralStorage != nil && limit.Value() > 0 {
+		usagePercent := float64(usage.EphemeralStorage.Value()) / float64(limit.Value()) * 100
+		if usagePercent > threshold*100 {
+			suggestions = append(suggestions,
+				fmt.Sprintf("⚠️ Ephemeral storage at %.1f%% of limit, pod risks eviction", usagePercent))
+		}
+	}
+
+	mounted := map[string]bool{}
+	for _, mount := range container.VolumeMounts {
+		mounted[mount.Name] = true
+	}
+	for _, volume := range pod.Spec.Volumes {
+		if volume.EmptyDir == nil || !mounted[volume.Name] {
+			continue
+		}
+
+		if volume.EmptyDir.SizeLimit == nil {
+			if volume.EmptyDir.Medium == v1.StorageMediumMemory {
+				suggestions = append(suggestions,
+					fmt.Sprintf("💾 Memory-backed emptyDir %q has no sizeLimit, usage counts against the memory limit", volume.Name))
+			} else {
+				suggestions = append(suggestions,
+					fmt.Sprintf("💾 emptyDir %q has no sizeLimit", volume.Name))
+			}
+			continue
+		}
+
+		sizeLimit := volume.EmptyDir.SizeLimit.Value()
+		if used, ok := a.storageStats.volume(pod.Namespace, pod.Name, volume.Name); ok && sizeLimit > 0 {
+			usagePercent := float64(used) / float64(sizeLimit) * 100
+			if usagePercent > threshold*100 {
+				suggestions = append(suggestions,
+					fmt.Sprintf("⚠️ emptyDir %q at %.1f%% of sizeLimit", volume.Name, usagePercent))
+			}
+		}
+	}
+
+	return suggestions
+}
+
+// ephemeralRecommendations sizes ephemeral storage from observed usage with
+// the same multipliers as memory: 2.5x for the limit (minimum 256Mi) and
+// 1.2x for the request (minimum 64Mi).
+func (a *PodAnalyzer) ephemeralRecommendations(analysis *PodAnalysis) {
+	if analysis.CurrentUsage == nil || analysis.CurrentUsage.EphemeralStorage == nil {
+		return
+	}
+	usedBytes := analysis.CurrentUsage.EphemeralStorage.Value()
+
+	recommendedLimit := usedBytes * 5 / 2 // 2.5x
+	if recommendedLimit < 256*1024*1024 { // Minimum 256Mi
+		recommendedLimit = 256 * 1024 * 1024
+	}
+	analysis.RecommendedEphemeralStorageLimit = fmt.Sprintf("%dMi", recommendedLimit/(1024*1024))
+
+	recommendedRequest := usedBytes * 6 / 5 // 1.2x
+	if recommendedRequest < 64*1024*1024 {  // Minimum 64Mi
+		recommendedRequest = 64 * 1024 * 1024
+	}
+	analysis.RecommendedEphemeralStorageRequest = fmt.Sprintf("%dMi", recommendedRequest/(1024*1024))
+}
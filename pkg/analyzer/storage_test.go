@@ -0,0 +1,240 @@
+package analyzer
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metricsv1beta1 "k8s.io/metrics/pkg/apis/metrics/v1beta1"
+)
+
+// loadStorageStats reads testdata/stats-summary.json, a kubelet
+// /stats/summary response for node-1.
+func loadStorageStats(t *testing.T) *StorageStats {
+	t.Helper()
+	data, err := os.ReadFile("testdata/stats-summary.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	stats := NewStorageStats()
+	if err := stats.AddSummary(data); err != nil {
+		t.Fatal(err)
+	}
+	return stats
+}
+
+func TestAddSummary(t *testing.T) {
+	stats := loadStorageStats(t)
+
+	for _, tt := range []struct {
+		namespace, pod, container string
+		used                      int64
+		ok                        bool
+	}{
+		// rootfs and logs are summed
+		{"web", "api", "app", 320 << 20, true},
+		{"web", "api", "sidecar", 10 << 20, true},
+		{"jobs", "worker-0", "worker", 0, true},
+		{"web", "api", "missing", 0, false},
+	} {
+		used, ok := stats.container(tt.namespace, tt.pod, tt.container)
+		if used != tt.used || ok != tt.ok {
+			t.Errorf("container %s/%s/%s = %d, %v, want %d, %v", tt.namespace, tt.pod, tt.container, used, ok, tt.used, tt.ok)
+		}
+	}
+
+	if used, ok := stats.volume("web", "api", "cache"); used != 900<<20 || !ok {
+		t.Errorf("volume web/api/cache = %d, %v, want %d, true", used, ok, 900<<20)
+	}
+	if _, ok := stats.volume("jobs", "worker-0", "cache"); ok {
+		t.Error("volume jobs/worker-0/cache found, want missing")
+	}
+
+	if err := stats.AddSummary([]byte("not json")); err == nil {
+		t.Error("AddSummary accepted invalid JSON")
+	}
+
+	var unset *StorageStats
+	if _, ok := unset.container("web", "api", "app"); ok {
+		t.Error("nil stats returned container usage")
+	}
+}
+
+func TestEphemeralStorageFindings(t *testing.T) {
+	compute := cpuMemory("100m", "128Mi")
+	withStorage := func(requests, limits []string) v1.ResourceRequirements {
+		return resources(append(append([]string{}, compute...), requests...), append(append([]string{}, compute...), limits...))
+	}
+
+	tests := []struct {
+		name      string
+		container string
+		resources v1.ResourceRequirements
+		risk      string
+		// suggestions and absent hold substrings expected in, and missing
+		// from, the suggestions
+		suggestions []string
+		absent      []string
+		// recommended is the ephemeral-storage limit and request
+		recommended []string
+	}{
+		{
+			name:        "no resources",
+			container:   "app",
+			risk:        "HIGH",
+			suggestions: []string{"No ephemeral-storage limit set", "No ephemeral-storage request set"},
+			recommended: []string{"800Mi", "384Mi"},
+		},
+		{
+			name:        "no request or limit",
+			container:   "app",
+			resources:   withStorage(nil, nil),
+			risk:        "LOW",
+			suggestions: []string{"No ephemeral-storage limit set", "No ephemeral-storage request set"},
+			recommended: []string{"800Mi", "384Mi"},
+		},
+		{
+			name:        "request only",
+			container:   "app",
+			resources:   withStorage([]string{"ephemeral-storage", "256Mi"}, nil),
+			risk:        "LOW",
+			suggestions: []string{"No ephemeral-storage limit set"},
+			absent:      []string{"No ephemeral-storage request set"},
+			recommended: []string{"800Mi", "384Mi"},
+		},
+		{
+			name:        "limit nearly used",
+			container:   "app",
+			resources:   withStorage([]string{"ephemeral-storage", "256Mi"}, []string{"ephemeral-storage", "350Mi"}),
+			risk:        "MEDIUM",
+			suggestions: []string{"Ephemeral storage at 91.4% of limit, pod risks eviction"},
+			absent:      []string{"No ephemeral-storage"},
+			recommended: []string{"800Mi", "384Mi"},
+		},
+		{
+			name:        "limit with headroom",
+			container:   "app",
+			resources:   withStorage([]string{"ephemeral-storage", "256Mi"}, []string{"ephemeral-storage", "1Gi"}),
+			risk:        "LOW",
+			absent:      []string{"Ephemeral storage at", "No ephemeral-storage"},
+			recommended: []string{"800Mi", "384Mi"},
+		},
+		{
+			name:        "small usage gets minimums",
+			container:   "sidecar",
+			resources:   withStorage(nil, nil),
+			risk:        "LOW",
+			recommended: []string{"256Mi", "64Mi"},
+		},
+		{
+			name:        "no stats for container",
+			container:   "unknown",
+			resources:   withStorage(nil, nil),
+			risk:        "LOW",
+			suggestions: []string{"No ephemeral-storage limit set"},
+			recommended: []string{"", ""},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := newTestAnalyzer(t)
+			a.EnableEphemeralStorageChecks(loadStorageStats(t))
+			pod := testPod("web", "api", testContainer(tt.container, tt.resources))
+
+			metrics := []metricsv1beta1.PodMetrics{*testPodMetrics("web", "api", tt.container, "60m", "100Mi")}
+
+			results := a.AnalyzePods([]v1.Pod{*pod}, metrics, 0.8)
+			if len(results) != 1 {
+				t.Fatalf("got %d results, want 1", len(results))
+			}
+			r := results[0]
+
+			if r.RiskLevel != tt.risk {
+				t.Errorf("risk = %s, want %s", r.RiskLevel, tt.risk)
+			}
+			for _, want := range tt.suggestions {
+				if !hasSuggestion(r.Suggestions, want) {
+					t.Errorf("suggestions %q lack %q", r.Suggestions, want)
+				}
+			}
+			for _, unwanted := range tt.absent {
+				if hasSuggestion(r.Suggestions, unwanted) {
+					t.Errorf("suggestions %q contain %q", r.Suggestions, unwanted)
+				}
+			}
+			got := []string{r.RecommendedEphemeralStorageLimit, r.RecommendedEphemeralStorageRequest}
+			if strings.Join(got, " ") != strings.Join(tt.recommended, " ") {
+				t.Errorf("recommended = %v, want %v", got, tt.recommended)
+			}
+			// The example is only generated for containers without limits
+			if len(tt.resources.Limits) == 0 && !strings.Contains(r.ExampleYAML, "ephemeral-storage: \""+tt.recommended[0]+"\"") {
+				t.Errorf("example YAML lacks the ephemeral-storage limit:\n%s", r.ExampleYAML)
+			}
+		})
+	}
+}
+
+func TestEmptyDirFindings(t *testing.T) {
+	sizeLimit := func(s string) *resource.Quantity {
+		q := resource.MustParse(s)
+		return &q
+	}
+	container := testContainer("app", resources(
+		[]string{"cpu", "100m", "memory", "128Mi", "ephemeral-storage", "256Mi"},
+		[]string{"cpu", "100m", "memory", "128Mi", "ephemeral-storage", "1Gi"},
+	))
+	for _, name := range []string{"cache", "scratch", "tmpfs", "large"} {
+		container.VolumeMounts = append(container.VolumeMounts, v1.VolumeMount{Name: name, MountPath: "/" + name})
+	}
+	pod := testPod("web", "api", container)
+	pod.Spec.Volumes = []v1.Volume{
+		// 900Mi used of 1Gi
+		{Name: "cache", VolumeSource: v1.VolumeSource{EmptyDir: &v1.EmptyDirVolumeSource{SizeLimit: sizeLimit("1Gi")}}},
+		{Name: "scratch", VolumeSource: v1.VolumeSource{EmptyDir: &v1.EmptyDirVolumeSource{}}},
+		{Name: "tmpfs", VolumeSource: v1.VolumeSource{EmptyDir: &v1.EmptyDirVolumeSource{Medium: v1.StorageMediumMemory}}},
+		// no stats for this volume
+		{Name: "large", VolumeSource: v1.VolumeSource{EmptyDir: &v1.EmptyDirVolumeSource{SizeLimit: sizeLimit("10Mi")}}},
+		// not mounted by the container
+		{Name: "unmounted", VolumeSource: v1.VolumeSource{EmptyDir: &v1.EmptyDirVolumeSource{}}},
+		{Name: "config", VolumeSource: v1.VolumeSource{ConfigMap: &v1.ConfigMapVolumeSource{}}},
+	}
+
+	a := newTestAnalyzer(t)
+	a.EnableEphemeralStorageChecks(loadStorageStats(t))
+	results := a.AnalyzePods([]v1.Pod{*pod}, nil, 0.8)
+	if len(results) != 1 {
+		t.Fatalf("got %d results, want 1", len(results))
+	}
+
+	var storage []string
+	for _, s := range results[0].Suggestions {
+		if strings.Contains(s, "emptyDir") {
+			storage = append(storage, s)
+		}
+	}
+	want := []string{
+		`⚠️ emptyDir "cache" at 87.9% of sizeLimit`,
+		`💾 emptyDir "scratch" has no sizeLimit`,
+		`💾 Memory-backed emptyDir "tmpfs" has no sizeLimit, usage counts against the memory limit`,
+	}
+	if strings.Join(storage, "\n") != strings.Join(want, "\n") {
+		t.Errorf("emptyDir suggestions = %q, want %q", storage, want)
+	}
+}
+
+func TestEphemeralStorageChecksDisabled(t *testing.T) {
+	pod := testPod("web", "api", testContainer("app", resources(cpuMemory("100m", "128Mi"), cpuMemory("100m", "128Mi"))))
+	pod.Spec.Volumes = []v1.Volume{{Name: "scratch", VolumeSource: v1.VolumeSource{EmptyDir: &v1.EmptyDirVolumeSource{}}}}
+	pod.Spec.Containers[0].VolumeMounts = []v1.VolumeMount{{Name: "scratch", MountPath: "/scratch"}}
+
+	r := newTestAnalyzer(t).AnalyzePods([]v1.Pod{*pod}, nil, 0.8)[0]
+	if hasSuggestion(r.Suggestions, "ephemeral-storage") || hasSuggestion(r.Suggestions, "emptyDir") {
+		t.Errorf("suggestions %q mention storage with the checks disabled", r.Suggestions)
+	}
+	if r.RecommendedEphemeralStorageLimit != "" {
+		t.Errorf("recommended ephemeral-storage limit %s with the checks disabled", r.RecommendedEphemeralStorageLimit)
+	}
+}
@@ -0,0 +1,259 @@
+package analyzer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// VPAResource identifies VerticalPodAutoscaler objects for the dynamic client.
+var VPAResource = schema.GroupVersionResource{
+	Group:    "autoscaling.k8s.io",
+	Version:  "v1",
+	Resource: "verticalpodautoscalers",
+}
+
+// VPA update modes. An unset mode defaults to Auto.
+const (
+	VPAUpdateModeOff      = "Off"
+	VPAUpdateModeInitial  = "Initial"
+	VPAUpdateModeRecreate = "Recreate"
+	VPAUpdateModeAuto     = "Auto"
+)
+
+// VPA is the subset of a VerticalPodAutoscaler used for comparison.
+type VPA struct {
+	Name       string
+	Namespace  string
+	TargetKind string
+	TargetName string
+	UpdateMode string
+	// RequestsOnly is set when every container policy keeps limits fixed
+	RequestsOnly bool
+	Containers   map[string]VPABounds
+}
+
+// VPABounds is a VPA's recommendation for one container.
+type VPABounds struct {
+	Target     v1.ResourceList
+	LowerBound v1.ResourceList
+	UpperBound v1.ResourceList
+}
+
+// VPARecommendation is the VPA matched to a container.
+type VPARecommendation struct {
+	Name       string
+	UpdateMode string
+	VPABounds
+}
+
+// vpaObject mirrors the parts of the autoscaling.k8s.io/v1 schema we read,
+// so the VPA client libraries are not needed.
+type vpaObject struct {
+	Metadata metav1.ObjectMeta `json:"metadata"`
+	Spec     struct {
+		TargetRef *struct {
+			Kind string `json:"kind"`
+			Name string `json:"name"`
+		} `json:"targetRef"`
+		UpdatePolicy *struct {
+			UpdateMode string `json:"updateMode"`
+		} `json:"updatePolicy"`
+		ResourcePolicy *struct {
+			ContainerPolicies []struct {
+				ContainerName    string `json:"containerName"`
+				ControlledValues string `json:"controlledValues"`
+			} `json:"containerPolicies"`
+		} `json:"resourcePolicy"`
+	} `json:"spec"`
+	Status struct {
+		Recommendation *struct {
+			ContainerRecommendations []struct {
+				ContainerName string          `json:"containerName"`
+				Target        v1.ResourceList `json:"target"`
+				LowerBound    v1.ResourceList `json:"lowerBound"`
+				UpperBound    v1.ResourceList `json:"upperBound"`
+			} `json:"containerRecommendations"`
+		} `json:"recommendation"`
+	} `json:"status"`
+}
+
+// GetVPAs lists VerticalPodAutoscalers through the dynamic client. It fails
+// when the VPA CRD is not installed.
+func (a *PodAnalyzer) GetVPAs(ctx context.Context, namespace string) ([]VPA, error) {
+	list, err := a.client.DynamicClient.Resource(VPAResource).Namespace(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	vpas := make([]VPA, 0, len(list.Items))
+	for _, item := range list.Items {
+		data, err := item.MarshalJSON()
+		if err != nil {
+			return nil, err
+		}
+		var obj vpaObject
+		if err := json.Unmarshal(data, &obj); err != nil {
+			return nil, fmt.Errorf("failed to parse VerticalPodAutoscaler %s/%s: %v", item.GetNamespace(), item.GetName(), err)
+		}
+		vpas = append(vpas, obj.toVPA())
+	}
+	return vpas, nil
+}
+
+func (o vpaObject) toVPA() VPA {
+	vpa := VPA{
+		Name:       o.Metadata.Name,
+		Namespace:  o.Metadata.Namespace,
+		UpdateMode: VPAUpdateModeAuto,
+		Containers: map[string]VPABounds{},
+	}
+	if o.Spec.TargetRef != nil {
+		vpa.TargetKind = o.Spec.TargetRef.Kind
+		vpa.TargetName = o.Spec.TargetRef.Name
+	}
+	if o.Spec.UpdatePolicy != nil && o.Spec.UpdatePolicy.UpdateMode != "" {
+		vpa.UpdateMode = o.Spec.UpdatePolicy.UpdateMode
+	}
+	if o.Spec.ResourcePolicy != nil && len(o.Spec.ResourcePolicy.ContainerPolicies) > 0 {
+		vpa.RequestsOnly = true
+		for _, policy := range o.Spec.ResourcePolicy.ContainerPolicies {
+			if policy.ControlledValues != "RequestsOnly" {
+				vpa.RequestsOnly = false
+			}
+		}
+	}
+	if o.Status.Recommendation != nil {
+		for _, rec := range o.Status.Recommendation.ContainerRecommendations {
+			vpa.Containers[rec.ContainerName] = VPABounds{
+				Target:     rec.Target,
+				LowerBound: rec.LowerBound,
+				UpperBound: rec.UpperBound,
+			}
+		}
+	}
+	return vpa
+}
+
+// SetVPAs enables comparison with existing VerticalPodAutoscalers.
+func (a *PodAnalyzer) SetVPAs(vpas []VPA) {
+	a.vpas = map[string]VPA{}
+	for _, vpa := range vpas {
+		a.vpas[workloadKey(vpa.Namespace, vpa.TargetKind, vpa.TargetName)] = vpa
+	}
+}
+
+func workloadKey(namespace, kind, name string) string {
+	return namespace + "/" + kind + "/" + name
+}
+
+// vpaSuggestions attaches the matching VPA recommendation to the analysis
+// and warns about limits that fight a VPA that applies its own values.
+func (a *PodAnalyzer) vpaSuggestions(analysis *PodAnalysis, container v1.Container) []string {
+	vpa, ok := a.vpas[workloadKey(analysis.Namespace, analysis.WorkloadKind, analysis.WorkloadName)]
+	if !ok {
+		return nil
+	}
+
+	bounds := vpa.Containers[container.Name]
+	analysis.VPA = &VPARecommendation{Name: vpa.Name, UpdateMode: vpa.UpdateMode, VPABounds: bounds}
+
+	if vpa.UpdateMode == VPAUpdateModeOff || vpa.UpdateMode == VPAUpdateModeInitial {
+		return nil
+	}
+
+	var suggestions []string
+	if len(container.Resources.Limits) > 0 {
+		if vpa.RequestsOnly {
+			suggestions = append(suggestions,
+				fmt.Sprintf("🔁 VPA %s (%s) only updates requests; the fixed limits here cap its recommendations", vpa.Name, vpa.UpdateMode))
+		} else {
+			suggestions = append(suggestions,
+				fmt.Sprintf("🔁 VPA %s (%s) scales limits with requests; limits set here will be rewritten on every eviction", vpa.Name, vpa.UpdateMode))
+		}
+	}
+	for _, name := range []v1.ResourceName{v1.ResourceCPU, v1.ResourceMemory} {
+		target, hasTarget := bounds.Target[name]
+		limit, hasLimit := container.Resources.Limits[name]
+		if hasTarget && hasLimit && target.Cmp(limit) > 0 {
+			suggestions = append(suggestions,
+				fmt.Sprintf("⚠️ VPA %s target %s %s exceeds the %s limit %s", vpa.Name, name, target.String(), name, limit.String()))
+		}
+	}
+	return suggestions
+}
+
+// vpaKinds are the workload kinds a VPA can target.
+var vpaKinds = map[string]string{
+	"Deployment":  "apps/v1",
+	"StatefulSet": "apps/v1",
+	"DaemonSet":   "apps/v1",
+	"ReplicaSet":  "apps/v1",
+}
+
+type vpaManifest struct {
+	APIVersion string `yaml:"apiVersion"`
+	Kind       string `yaml:"kind"`
+	Metadata   struct {
+		Name      string `yaml:"name"`
+		Namespace string `yaml:"namespace"`
+	} `yaml:"metadata"`
+	Spec struct {
+		TargetRef struct {
+			APIVersion string `yaml:"apiVersion"`
+			Kind       string `yaml:"kind"`
+			Name       string `yaml:"name"`
+		} `yaml:"targetRef"`
+		UpdatePolicy struct {
+			UpdateMode string `yaml:"updateMode"`
+		} `yaml:"updatePolicy"`
+	} `yaml:"spec"`
+}
+
+// GenerateVPAs renders VerticalPodAutoscalers in Off (recommendation-only)
+// mode, as multi-document YAML, for every analyzed workload without a VPA.
+// Bare pods and Jobs are skipped since a VPA cannot target them.
+func (a *PodAnalyzer) GenerateVPAs(results []PodAnalysis) ([]byte, error) {
+	seen := map[string]bool{}
+	var keys []string
+	for _, result := range results {
+		if _, ok := vpaKinds[result.WorkloadKind]; !ok {
+			continue
+		}
+		key := workloadKey(result.Namespace, result.WorkloadKind, result.WorkloadName)
+		if _, exists := a.vpas[key]; exists || seen[key] {
+			continue
+		}
+		seen[key] = true
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var docs []string
+	for _, key := range keys {
+		parts := strings.SplitN(key, "/", 3)
+		var m vpaManifest
+		m.APIVersion = VPAResource.GroupVersion().String()
+		m.Kind = "VerticalPodAutoscaler"
+		m.Metadata.Name = parts[2]
+		m.Metadata.Namespace = parts[0]
+		m.Spec.TargetRef.APIVersion = vpaKinds[parts[1]]
+		m.Spec.TargetRef.Kind = parts[1]
+		m.Spec.TargetRef.Name = parts[2]
+		m.Spec.UpdatePolicy.UpdateMode = VPAUpdateModeOff
+
+		data, err := yaml.Marshal(m)
+		if err != nil {
+			return nil, err
+		}
+		docs = append(docs, string(data))
+	}
+	return []byte(strings.Join(docs, "---\n")), nil
+}
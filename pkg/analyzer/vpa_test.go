@@ -0,0 +1,292 @@
+package analyzer
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/fake"
+	clienttesting "k8s.io/client-go/testing"
+
+	"pod-limit-checker/pkg/kubernetes"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata/golden")
+
+// golden compares got with testdata/golden/name, or rewrites it with -update.
+func golden(t *testing.T, name string, got []byte) {
+	t.Helper()
+	path := filepath.Join("testdata", "golden", name)
+	if *update {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("%v (run go test -update to create it)", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("%s differs from the golden file, run go test -update and review the diff:\n%s", name, got)
+	}
+}
+
+// decodeObjects reads multi-document YAML into unstructured objects.
+func decodeObjects(t *testing.T, data []byte) []runtime.Object {
+	t.Helper()
+	decoder := utilyaml.NewYAMLOrJSONDecoder(bytes.NewReader(data), 4096)
+	var objects []runtime.Object
+	for {
+		obj := &unstructured.Unstructured{}
+		if err := decoder.Decode(&obj.Object); err == io.EOF {
+			return objects
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		if len(obj.Object) > 0 {
+			objects = append(objects, obj)
+		}
+	}
+}
+
+// newVPAAnalyzer returns an analyzer whose dynamic client serves vpas.
+func newVPAAnalyzer(t *testing.T, vpas ...runtime.Object) (*PodAnalyzer, *dynamicfake.FakeDynamicClient) {
+	t.Helper()
+	dynamic := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{VPAResource: "VerticalPodAutoscalerList"}, vpas...)
+	return NewPodAnalyzer(&kubernetes.Client{
+		Clientset:     fake.NewSimpleClientset(),
+		DynamicClient: dynamic,
+	}), dynamic
+}
+
+func loadVPAs(t *testing.T) []runtime.Object {
+	t.Helper()
+	data, err := os.ReadFile("testdata/vpas.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return decodeObjects(t, data)
+}
+
+func TestGetVPAs(t *testing.T) {
+	a, _ := newVPAAnalyzer(t, loadVPAs(t)...)
+
+	vpas, err := a.GetVPAs(context.Background(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	byName := map[string]VPA{}
+	for _, vpa := range vpas {
+		byName[vpa.Namespace+"/"+vpa.Name] = vpa
+	}
+	if len(byName) != 4 {
+		t.Fatalf("got VPAs %v, want 4", vpas)
+	}
+
+	api := byName["web/api"]
+	if api.TargetKind != "Deployment" || api.TargetName != "api" || api.UpdateMode != VPAUpdateModeAuto || api.RequestsOnly {
+		t.Errorf("web/api = %+v", api)
+	}
+	bounds := api.Containers["app"]
+	if target := bounds.Target[v1.ResourceCPU]; target.String() != "600m" {
+		t.Errorf("web/api target cpu = %s, want 600m", target.String())
+	}
+	if upper := bounds.UpperBound[v1.ResourceMemory]; upper.String() != "512Mi" {
+		t.Errorf("web/api upper bound memory = %s, want 512Mi", upper.String())
+	}
+
+	if cache := byName["web/cache"]; cache.UpdateMode != VPAUpdateModeRecreate || !cache.RequestsOnly || len(cache.Containers) != 0 {
+		t.Errorf("web/cache = %+v, want Recreate, requests only, no recommendation", cache)
+	}
+	// An unset update mode defaults to Auto
+	if agent := byName["ops/agent"]; agent.UpdateMode != VPAUpdateModeAuto || agent.TargetKind != "DaemonSet" {
+		t.Errorf("ops/agent = %+v, want Auto DaemonSet", agent)
+	}
+	// One container policy that also controls limits is enough
+	if reports := byName["batch/reports"]; reports.UpdateMode != VPAUpdateModeOff || reports.RequestsOnly {
+		t.Errorf("batch/reports = %+v, want Off, not requests only", reports)
+	}
+
+	web, err := a.GetVPAs(context.Background(), "web")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(web) != 2 {
+		t.Errorf("got %d VPAs in web, want 2", len(web))
+	}
+}
+
+// TestGetVPAsMissingCRD checks the error check relies on to tell a missing
+// VPA CRD from a failed list.
+func TestGetVPAsMissingCRD(t *testing.T) {
+	a, dynamic := newVPAAnalyzer(t)
+	dynamic.PrependReactor("list", VPAResource.Resource, func(clienttesting.Action) (bool, runtime.Object, error) {
+		return true, nil, apierrors.NewNotFound(VPAResource.GroupResource(), "")
+	})
+
+	vpas, err := a.GetVPAs(context.Background(), "")
+	if !apierrors.IsNotFound(err) {
+		t.Errorf("GetVPAs error = %v, want NotFound", err)
+	}
+	if vpas != nil {
+		t.Errorf("GetVPAs = %v with an error", vpas)
+	}
+}
+
+func TestVPASuggestions(t *testing.T) {
+	a, _ := newVPAAnalyzer(t, loadVPAs(t)...)
+	vpas, err := a.GetVPAs(context.Background(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	a.SetVPAs(vpas)
+
+	tests := []struct {
+		name             string
+		namespace        string
+		kind             string
+		workload         string
+		resources        v1.ResourceRequirements
+		vpa              string
+		suggestions      []string
+		wantNoSuggestion bool
+	}{
+		{
+			name:        "auto scales limits",
+			namespace:   "web",
+			kind:        "Deployment",
+			workload:    "api",
+			resources:   resources(cpuMemory("100m", "128Mi"), cpuMemory("500m", "512Mi")),
+			vpa:         "api",
+			suggestions: []string{"VPA api (Auto) scales limits with requests", "VPA api target cpu 600m exceeds the cpu limit 500m"},
+		},
+		{
+			name:             "auto without limits",
+			namespace:        "web",
+			kind:             "Deployment",
+			workload:         "api",
+			resources:        resources(cpuMemory("100m", "128Mi"), nil),
+			vpa:              "api",
+			wantNoSuggestion: true,
+		},
+		{
+			name:        "requests only",
+			namespace:   "web",
+			kind:        "StatefulSet",
+			workload:    "cache",
+			resources:   resources(cpuMemory("100m", "128Mi"), cpuMemory("500m", "512Mi")),
+			vpa:         "cache",
+			suggestions: []string{"VPA cache (Recreate) only updates requests"},
+		},
+		{
+			name:             "off is reported without suggestions",
+			namespace:        "batch",
+			kind:             "Deployment",
+			workload:         "reports",
+			resources:        resources(cpuMemory("100m", "128Mi"), cpuMemory("500m", "512Mi")),
+			vpa:              "reports",
+			wantNoSuggestion: true,
+		},
+		{
+			name:             "same name in another namespace",
+			namespace:        "shop",
+			kind:             "Deployment",
+			workload:         "api",
+			resources:        resources(cpuMemory("100m", "128Mi"), cpuMemory("500m", "512Mi")),
+			wantNoSuggestion: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			container := testContainer("app", tt.resources)
+			analysis := PodAnalysis{Namespace: tt.namespace, WorkloadKind: tt.kind, WorkloadName: tt.workload}
+			suggestions := a.vpaSuggestions(&analysis, container)
+
+			if tt.vpa == "" {
+				if analysis.VPA != nil {
+					t.Errorf("matched VPA %s, want none", analysis.VPA.Name)
+				}
+			} else if analysis.VPA == nil || analysis.VPA.Name != tt.vpa {
+				t.Errorf("matched VPA %v, want %s", analysis.VPA, tt.vpa)
+			}
+			for _, want := range tt.suggestions {
+				if !hasSuggestion(suggestions, want) {
+					t.Errorf("suggestions %q lack %q", suggestions, want)
+				}
+			}
+			if tt.wantNoSuggestion && len(suggestions) > 0 {
+				t.Errorf("suggestions = %q, want none", suggestions)
+			}
+		})
+	}
+}
+
+func TestGenerateVPAs(t *testing.T) {
+	a, _ := newVPAAnalyzer(t, loadVPAs(t)...)
+	existing, err := a.GetVPAs(context.Background(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	a.SetVPAs(existing)
+
+	results := []PodAnalysis{
+		{Namespace: "web", WorkloadKind: "Deployment", WorkloadName: "checkout", ContainerName: "app"},
+		{Namespace: "web", WorkloadKind: "Deployment", WorkloadName: "checkout", ContainerName: "proxy"},
+		{Namespace: "data", WorkloadKind: "StatefulSet", WorkloadName: "postgres", ContainerName: "db"},
+		{Namespace: "ops", WorkloadKind: "DaemonSet", WorkloadName: "node-exporter", ContainerName: "exporter"},
+		{Namespace: "web", WorkloadKind: "ReplicaSet", WorkloadName: "legacy", ContainerName: "app"},
+		// Already covered by a VPA
+		{Namespace: "web", WorkloadKind: "Deployment", WorkloadName: "api", ContainerName: "app"},
+		{Namespace: "ops", WorkloadKind: "DaemonSet", WorkloadName: "agent", ContainerName: "agent"},
+		// A VPA cannot target these
+		{Namespace: "batch", WorkloadKind: "Job", WorkloadName: "nightly", ContainerName: "job"},
+		{Namespace: "web", WorkloadKind: "Pod", WorkloadName: "debug", ContainerName: "shell"},
+	}
+
+	data, err := a.GenerateVPAs(results)
+	if err != nil {
+		t.Fatal(err)
+	}
+	golden(t, "vpas.yaml", data)
+
+	// The generated VPAs are recognised as existing on the next run
+	generated := decodeObjects(t, data)
+	if len(generated) != 4 {
+		t.Fatalf("generated %d VPAs, want 4", len(generated))
+	}
+	rerun, _ := newVPAAnalyzer(t, append(loadVPAs(t), generated...)...)
+	vpas, err := rerun.GetVPAs(context.Background(), metav1.NamespaceAll)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rerun.SetVPAs(vpas)
+	for _, vpa := range vpas {
+		if vpa.Name == "checkout" && vpa.UpdateMode != VPAUpdateModeOff {
+			t.Errorf("generated VPA %s has mode %s, want Off", vpa.Name, vpa.UpdateMode)
+		}
+	}
+	again, err := rerun.GenerateVPAs(results)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(again) != 0 {
+		t.Errorf("second run generated:\n%s", again)
+	}
+}
@@ -0,0 +1,110 @@
+package analyzer
+
+import (
+	"regexp"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// WorkloadOf returns the kind and name of the top-level workload owning a
+// pod, derived from its controller reference without extra API calls.
+// ReplicaSets created by a Deployment are resolved to the Deployment by
+// stripping the pod-template-hash suffix. Bare pods are their own workload.
+func WorkloadOf(pod v1.Pod) (string, string) {
+	ref := metav1.GetControllerOf(&pod)
+	if ref == nil {
+		return "Pod", pod.Name
+	}
+
+	if ref.Kind == "ReplicaSet" {
+		if hash := pod.Labels["pod-template-hash"]; hash != "" && strings.HasSuffix(ref.Name, "-"+hash) {
+			return "Deployment", strings.TrimSuffix(ref.Name, "-"+hash)
+		}
+	}
+
+	return ref.Kind, ref.Name
+}
+
+// cronJobSuffix is the scheduled-time suffix of the Jobs a CronJob creates,
+// in minutes since the epoch.
+var cronJobSuffix = regexp.MustCompile(`-[0-9]{8,}$`)
+
+// CronJobName returns the name of the CronJob that created a Job, judged
+// from the Job's name alone. ok is false for names without the suffix.
+func CronJobName(job string) (name string, ok bool) {
+	if !cronJobSuffix.MatchString(job) {
+		return "", false
+	}
+	return cronJobSuffix.ReplaceAllString(job, ""), true
+}
+
+// WorkloadRecommendation is the usage-based recommendation for a container
+// of a workload. Replicas can differ, so it holds the largest of theirs.
+type WorkloadRecommendation struct {
+	Namespace     string
+	WorkloadKind  string
+	WorkloadName  string
+	Container     string
+	CPULimit      string
+	CPURequest    string
+	MemoryLimit   string
+	MemoryRequest string
+}
+
+// WorkloadRecommendations merges the recommendations of the replicas of
+// each workload container, in the order the containers first appear.
+// Containers without a recommendation are left out.
+func WorkloadRecommendations(results []PodAnalysis) []WorkloadRecommendation {
+	byKey := map[string]*WorkloadRecommendation{}
+	var keys []string
+	for _, result := range results {
+		if result.RecommendedCPULimit == "" || result.RecommendedMemoryLimit == "" {
+			continue
+		}
+		kind, name := result.WorkloadKind, result.WorkloadName
+		if kind == "" {
+			kind, name = "Pod", result.PodName
+		}
+
+		key := workloadKey(result.Namespace, kind, name) + "/" + result.ContainerName
+		r, ok := byKey[key]
+		if !ok {
+			byKey[key] = &WorkloadRecommendation{
+				Namespace:     result.Namespace,
+				WorkloadKind:  kind,
+				WorkloadName:  name,
+				Container:     result.ContainerName,
+				CPULimit:      result.RecommendedCPULimit,
+				CPURequest:    result.RecommendedCPURequest,
+				MemoryLimit:   result.RecommendedMemoryLimit,
+				MemoryRequest: result.RecommendedMemoryRequest,
+			}
+			keys = append(keys, key)
+			continue
+		}
+		r.CPULimit = largerQuantity(r.CPULimit, result.RecommendedCPULimit)
+		r.CPURequest = largerQuantity(r.CPURequest, result.RecommendedCPURequest)
+		r.MemoryLimit = largerQuantity(r.MemoryLimit, result.RecommendedMemoryLimit)
+		r.MemoryRequest = largerQuantity(r.MemoryRequest, result.RecommendedMemoryRequest)
+	}
+
+	recommendations := make([]WorkloadRecommendation, 0, len(keys))
+	for _, key := range keys {
+		recommendations = append(recommendations, *byKey[key])
+	}
+	return recommendations
+}
+
+// largerQuantity returns the larger of two quantity strings, preferring
+// the one that parses.
+func largerQuantity(a, b string) string {
+	qa, errA := resource.ParseQuantity(a)
+	qb, errB := resource.ParseQuantity(b)
+	if errA != nil || errB == nil && qb.Cmp(qa) > 0 {
+		return b
+	}
+	return a
+}
@@ -0,0 +1,27 @@
+package analyzer
+
+import "testing"
+
+func TestCronJobName(t *testing.T) {
+	tests := []struct {
+		job    string
+		want   string
+		wantOK bool
+	}{
+		{"report-28790640", "report", true},
+		{"nightly-backup-287906401", "nightly-backup", true},
+		// Releases and short counters are not schedule times
+		{"migrate-v2", "", false},
+		{"migrate-2", "", false},
+		{"seed-2024", "", false},
+		{"28790640", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.job, func(t *testing.T) {
+			name, ok := CronJobName(tt.job)
+			if name != tt.want || ok != tt.wantOK {
+				t.Errorf("CronJobName(%q) = %q, %v, want %q, %v", tt.job, name, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
@@ -0,0 +1,185 @@
+package cost
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"sort"
+
+	"gopkg.in/yaml.v2"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	"pod-limit-checker/pkg/analyzer"
+)
+
+const (
+	defaultHoursPerMonth = 730
+	bytesPerGiB          = 1024 * 1024 * 1024
+)
+
+// Rate is the price of one CPU core and one GiB of memory for one hour.
+type Rate struct {
+	CPUCoreHour   float64 `yaml:"cpuCoreHour"`
+	MemoryGiBHour float64 `yaml:"memoryGiBHour"`
+}
+
+// PriceTable maps nodes to rates. The value of NodeLabel on each node
+// (e.g. an instance type or node pool name) selects an entry in Rates;
+// nodes without a matching entry are priced at Default.
+type PriceTable struct {
+	Currency      string          `yaml:"currency"`
+	HoursPerMonth float64         `yaml:"hoursPerMonth"`
+	NodeLabel     string          `yaml:"nodeLabel"`
+	Default       Rate            `yaml:"default"`
+	Rates         map[string]Rate `yaml:"rates"`
+}
+
+// LoadPriceTable reads a price table from a YAML file.
+func LoadPriceTable(path string) (*PriceTable, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read price file: %v", err)
+	}
+
+	table := &PriceTable{}
+	if err := yaml.UnmarshalStrict(data, table); err != nil {
+		return nil, fmt.Errorf("failed to parse price file %s: %v", path, err)
+	}
+	if table.HoursPerMonth == 0 {
+		table.HoursPerMonth = defaultHoursPerMonth
+	}
+	if table.Currency == "" {
+		table.Currency = "USD"
+	}
+
+	return table, nil
+}
+
+// rateFor returns the rate for a node given its labels.
+func (t *PriceTable) rateFor(labels map[string]string) Rate {
+	if t.NodeLabel != "" {
+		if rate, ok := t.Rates[labels[t.NodeLabel]]; ok {
+			return rate
+		}
+	}
+	return t.Default
+}
+
+// monthly prices a CPU amount in millicores and a memory amount in bytes.
+func (t *PriceTable) monthly(rate Rate, cpuMilli, memBytes int64) float64 {
+	cores := float64(cpuMilli) / 1000
+	gib := float64(memBytes) / bytesPerGiB
+	return (cores*rate.CPUCoreHour + gib*rate.MemoryGiBHour) * t.HoursPerMonth
+}
+
+// Estimate is the monthly cost of current requests versus recommended
+// requests, rolled up per workload, per namespace and for the cluster.
+type Estimate struct {
+	Currency    string     `json:"currency" yaml:"currency"`
+	Cluster     CostLine   `json:"cluster" yaml:"cluster"`
+	ByNamespace []CostLine `json:"byNamespace" yaml:"byNamespace"`
+	ByWorkload  []CostLine `json:"byWorkload" yaml:"byWorkload"`
+}
+
+// CostLine is the monthly cost of one group of containers, rounded to
+// cents once summed. Savings is the difference of the rounded costs, so
+// the three figures add up as printed.
+type CostLine struct {
+	Name      string  `json:"name" yaml:"name"`
+	Current   float64 `json:"current" yaml:"current"`
+	Projected float64 `json:"projected" yaml:"projected"`
+	Savings   float64 `json:"savings" yaml:"savings"`
+}
+
+func (l *CostLine) add(current, projected float64) {
+	l.Current += current
+	l.Projected += projected
+	l.Savings = l.Current - l.Projected
+}
+
+// rounded returns the line with its costs rounded to cents.
+func (l CostLine) rounded() CostLine {
+	current, projected := cents(l.Current), cents(l.Projected)
+	return CostLine{Name: l.Name, Current: current, Projected: projected, Savings: cents(current - projected)}
+}
+
+func cents(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
+
+// EstimateCost prices every container's requests on the node it runs on.
+// Projected cost uses the recommended requests where the analyzer produced
+// them and the current requests otherwise, so containers without usage
+// data contribute no savings.
+func EstimateCost(results []analyzer.PodAnalysis, nodes []v1.Node, table *PriceTable) *Estimate {
+	nodeLabels := make(map[string]map[string]string, len(nodes))
+	for _, node := range nodes {
+		nodeLabels[node.Name] = node.Labels
+	}
+
+	estimate := &Estimate{Currency: table.Currency, Cluster: CostLine{Name: "cluster"}}
+	byNamespace := map[string]*CostLine{}
+	byWorkload := map[string]*CostLine{}
+
+	for _, result := range results {
+		rate := table.rateFor(nodeLabels[result.NodeName])
+
+		cpuMilli, memBytes := requestValues(result.CurrentRequests)
+		current := table.monthly(rate, cpuMilli, memBytes)
+
+		if q, err := resource.ParseQuantity(result.RecommendedCPURequest); err == nil {
+			cpuMilli = q.MilliValue()
+		}
+		if q, err := resource.ParseQuantity(result.RecommendedMemoryRequest); err == nil {
+			memBytes = q.Value()
+		}
+		projected := table.monthly(rate, cpuMilli, memBytes)
+
+		estimate.Cluster.add(current, projected)
+		costLine(byNamespace, result.Namespace).add(current, projected)
+		workload := fmt.Sprintf("%s/%s/%s", result.Namespace, result.WorkloadKind, result.WorkloadName)
+		costLine(byWorkload, workload).add(current, projected)
+	}
+
+	estimate.Cluster = estimate.Cluster.rounded()
+	estimate.ByNamespace = sortedLines(byNamespace)
+	estimate.ByWorkload = sortedLines(byWorkload)
+	return estimate
+}
+
+func requestValues(requests v1.ResourceList) (int64, int64) {
+	var cpuMilli, memBytes int64
+	if cpu, ok := requests[v1.ResourceCPU]; ok {
+		cpuMilli = cpu.MilliValue()
+	}
+	if mem, ok := requests[v1.ResourceMemory]; ok {
+		memBytes = mem.Value()
+	}
+	return cpuMilli, memBytes
+}
+
+func costLine(lines map[string]*CostLine, name string) *CostLine {
+	line, ok := lines[name]
+	if !ok {
+		line = &CostLine{Name: name}
+		lines[name] = line
+	}
+	return line
+}
+
+// sortedLines rounds lines and orders them by savings, largest first.
+func sortedLines(lines map[string]*CostLine) []CostLine {
+	sorted := make([]CostLine, 0, len(lines))
+	for _, line := range lines {
+		sorted = append(sorted, line.rounded())
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].Savings != sorted[j].Savings {
+			return sorted[i].Savings > sorted[j].Savings
+		}
+		return sorted[i].Name < sorted[j].Name
+	})
+	return sorted
+}
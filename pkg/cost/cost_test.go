@@ -0,0 +1,115 @@
+package cost
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"pod-limit-checker/pkg/analyzer"
+)
+
+func TestLoadPriceTable(t *testing.T) {
+	files, err := filepath.Glob("../../config/pricing/*.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) == 0 {
+		t.Fatal("no price tables in config/pricing")
+	}
+	for _, file := range files {
+		table, err := LoadPriceTable(file)
+		if err != nil {
+			t.Errorf("%s: %v", file, err)
+			continue
+		}
+		if table.Currency == "" || table.HoursPerMonth != 730 || table.Default.CPUCoreHour <= 0 || table.Default.MemoryGiBHour <= 0 {
+			t.Errorf("%s = %+v, want a currency, 730 hours and a default rate", file, table)
+		}
+		if (table.NodeLabel == "") != (len(table.Rates) == 0) {
+			t.Errorf("%s: rates %v keyed by node label %q", file, table.Rates, table.NodeLabel)
+		}
+	}
+
+	aws, err := LoadPriceTable("../../config/pricing/aws.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rate := aws.Rates["r5.large"]; rate != (Rate{CPUCoreHour: 0.028, MemoryGiBHour: 0.006}) {
+		t.Errorf("r5.large = %+v", rate)
+	}
+}
+
+func TestLoadPriceTableDefaults(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	table, err := LoadPriceTable(write("minimal.yaml", "default:\n  cpuCoreHour: 0.02\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if table.Currency != "USD" || table.HoursPerMonth != defaultHoursPerMonth {
+		t.Errorf("defaults = %s, %v hours, want USD, %d", table.Currency, table.HoursPerMonth, defaultHoursPerMonth)
+	}
+
+	if _, err := LoadPriceTable(write("typo.yaml", "default:\n  cpuCoreHours: 0.02\n")); err == nil || !strings.Contains(err.Error(), "cpuCoreHours") {
+		t.Errorf("error = %v, want the unknown field", err)
+	}
+	if _, err := LoadPriceTable(filepath.Join(dir, "missing.yaml")); err == nil {
+		t.Error("missing price file accepted")
+	}
+}
+
+func TestEstimateCost(t *testing.T) {
+	table, err := LoadPriceTable("../../config/pricing/aws.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	node := func(name, instanceType string) v1.Node {
+		return v1.Node{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{"node.kubernetes.io/instance-type": instanceType}}}
+	}
+	nodes := []v1.Node{node("node-r5", "r5.large"), node("node-x", "x9.huge")}
+	requests := func(cpu, memory string) v1.ResourceList {
+		return v1.ResourceList{v1.ResourceCPU: resource.MustParse(cpu), v1.ResourceMemory: resource.MustParse(memory)}
+	}
+	results := []analyzer.PodAnalysis{
+		// r5.large rate: (1 x 0.028 + 2 x 0.006) x 730 = 29.20, (0.5 x 0.028 + 1 x 0.006) x 730 = 14.60
+		{Namespace: "web", WorkloadKind: "Deployment", WorkloadName: "api", NodeName: "node-r5",
+			CurrentRequests: requests("1", "2Gi"), RecommendedCPURequest: "500m", RecommendedMemoryRequest: "1Gi"},
+		// Unknown instance type, default rate: (0.25 x 0.0316 + 0.5 x 0.0042) x 730 = 7.30, no recommendation
+		{Namespace: "web", WorkloadKind: "Deployment", WorkloadName: "api", NodeName: "node-x",
+			CurrentRequests: requests("250m", "512Mi")},
+		// Not scheduled, default rate: 2.69005 now and 1.345025 recommended
+		{Namespace: "jobs", WorkloadKind: "Pod", WorkloadName: "worker-0",
+			CurrentRequests: requests("100m", "128Mi"), RecommendedCPURequest: "50m", RecommendedMemoryRequest: "64Mi"},
+	}
+
+	estimate := EstimateCost(results, nodes, table)
+	want := &Estimate{
+		Currency: "USD",
+		// 39.19005 and 23.245025; savings are those of the rounded costs
+		Cluster: CostLine{Name: "cluster", Current: 39.19, Projected: 23.25, Savings: 15.94},
+		ByNamespace: []CostLine{
+			{Name: "web", Current: 36.5, Projected: 21.9, Savings: 14.6},
+			{Name: "jobs", Current: 2.69, Projected: 1.35, Savings: 1.34},
+		},
+		ByWorkload: []CostLine{
+			{Name: "web/Deployment/api", Current: 36.5, Projected: 21.9, Savings: 14.6},
+			{Name: "jobs/Pod/worker-0", Current: 2.69, Projected: 1.35, Savings: 1.34},
+		},
+	}
+	if !reflect.DeepEqual(estimate, want) {
+		t.Errorf("estimate = %+v\nwant %+v", estimate, want)
+	}
+}
@@ -0,0 +1,291 @@
+package gitops
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// edit changes one line of a file: it replaces the runes [start, end) with
+// text and then inserts lines after it. Edits work on the original text,
+// so everything they do not touch, comments included, stays as it is.
+type edit struct {
+	// line is 1-based
+	line       int
+	start, end int
+	text       string
+	replace    bool
+	insert     []string
+}
+
+// sections are the resource lists set on a container, in the order they
+// are written when missing.
+var sections = []string{"limits", "requests"}
+
+// resourceNames are the resources set in each section.
+var resourceNames = []string{"cpu", "memory"}
+
+// containersOf returns the containers sequence of a workload's pod spec.
+func containersOf(m *manifest) *yaml.Node {
+	spec := lookup(m.doc, "spec")
+	switch m.kind {
+	case "Pod":
+	case "CronJob":
+		spec = lookup(lookup(lookup(lookup(spec, "jobTemplate"), "spec"), "template"), "spec")
+	default:
+		spec = lookup(lookup(spec, "template"), "spec")
+	}
+	containers := lookup(spec, "containers")
+	if containers == nil || containers.Kind != yaml.SequenceNode {
+		return nil
+	}
+	return containers
+}
+
+func findContainer(containers *yaml.Node, name string) *yaml.Node {
+	for _, container := range containers.Content {
+		if scalar(lookup(container, "name")) == name {
+			return container
+		}
+	}
+	return nil
+}
+
+// currentResources reads the values set on a container.
+func currentResources(container *yaml.Node) Resources {
+	resources := lookup(container, "resources")
+	value := func(section, name string) string {
+		return scalar(lookup(lookup(resources, section), name))
+	}
+	return Resources{
+		CPULimit:      value("limits", "cpu"),
+		CPURequest:    value("requests", "cpu"),
+		MemoryLimit:   value("limits", "memory"),
+		MemoryRequest: value("requests", "memory"),
+	}
+}
+
+// containerEdits returns the edits that set target on a container. Missing
+// keys are inserted right below their parent key, or below the container
+// name, at the indentation of their siblings.
+func containerEdits(doc, container *yaml.Node, target Resources) ([]edit, error) {
+	step := indentStep(doc)
+	values := map[string]map[string]string{
+		"limits":   {"cpu": target.CPULimit, "memory": target.MemoryLimit},
+		"requests": {"cpu": target.CPURequest, "memory": target.MemoryRequest},
+	}
+
+	// block renders sections at an indentation
+	block := func(indent int, names []string) []string {
+		var lines []string
+		for _, section := range names {
+			lines = append(lines, spaces(indent)+section+":")
+			for _, name := range resourceNames {
+				lines = append(lines, spaces(indent+step)+name+": "+values[section][name])
+			}
+		}
+		return lines
+	}
+
+	resourcesKey := lookupKey(container, "resources")
+	if resourcesKey == nil {
+		nameKey := lookupKey(container, "name")
+		nameValue := lookup(container, "name")
+		indent := nameKey.Column - 1
+		lines := append([]string{spaces(indent) + "resources:"}, block(indent+step, sections)...)
+		return []edit{{line: nameValue.Line, insert: lines}}, nil
+	}
+
+	resources := lookup(container, "resources")
+	if isEmpty(resources) {
+		e, err := clearValue(resourcesKey, resources)
+		if err != nil {
+			return nil, err
+		}
+		e.insert = block(resourcesKey.Column-1+step, sections)
+		return []edit{e}, nil
+	}
+	if resources.Kind != yaml.MappingNode || resources.Style&yaml.FlowStyle != 0 {
+		return nil, fmt.Errorf("resources of line %d use flow style", resourcesKey.Line)
+	}
+
+	var edits []edit
+	var missing []string
+	for _, section := range sections {
+		key, value := lookupKey(resources, section), lookup(resources, section)
+		switch {
+		case key == nil:
+			missing = append(missing, section)
+		case isEmpty(value):
+			e, err := clearValue(key, value)
+			if err != nil {
+				return nil, err
+			}
+			indent := key.Column - 1 + step
+			for _, name := range resourceNames {
+				e.insert = append(e.insert, spaces(indent)+name+": "+values[section][name])
+			}
+			edits = append(edits, e)
+		case value.Kind == yaml.MappingNode && value.Style&yaml.FlowStyle == 0:
+			var inserted []string
+			for _, name := range resourceNames {
+				want := values[section][name]
+				current := lookup(value, name)
+				if current == nil {
+					inserted = append(inserted, spaces(value.Column-1)+name+": "+want)
+					continue
+				}
+				if current.Kind != yaml.ScalarNode || sameQuantity(current.Value, want) {
+					continue
+				}
+				e, err := replaceScalar(current, want)
+				if err != nil {
+					return nil, err
+				}
+				edits = append(edits, e)
+			}
+			if len(inserted) > 0 {
+				edits = append(edits, edit{line: lastLine(key, value), insert: inserted})
+			}
+		default:
+			return nil, fmt.Errorf("resources.%s of line %d use flow style", section, key.Line)
+		}
+	}
+	if len(missing) > 0 {
+		edits = append(edits, edit{line: resourcesKey.Line, insert: block(resources.Column-1, missing)})
+	}
+	return edits, nil
+}
+
+// lastLine returns the line after which keys are appended to a mapping:
+// that of its last value when it is a single-line scalar, else the line of
+// the mapping's key.
+func lastLine(key, mapping *yaml.Node) int {
+	last := mapping.Content[len(mapping.Content)-1]
+	if last.Kind == yaml.ScalarNode && (last.Style == 0 || last.Style == yaml.DoubleQuotedStyle || last.Style == yaml.SingleQuotedStyle) {
+		return last.Line
+	}
+	return key.Line
+}
+
+// isEmpty reports whether a value is null or an empty flow mapping.
+func isEmpty(node *yaml.Node) bool {
+	return node.Kind == yaml.ScalarNode && node.Tag == "!!null" ||
+		node.Kind == yaml.MappingNode && node.Style&yaml.FlowStyle != 0 && len(node.Content) == 0
+}
+
+// clearValue removes an empty value, such as {} or ~, after its key so a
+// block can be inserted below.
+func clearValue(key, value *yaml.Node) (edit, error) {
+	if value.Line != key.Line {
+		return edit{}, fmt.Errorf("value of %s on line %d is not on the same line", key.Value, key.Line)
+	}
+	width := len([]rune(value.Value))
+	if value.Kind == yaml.MappingNode {
+		width = len("{}")
+	}
+	if width == 0 {
+		return edit{line: key.Line}, nil
+	}
+	return edit{line: key.Line, start: value.Column - 1, end: value.Column - 1 + width, replace: true}, nil
+}
+
+// replaceScalar replaces a single-line scalar, keeping its quoting style.
+func replaceScalar(node *yaml.Node, value string) (edit, error) {
+	width := len([]rune(node.Value))
+	switch node.Style {
+	case yaml.DoubleQuotedStyle:
+		value = `"` + value + `"`
+		width += 2
+	case yaml.SingleQuotedStyle:
+		value = "'" + value + "'"
+		width += 2
+	case 0:
+	default:
+		return edit{}, fmt.Errorf("value on line %d is not a single-line scalar", node.Line)
+	}
+	if strings.ContainsAny(node.Value, "\"'\\\n") {
+		return edit{}, fmt.Errorf("value on line %d has escapes", node.Line)
+	}
+	return edit{line: node.Line, start: node.Column - 1, end: node.Column - 1 + width, text: value, replace: true}, nil
+}
+
+// indentStep is the indentation the document uses per level, taken from
+// its metadata block.
+func indentStep(doc *yaml.Node) int {
+	key, value := lookupKey(doc, "metadata"), lookup(doc, "metadata")
+	if key != nil && value != nil && value.Kind == yaml.MappingNode && value.Style&yaml.FlowStyle == 0 && value.Column > key.Column {
+		return value.Column - key.Column
+	}
+	return 2
+}
+
+func sameQuantity(a, b string) bool {
+	qa, errA := resource.ParseQuantity(a)
+	qb, errB := resource.ParseQuantity(b)
+	if errA != nil || errB != nil {
+		return a == b
+	}
+	return qa.Cmp(qb) == 0
+}
+
+func spaces(n int) string {
+	return strings.Repeat(" ", n)
+}
+
+// applyEdits applies edits to a file's content, bottom-up so line numbers
+// stay valid. Line endings are kept.
+func applyEdits(data []byte, edits []edit) ([]byte, error) {
+	lines := strings.SplitAfter(string(data), "\n")
+	sort.SliceStable(edits, func(i, j int) bool {
+		if edits[i].line != edits[j].line {
+			return edits[i].line > edits[j].line
+		}
+		// Replacements on a line go right to left
+		return edits[i].start > edits[j].start
+	})
+
+	for _, e := range edits {
+		if e.line < 1 || e.line > len(lines) {
+			return nil, fmt.Errorf("edit beyond the end of the file at line %d", e.line)
+		}
+		line := lines[e.line-1]
+		body := strings.TrimRight(line, "\r\n")
+		ending := line[len(body):]
+		// The last line may lack a line ending; it keeps lacking one
+		last := ending == ""
+		if last {
+			ending = "\n"
+			if strings.HasSuffix(lines[0], "\r\n") {
+				ending = "\r\n"
+			}
+		}
+
+		if e.replace {
+			runes := []rune(body)
+			if e.end > len(runes) {
+				return nil, fmt.Errorf("edit beyond the end of line %d", e.line)
+			}
+			body = string(runes[:e.start]) + e.text + string(runes[e.end:])
+			if e.text == "" {
+				// Keep a comment after a removed value, drop the blank
+				body = strings.TrimRight(string(runes[:e.start]), " ")
+				if rest := strings.TrimSpace(string(runes[e.end:])); rest != "" {
+					body += " " + rest
+				}
+			}
+		}
+
+		for _, inserted := range e.insert {
+			body += ending + inserted
+		}
+		if !last {
+			body += ending
+		}
+		lines[e.line-1] = body
+	}
+	return []byte(strings.Join(lines, "")), nil
+}
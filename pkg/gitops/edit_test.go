@@ -0,0 +1,297 @@
+package gitops
+
+import (
+	"strings"
+	"testing"
+)
+
+var target = Resources{CPULimit: "500m", CPURequest: "250m", MemoryLimit: "512Mi", MemoryRequest: "256Mi"}
+
+// editManifest applies the edits setting target on the app container of
+// the first manifest in src.
+func editManifest(t *testing.T, src string, target Resources) (string, error) {
+	t.Helper()
+	manifests := parseManifests("deploy.yaml", []byte(src))
+	if len(manifests) == 0 {
+		t.Fatal("no manifest parsed")
+	}
+	containers := containersOf(manifests[0])
+	if containers == nil {
+		t.Fatal("no containers")
+	}
+	container := findContainer(containers, "app")
+	if container == nil {
+		t.Fatal("container app not found")
+	}
+	edits, err := containerEdits(manifests[0].doc, container, target)
+	if err != nil {
+		return "", err
+	}
+	out, err := applyEdits([]byte(src), edits)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(out), nil
+}
+
+func TestContainerEdits(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{
+			name: "values replaced, comments kept",
+			src: `# Deployed by Argo CD
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: api
+spec:
+  template:
+    spec:
+      containers:
+      - name: app # main container
+        image: example/api:1.4
+        resources:
+          # sized for Black Friday
+          limits:
+            cpu: "2" # burst
+            memory: '1Gi'
+          requests:
+            cpu: 100m
+            memory: 128Mi
+      - name: proxy
+        image: envoyproxy/envoy
+`,
+			want: `# Deployed by Argo CD
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: api
+spec:
+  template:
+    spec:
+      containers:
+      - name: app # main container
+        image: example/api:1.4
+        resources:
+          # sized for Black Friday
+          limits:
+            cpu: "500m" # burst
+            memory: '512Mi'
+          requests:
+            cpu: 250m
+            memory: 256Mi
+      - name: proxy
+        image: envoyproxy/envoy
+`,
+		},
+		{
+			name: "missing resources",
+			src: `apiVersion: apps/v1
+kind: Deployment
+metadata:
+    name: api
+spec:
+    template:
+        spec:
+            containers:
+              - name: app
+                image: example/api:1.4
+`,
+			want: `apiVersion: apps/v1
+kind: Deployment
+metadata:
+    name: api
+spec:
+    template:
+        spec:
+            containers:
+              - name: app
+                resources:
+                    limits:
+                        cpu: 500m
+                        memory: 512Mi
+                    requests:
+                        cpu: 250m
+                        memory: 256Mi
+                image: example/api:1.4
+`,
+		},
+		{
+			name: "missing section and values",
+			src: `kind: Pod
+metadata:
+  name: api
+spec:
+  containers:
+  - name: app
+    resources:
+      limits:
+        memory: 1Gi
+  restartPolicy: Always
+`,
+			// Missing keys go right below their parent or after the last value
+			want: `kind: Pod
+metadata:
+  name: api
+spec:
+  containers:
+  - name: app
+    resources:
+      requests:
+        cpu: 250m
+        memory: 256Mi
+      limits:
+        memory: 512Mi
+        cpu: 500m
+  restartPolicy: Always
+`,
+		},
+		{
+			name: "empty mapping",
+			src: `kind: Deployment
+metadata:
+  name: api
+spec:
+  template:
+    spec:
+      containers:
+      - name: app
+        resources: {}  # TODO size
+        image: example/api
+`,
+			want: `kind: Deployment
+metadata:
+  name: api
+spec:
+  template:
+    spec:
+      containers:
+      - name: app
+        resources: # TODO size
+          limits:
+            cpu: 500m
+            memory: 512Mi
+          requests:
+            cpu: 250m
+            memory: 256Mi
+        image: example/api
+`,
+		},
+		{
+			name: "null values",
+			src: `kind: CronJob
+metadata:
+  name: report
+spec:
+  jobTemplate:
+    spec:
+      template:
+        spec:
+          containers:
+          - name: app
+            resources:
+              limits: ~
+              requests:
+`,
+			want: `kind: CronJob
+metadata:
+  name: report
+spec:
+  jobTemplate:
+    spec:
+      template:
+        spec:
+          containers:
+          - name: app
+            resources:
+              limits:
+                cpu: 500m
+                memory: 512Mi
+              requests:
+                cpu: 250m
+                memory: 256Mi
+`,
+		},
+		{
+			name: "no final newline",
+			src:  "kind: Pod\nmetadata:\n  name: api\nspec:\n  containers:\n  - name: app\n    resources: ~",
+			want: "kind: Pod\nmetadata:\n  name: api\nspec:\n  containers:\n  - name: app\n    resources:\n" +
+				"      limits:\n        cpu: 500m\n        memory: 512Mi\n      requests:\n        cpu: 250m\n        memory: 256Mi",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := editManifest(t, tt.src, target)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("edited manifest:\n%s\nwant:\n%s", got, tt.want)
+			}
+			// The result parses to the target
+			manifests := parseManifests("deploy.yaml", []byte(got))
+			container := findContainer(containersOf(manifests[0]), "app")
+			if current := currentResources(container); current != target {
+				t.Errorf("edited manifest has %+v, want %+v", current, target)
+			}
+		})
+	}
+}
+
+func TestContainerEditsCRLF(t *testing.T) {
+	src := strings.ReplaceAll(`kind: Deployment
+metadata:
+  name: api
+spec:
+  template:
+    spec:
+      containers:
+      - name: app
+        resources:
+          limits:
+            cpu: "1"
+      - name: proxy
+`, "\n", "\r\n")
+	got, err := editManifest(t, src, target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Count(got, "\n") != strings.Count(got, "\r\n") {
+		t.Errorf("edited manifest mixes line endings:\n%q", got)
+	}
+	want := strings.ReplaceAll(`kind: Deployment
+metadata:
+  name: api
+spec:
+  template:
+    spec:
+      containers:
+      - name: app
+        resources:
+          requests:
+            cpu: 250m
+            memory: 256Mi
+          limits:
+            cpu: "500m"
+            memory: 512Mi
+      - name: proxy
+`, "\n", "\r\n")
+	if got != want {
+		t.Errorf("edited manifest:\n%q\nwant:\n%q", got, want)
+	}
+}
+
+func TestContainerEditsUnsupported(t *testing.T) {
+	for name, resources := range map[string]string{
+		"flow resources": "resources: {limits: {cpu: 1}}",
+		"flow section":   "resources:\n      limits: {cpu: 1}",
+	} {
+		src := "kind: Pod\nmetadata:\n  name: api\nspec:\n  containers:\n  - name: app\n    " + resources + "\n"
+		if _, err := editManifest(t, src, target); err == nil || !strings.Contains(err.Error(), "flow style") {
+			t.Errorf("%s: error = %v, want flow style", name, err)
+		}
+	}
+}
@@ -0,0 +1,328 @@
+package gitops
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"pod-limit-checker/pkg/analyzer"
+	"pod-limit-checker/pkg/helm"
+)
+
+// Plan lists the manifest changes that apply the recommendations to a Git
+// checkout, so a GitOps controller rolls them out instead of reverting
+// changes made in the cluster.
+type Plan struct {
+	Repo    string   `json:"repo" yaml:"repo"`
+	Branch  string   `json:"branch,omitempty" yaml:"branch,omitempty"`
+	Commit  string   `json:"commit,omitempty" yaml:"commit,omitempty"`
+	Changes []Change `json:"changes" yaml:"changes"`
+
+	// edits by file, relative to Repo
+	edits map[string][]edit
+}
+
+// Change is the change of one container in a workload manifest. Skipped
+// explains why no manifest could be changed.
+type Change struct {
+	Namespace string    `json:"namespace" yaml:"namespace"`
+	Kind      string    `json:"kind" yaml:"kind"`
+	Name      string    `json:"name" yaml:"name"`
+	Container string    `json:"container" yaml:"container"`
+	File      string    `json:"file,omitempty" yaml:"file,omitempty"`
+	Line      int       `json:"line,omitempty" yaml:"line,omitempty"`
+	Current   Resources `json:"current" yaml:"current"`
+	Target    Resources `json:"target" yaml:"target"`
+	// SharedWith lists the other namespaces whose kustomizations include
+	// the file; the change applies to them too
+	SharedWith []string `json:"sharedWith,omitempty" yaml:"sharedWith,omitempty"`
+	Skipped    string   `json:"skipped,omitempty" yaml:"skipped,omitempty"`
+}
+
+// Resources holds CPU and memory values as written in the manifest.
+type Resources struct {
+	CPULimit      string `json:"cpuLimit,omitempty" yaml:"cpuLimit,omitempty"`
+	CPURequest    string `json:"cpuRequest,omitempty" yaml:"cpuRequest,omitempty"`
+	MemoryLimit   string `json:"memoryLimit,omitempty" yaml:"memoryLimit,omitempty"`
+	MemoryRequest string `json:"memoryRequest,omitempty" yaml:"memoryRequest,omitempty"`
+}
+
+// PlanChanges finds the manifest of every workload container with a
+// usage-based recommendation in the checkout at repo. Manifests are
+// matched by kind, name and namespace; a manifest without a namespace
+// matches through the kustomizations including it, or when it is the only
+// one of that kind and name. Helm charts are not edited: workloads of a
+// Helm release, as found by helm.FindWorkloads, are skipped, since Helm
+// renders them from the release's values.
+func PlanChanges(repo string, results []analyzer.PodAnalysis, helmWorkloads map[string]helm.Workload) (*Plan, error) {
+	manifests, err := indexRepository(repo)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %v", repo, err)
+	}
+
+	plan := &Plan{Repo: repo, Changes: []Change{}, edits: map[string][]edit{}}
+	for _, change := range targets(results) {
+		if workload, ok := helmWorkloads[change.Namespace+"/"+change.Kind+"/"+change.Name]; ok {
+			change.Skipped = fmt.Sprintf("installed by Helm release %s/%s, set its values with --helm-values",
+				workload.Release.Namespace, workload.Release.Name)
+			plan.Changes = append(plan.Changes, change)
+			continue
+		}
+		m, skipped := match(manifests, change)
+		if m == nil {
+			change.Skipped = skipped
+			plan.Changes = append(plan.Changes, change)
+			continue
+		}
+		change.Kind, change.Name, change.File = m.kind, m.name, m.file
+
+		var container *yaml.Node
+		if containers := containersOf(m); containers != nil {
+			container = findContainer(containers, change.Container)
+		}
+		if container == nil {
+			change.Skipped = "container not found in " + m.file
+			plan.Changes = append(plan.Changes, change)
+			continue
+		}
+		change.Line = container.Line
+		change.Current = currentResources(container)
+		if sameResources(change.Current, change.Target) {
+			continue
+		}
+		for _, namespace := range m.namespaces {
+			if namespace != change.Namespace {
+				change.SharedWith = append(change.SharedWith, namespace)
+			}
+		}
+
+		edits, err := containerEdits(m.doc, container, change.Target)
+		if err != nil {
+			change.Skipped = err.Error()
+		} else {
+			plan.edits[m.file] = append(plan.edits[m.file], edits...)
+		}
+		plan.Changes = append(plan.Changes, change)
+	}
+
+	sort.SliceStable(plan.Changes, func(i, j int) bool {
+		a, b := plan.Changes[i], plan.Changes[j]
+		if a.Namespace != b.Namespace {
+			return a.Namespace < b.Namespace
+		}
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		return a.Container < b.Container
+	})
+	return plan, nil
+}
+
+// targets returns a change per workload container with a recommendation.
+func targets(results []analyzer.PodAnalysis) []Change {
+	recommendations := analyzer.WorkloadRecommendations(results)
+	changes := make([]Change, 0, len(recommendations))
+	for _, r := range recommendations {
+		changes = append(changes, Change{
+			Namespace: r.Namespace,
+			Kind:      r.WorkloadKind,
+			Name:      r.WorkloadName,
+			Container: r.Container,
+			Target: Resources{
+				CPULimit:      r.CPULimit,
+				CPURequest:    r.CPURequest,
+				MemoryLimit:   r.MemoryLimit,
+				MemoryRequest: r.MemoryRequest,
+			},
+		})
+	}
+	return changes
+}
+
+// match finds the manifest of a change's workload, or says why there is
+// none. Jobs created by a CronJob are matched to the CronJob.
+func match(manifests []*manifest, change Change) (*manifest, string) {
+	candidates := matching(manifests, change.Kind, change.Name, change.Namespace)
+	if name, ok := analyzer.CronJobName(change.Name); ok && len(candidates) == 0 && change.Kind == "Job" {
+		candidates = matching(manifests, "CronJob", name, change.Namespace)
+	}
+	switch len(candidates) {
+	case 0:
+		return nil, "no manifest found"
+	case 1:
+		return candidates[0], ""
+	}
+	files := make([]string, 0, len(candidates))
+	for _, m := range candidates {
+		files = append(files, m.file)
+	}
+	return nil, "ambiguous, found in " + strings.Join(files, ", ")
+}
+
+// matching returns the manifests of a workload. Manifests whose namespace
+// is known are preferred over those that may be deployed anywhere.
+func matching(manifests []*manifest, kind, name, namespace string) []*manifest {
+	var exact, anywhere []*manifest
+	for _, m := range manifests {
+		if m.kind != kind || m.name != name {
+			continue
+		}
+		switch {
+		case m.namespace != "":
+			if m.namespace == namespace {
+				exact = append(exact, m)
+			}
+		case len(m.namespaces) > 0:
+			for _, candidate := range m.namespaces {
+				if candidate == namespace {
+					exact = append(exact, m)
+					break
+				}
+			}
+		default:
+			anywhere = append(anywhere, m)
+		}
+	}
+	if len(exact) > 0 {
+		return exact
+	}
+	return anywhere
+}
+
+func sameResources(current, target Resources) bool {
+	return sameQuantity(current.CPULimit, target.CPULimit) &&
+		sameQuantity(current.CPURequest, target.CPURequest) &&
+		sameQuantity(current.MemoryLimit, target.MemoryLimit) &&
+		sameQuantity(current.MemoryRequest, target.MemoryRequest)
+}
+
+// Apply edits the manifests and commits them on a new branch of the
+// checkout, which must be clean. Every edit is computed before the branch
+// is created, and a failure after that restores the files and the branch
+// that was checked out, so the checkout is left as it was. It uses the git
+// command line, so the user's configuration, identity and signing apply.
+func Apply(plan *Plan, branch, message string) (err error) {
+	if len(plan.edits) == 0 {
+		return fmt.Errorf("no manifest to change")
+	}
+	status, err := git(plan.Repo, "status", "--porcelain")
+	if err != nil {
+		return err
+	}
+	if status != "" {
+		return fmt.Errorf("%s has uncommitted changes", plan.Repo)
+	}
+
+	files := make([]string, 0, len(plan.edits))
+	for file := range plan.edits {
+		files = append(files, file)
+	}
+	sort.Strings(files)
+	type edited struct {
+		path           string
+		mode           os.FileMode
+		original, data []byte
+	}
+	var changes []edited
+	for _, file := range files {
+		path := filepath.Join(plan.Repo, file)
+		info, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+		original, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		data, err := applyEdits(original, plan.edits[file])
+		if err != nil {
+			return fmt.Errorf("failed to edit %s: %v", file, err)
+		}
+		changes = append(changes, edited{path: path, mode: info.Mode().Perm(), original: original, data: data})
+	}
+
+	previous, err := currentBranch(plan.Repo)
+	if err != nil {
+		return err
+	}
+	if _, err := git(plan.Repo, "checkout", "-b", branch); err != nil {
+		return err
+	}
+	written := 0
+	defer func() {
+		if err == nil {
+			return
+		}
+		for _, change := range changes[:written] {
+			os.WriteFile(change.path, change.original, change.mode)
+		}
+		if _, rollbackErr := git(plan.Repo, "checkout", "-f", previous); rollbackErr != nil {
+			err = fmt.Errorf("%v; rolling back: %v", err, rollbackErr)
+			return
+		}
+		if _, rollbackErr := git(plan.Repo, "branch", "-D", branch); rollbackErr != nil {
+			err = fmt.Errorf("%v; rolling back: %v", err, rollbackErr)
+		}
+	}()
+
+	for _, change := range changes {
+		// Counted first so a partial write is restored too
+		written++
+		if err := os.WriteFile(change.path, change.data, change.mode); err != nil {
+			return err
+		}
+	}
+	if _, err := git(plan.Repo, append([]string{"add", "--"}, files...)...); err != nil {
+		return err
+	}
+	if _, err := git(plan.Repo, "commit", "-m", message); err != nil {
+		return err
+	}
+	commit, err := git(plan.Repo, "rev-parse", "HEAD")
+	if err != nil {
+		return err
+	}
+	plan.Branch, plan.Commit = branch, commit
+	return nil
+}
+
+// currentBranch returns the checked out branch, or the commit of a
+// detached HEAD.
+func currentBranch(repo string) (string, error) {
+	if branch, err := git(repo, "symbolic-ref", "-q", "--short", "HEAD"); err == nil {
+		return branch, nil
+	}
+	return git(repo, "rev-parse", "HEAD")
+}
+
+// CommitMessage describes the plan's changes.
+func CommitMessage(plan *Plan) string {
+	var lines []string
+	for _, change := range plan.Changes {
+		if change.Skipped != "" {
+			continue
+		}
+		lines = append(lines, fmt.Sprintf("- %s/%s %s: cpu %s/%s, memory %s/%s (limit/request)",
+			change.Namespace, change.Name, change.Container,
+			change.Target.CPULimit, change.Target.CPURequest, change.Target.MemoryLimit, change.Target.MemoryRequest))
+	}
+	return fmt.Sprintf("Right-size resources of %d container(s)\n\nApplies the usage-based recommendations of pod-limit-checker:\n\n%s\n",
+		len(lines), strings.Join(lines, "\n"))
+}
+
+func git(repo string, args ...string) (string, error) {
+	cmd := exec.Command("git", append([]string{"-C", repo}, args...)...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("git %s: %v: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimSpace(string(out)), nil
+}
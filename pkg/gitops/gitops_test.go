@@ -0,0 +1,256 @@
+package gitops
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"pod-limit-checker/pkg/analyzer"
+	"pod-limit-checker/pkg/helm"
+)
+
+const apiManifest = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: api
+  namespace: web
+spec:
+  template:
+    spec:
+      containers:
+      - name: app
+        image: example/api:1.4 # pinned
+        resources:
+          limits:
+            cpu: "2"
+`
+
+const workerManifest = `apiVersion: batch/v1
+kind: CronJob
+metadata:
+  name: report
+  namespace: batch
+spec:
+  jobTemplate:
+    spec:
+      template:
+        spec:
+          containers:
+          - name: app
+            resources: {}
+`
+
+// newRepo creates a Git checkout with files committed on main.
+func newRepo(t *testing.T, files map[string]string) string {
+	t.Helper()
+	repo := t.TempDir()
+	for file, content := range files {
+		path := filepath.Join(repo, file)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, args := range [][]string{
+		{"init", "-q", "-b", "main"},
+		{"config", "user.name", "Test"},
+		{"config", "user.email", "test@example.com"},
+		{"config", "commit.gpgsign", "false"},
+		{"add", "."},
+		{"commit", "-q", "-m", "Initial manifests"},
+	} {
+		if _, err := git(repo, args...); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return repo
+}
+
+func recommendation(namespace, kind, name string) analyzer.PodAnalysis {
+	return analyzer.PodAnalysis{
+		Namespace:                namespace,
+		PodName:                  name + "-0",
+		ContainerName:            "app",
+		WorkloadKind:             kind,
+		WorkloadName:             name,
+		RecommendedCPULimit:      "500m",
+		RecommendedCPURequest:    "250m",
+		RecommendedMemoryLimit:   "512Mi",
+		RecommendedMemoryRequest: "256Mi",
+	}
+}
+
+func readFile(t *testing.T, repo, file string) string {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join(repo, file))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestPlanChanges(t *testing.T) {
+	repo := newRepo(t, map[string]string{
+		"apps/api.yaml":    apiManifest,
+		"jobs/report.yaml": workerManifest,
+		// Not YAML until rendered
+		"charts/ingress/templates/deployment.yaml": "kind: Deployment\nmetadata:\n  name: {{ .Release.Name }}\n",
+	})
+	results := []analyzer.PodAnalysis{
+		recommendation("web", "Deployment", "api"),
+		recommendation("batch", "Job", "report-28790640"),
+		recommendation("ingress", "Deployment", "ingress-nginx-controller"),
+		recommendation("web", "Deployment", "missing"),
+	}
+	helmWorkloads := map[string]helm.Workload{
+		"ingress/Deployment/ingress-nginx-controller": {
+			Kind: "Deployment", Namespace: "ingress", Name: "ingress-nginx-controller",
+			Release: helm.Release{Name: "ingress-nginx", Namespace: "ingress"},
+		},
+	}
+
+	plan, err := PlanChanges(repo, results, helmWorkloads)
+	if err != nil {
+		t.Fatal(err)
+	}
+	byName := map[string]Change{}
+	for _, change := range plan.Changes {
+		byName[change.Name] = change
+	}
+	if len(byName) != 4 {
+		t.Fatalf("changes = %+v, want 4", plan.Changes)
+	}
+	if api := byName["api"]; api.File != filepath.Join("apps", "api.yaml") || api.Skipped != "" || api.Current.CPULimit != "2" {
+		t.Errorf("api = %+v", api)
+	}
+	// Jobs of a CronJob are matched to it
+	if report := byName["report"]; report.Kind != "CronJob" || report.Skipped != "" {
+		t.Errorf("report = %+v, want the CronJob", report)
+	}
+	if ingress := byName["ingress-nginx-controller"]; !strings.Contains(ingress.Skipped, "Helm release ingress/ingress-nginx") {
+		t.Errorf("Helm workload = %+v, want skipped", ingress)
+	}
+	if missing := byName["missing"]; missing.Skipped != "no manifest found" {
+		t.Errorf("missing = %+v", missing)
+	}
+	if len(plan.edits) != 2 {
+		t.Errorf("edits in %d files, want 2", len(plan.edits))
+	}
+}
+
+func TestApply(t *testing.T) {
+	repo := newRepo(t, map[string]string{"apps/api.yaml": apiManifest, "jobs/report.yaml": workerManifest})
+	plan, err := PlanChanges(repo, []analyzer.PodAnalysis{
+		recommendation("web", "Deployment", "api"),
+		recommendation("batch", "CronJob", "report"),
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := Apply(plan, "rightsize", CommitMessage(plan)); err != nil {
+		t.Fatal(err)
+	}
+	if branch, _ := currentBranch(repo); branch != "rightsize" || plan.Branch != "rightsize" || plan.Commit == "" {
+		t.Errorf("on branch %s, plan %s@%s, want the commit on rightsize", branch, plan.Branch, plan.Commit)
+	}
+	if status, _ := git(repo, "status", "--porcelain"); status != "" {
+		t.Errorf("uncommitted changes left:\n%s", status)
+	}
+	if message, _ := git(repo, "log", "-1", "--format=%s"); message != "Right-size resources of 2 container(s)" {
+		t.Errorf("commit message = %q", message)
+	}
+	api := readFile(t, repo, "apps/api.yaml")
+	if !strings.Contains(api, `cpu: "500m"`) || !strings.Contains(api, "# pinned") {
+		t.Errorf("apps/api.yaml:\n%s", api)
+	}
+}
+
+// assertUnchanged checks that a failed Apply left the checkout on main
+// with its files and without the branch.
+func assertUnchanged(t *testing.T, repo string, files map[string]string) {
+	t.Helper()
+	if branch, _ := currentBranch(repo); branch != "main" {
+		t.Errorf("on branch %s, want main", branch)
+	}
+	if branches, _ := git(repo, "branch", "--list", "rightsize"); branches != "" {
+		t.Errorf("branch left behind: %s", branches)
+	}
+	if status, _ := git(repo, "status", "--porcelain"); status != "" {
+		t.Errorf("changes left:\n%s", status)
+	}
+	for file, content := range files {
+		if got := readFile(t, repo, file); got != content {
+			t.Errorf("%s changed:\n%s", file, got)
+		}
+	}
+}
+
+func TestApplyRollsBackFailedCommit(t *testing.T) {
+	files := map[string]string{"apps/api.yaml": apiManifest, "jobs/report.yaml": workerManifest}
+	repo := newRepo(t, files)
+	hook := filepath.Join(repo, ".git", "hooks", "pre-commit")
+	if err := os.WriteFile(hook, []byte("#!/bin/sh\necho rejected by policy >&2\nexit 1\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	plan, err := PlanChanges(repo, []analyzer.PodAnalysis{
+		recommendation("web", "Deployment", "api"),
+		recommendation("batch", "CronJob", "report"),
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = Apply(plan, "rightsize", CommitMessage(plan))
+	if err == nil || !strings.Contains(err.Error(), "rejected by policy") {
+		t.Fatalf("error = %v, want the hook's", err)
+	}
+	assertUnchanged(t, repo, files)
+	if plan.Branch != "" || plan.Commit != "" {
+		t.Errorf("plan records %s@%s for a failed commit", plan.Branch, plan.Commit)
+	}
+}
+
+// TestApplyFailedEdit checks that no branch is created when an edit does
+// not apply, here because the file shrank since the plan was made.
+func TestApplyFailedEdit(t *testing.T) {
+	files := map[string]string{"apps/api.yaml": apiManifest, "jobs/report.yaml": workerManifest}
+	repo := newRepo(t, files)
+	plan, err := PlanChanges(repo, []analyzer.PodAnalysis{
+		recommendation("web", "Deployment", "api"),
+		recommendation("batch", "CronJob", "report"),
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files["jobs/report.yaml"] = "apiVersion: batch/v1\n"
+	if err := os.WriteFile(filepath.Join(repo, "jobs/report.yaml"), []byte(files["jobs/report.yaml"]), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := git(repo, "commit", "-q", "-am", "Remove the report job"); err != nil {
+		t.Fatal(err)
+	}
+
+	err = Apply(plan, "rightsize", CommitMessage(plan))
+	if err == nil || !strings.Contains(err.Error(), "failed to edit jobs/report.yaml") {
+		t.Fatalf("error = %v, want the failed edit", err)
+	}
+	assertUnchanged(t, repo, files)
+}
+
+func TestApplyDirtyCheckout(t *testing.T) {
+	repo := newRepo(t, map[string]string{"apps/api.yaml": apiManifest})
+	plan, err := PlanChanges(repo, []analyzer.PodAnalysis{recommendation("web", "Deployment", "api")}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(repo, "notes.txt"), []byte("wip"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := Apply(plan, "rightsize", "message"); err == nil || !strings.Contains(err.Error(), "uncommitted changes") {
+		t.Errorf("error = %v, want uncommitted changes", err)
+	}
+}
@@ -0,0 +1,212 @@
+package gitops
+
+import (
+	"bytes"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// manifest is one Kubernetes object found in the repository.
+type manifest struct {
+	// file is relative to the repository root
+	file      string
+	kind      string
+	name      string
+	namespace string
+	// namespaces are set by the kustomizations including the file when the
+	// object itself has none
+	namespaces []string
+	doc        *yaml.Node
+}
+
+// kustomization is the part of a kustomization.yaml that decides which
+// namespace its resources end up in.
+type kustomization struct {
+	Namespace string   `yaml:"namespace"`
+	Resources []string `yaml:"resources"`
+	// Bases is the deprecated predecessor of resources
+	Bases []string `yaml:"bases"`
+}
+
+var kustomizationFiles = []string{"kustomization.yaml", "kustomization.yml", "Kustomization"}
+
+// indexRepository parses every YAML file under root. Files that are not
+// valid YAML, such as Helm templates, are skipped.
+func indexRepository(root string) ([]*manifest, error) {
+	var manifests []*manifest
+	kustomizations := map[string]kustomization{}
+
+	err := filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			if path != root && strings.HasPrefix(entry.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !isKustomization(entry.Name()) && !strings.HasSuffix(entry.Name(), ".yaml") && !strings.HasSuffix(entry.Name(), ".yml") {
+			return nil
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+
+		if isKustomization(entry.Name()) {
+			var k kustomization
+			if yaml.Unmarshal(data, &k) == nil {
+				kustomizations[filepath.Dir(rel)] = k
+			}
+			return nil
+		}
+		manifests = append(manifests, parseManifests(rel, data)...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	namespaces := kustomizeNamespaces(root, kustomizations)
+	for _, m := range manifests {
+		if m.namespace == "" {
+			m.namespaces = namespaces[m.file]
+		}
+	}
+	return manifests, nil
+}
+
+func isKustomization(name string) bool {
+	for _, file := range kustomizationFiles {
+		if name == file {
+			return true
+		}
+	}
+	return false
+}
+
+// parseManifests returns the objects with a kind and name in a YAML file.
+func parseManifests(file string, data []byte) []*manifest {
+	var manifests []*manifest
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	for {
+		doc := &yaml.Node{}
+		if err := decoder.Decode(doc); err != nil {
+			// io.EOF, or a document that is not YAML
+			if err != io.EOF {
+				return nil
+			}
+			return manifests
+		}
+		if len(doc.Content) == 0 {
+			continue
+		}
+		root := doc.Content[0]
+		kind := scalar(lookup(root, "kind"))
+		metadata := lookup(root, "metadata")
+		name := scalar(lookup(metadata, "name"))
+		if kind == "" || name == "" {
+			continue
+		}
+		manifests = append(manifests, &manifest{
+			file:      file,
+			kind:      kind,
+			name:      name,
+			namespace: scalar(lookup(metadata, "namespace")),
+			doc:       root,
+		})
+	}
+}
+
+// kustomizeNamespaces maps the files included by kustomizations that set a
+// namespace to those namespaces. The namespace of an overlay applies to
+// everything it includes, through any number of bases.
+func kustomizeNamespaces(root string, kustomizations map[string]kustomization) map[string][]string {
+	namespaces := map[string]map[string]bool{}
+
+	var include func(dir, namespace string, visited map[string]bool)
+	include = func(dir, namespace string, visited map[string]bool) {
+		if visited[dir] {
+			return
+		}
+		visited[dir] = true
+		k := kustomizations[dir]
+		for _, resource := range append(append([]string{}, k.Resources...), k.Bases...) {
+			if strings.Contains(resource, "://") || strings.HasPrefix(resource, "github.com/") {
+				// Remote bases are not in this repository
+				continue
+			}
+			path := filepath.Clean(filepath.Join(dir, resource))
+			if _, ok := kustomizations[path]; ok {
+				include(path, namespace, visited)
+				continue
+			}
+			if info, err := os.Stat(filepath.Join(root, path)); err != nil || info.IsDir() {
+				continue
+			}
+			if namespaces[path] == nil {
+				namespaces[path] = map[string]bool{}
+			}
+			namespaces[path][namespace] = true
+		}
+	}
+	for dir, k := range kustomizations {
+		if k.Namespace != "" {
+			include(dir, k.Namespace, map[string]bool{})
+		}
+	}
+
+	result := map[string][]string{}
+	for file, set := range namespaces {
+		for namespace := range set {
+			result[file] = append(result[file], namespace)
+		}
+		sort.Strings(result[file])
+	}
+	return result
+}
+
+// lookup returns the value of key in a mapping node, or nil.
+func lookup(node *yaml.Node, key string) *yaml.Node {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// lookupKey returns the key node of key in a mapping node, or nil.
+func lookupKey(node *yaml.Node, key string) *yaml.Node {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i]
+		}
+	}
+	return nil
+}
+
+func scalar(node *yaml.Node) string {
+	if node == nil || node.Kind != yaml.ScalarNode {
+		return ""
+	}
+	return node.Value
+}
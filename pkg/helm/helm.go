@@ -0,0 +1,345 @@
+package helm
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"pod-limit-checker/pkg/analyzer"
+)
+
+// Labels and annotations Helm and charts put on the objects of a release.
+const (
+	ReleaseNameAnnotation      = "meta.helm.sh/release-name"
+	ReleaseNamespaceAnnotation = "meta.helm.sh/release-namespace"
+	ManagedByLabel             = "app.kubernetes.io/managed-by"
+	InstanceLabel              = "app.kubernetes.io/instance"
+	ComponentLabel             = "app.kubernetes.io/component"
+	ChartLabel                 = "helm.sh/chart"
+)
+
+// Release identifies the Helm release a workload belongs to.
+type Release struct {
+	Name      string
+	Namespace string
+	// Chart is the chart name without its version, when labelled
+	Chart string
+}
+
+// Workload is a Helm-managed workload with the values key its chart
+// likely uses for it: "" for top-level values, else e.g. "controller".
+type Workload struct {
+	Kind      string
+	Namespace string
+	Name      string
+	Release   Release
+	Component string
+}
+
+// ValuesFile is a values override for one release.
+type ValuesFile struct {
+	Release Release
+	// Name is the file name, <namespace>.<release>.values.yaml
+	Name    string
+	Content []byte
+}
+
+// chartVersion is the version suffix of a helm.sh/chart label.
+var chartVersion = regexp.MustCompile(`-v?[0-9]+\.[0-9]+\.[0-9]+.*$`)
+
+type workloadMeta struct {
+	kind string
+	meta metav1.ObjectMeta
+}
+
+// FindWorkloads lists the Deployments, StatefulSets, DaemonSets and Jobs
+// installed by Helm, keyed by namespace/Kind/name.
+func FindWorkloads(ctx context.Context, client kubernetes.Interface, namespace string) (map[string]Workload, error) {
+	var objects []workloadMeta
+	add := func(kind string, meta metav1.ObjectMeta) {
+		objects = append(objects, workloadMeta{kind: kind, meta: meta})
+	}
+
+	opts := metav1.ListOptions{}
+	deployments, err := client.AppsV1().Deployments(namespace).List(ctx, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list Deployments: %v", err)
+	}
+	for _, d := range deployments.Items {
+		add("Deployment", d.ObjectMeta)
+	}
+	statefulSets, err := client.AppsV1().StatefulSets(namespace).List(ctx, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list StatefulSets: %v", err)
+	}
+	for _, s := range statefulSets.Items {
+		add("StatefulSet", s.ObjectMeta)
+	}
+	daemonSets, err := client.AppsV1().DaemonSets(namespace).List(ctx, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list DaemonSets: %v", err)
+	}
+	for _, d := range daemonSets.Items {
+		add("DaemonSet", d.ObjectMeta)
+	}
+	jobs, err := client.BatchV1().Jobs(namespace).List(ctx, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list Jobs: %v", err)
+	}
+	for _, j := range jobs.Items {
+		add("Job", j.ObjectMeta)
+	}
+
+	workloads := map[string]Workload{}
+	for _, object := range objects {
+		release, ok := releaseOf(object.meta)
+		if !ok {
+			continue
+		}
+		workloads[object.meta.Namespace+"/"+object.kind+"/"+object.meta.Name] = Workload{
+			Kind:      object.kind,
+			Namespace: object.meta.Namespace,
+			Name:      object.meta.Name,
+			Release:   release,
+			Component: componentOf(object.meta, release),
+		}
+	}
+	return workloads, nil
+}
+
+// releaseOf returns the release of a Helm-managed object. Helm 3.2+
+// annotates every object it installs; older releases are recognized by the
+// managed-by label, with the release name in the instance label.
+func releaseOf(meta metav1.ObjectMeta) (Release, bool) {
+	release := Release{
+		Name:      meta.Annotations[ReleaseNameAnnotation],
+		Namespace: meta.Annotations[ReleaseNamespaceAnnotation],
+		Chart:     chartVersion.ReplaceAllString(meta.Labels[ChartLabel], ""),
+	}
+	if release.Name == "" && meta.Labels[ManagedByLabel] == "Helm" {
+		release.Name = meta.Labels[InstanceLabel]
+	}
+	if release.Name == "" {
+		return Release{}, false
+	}
+	if release.Namespace == "" {
+		release.Namespace = meta.Namespace
+	}
+	return release, true
+}
+
+// componentOf guesses the values key of a workload. Charts with several
+// workloads name them <release>-<chart>-<component> or <release>-<component>
+// and usually key their values by component, as in controller.resources;
+// the component label is used when set.
+func componentOf(meta metav1.ObjectMeta, release Release) string {
+	component := meta.Labels[ComponentLabel]
+	if component == "" {
+		name := meta.Name
+		for _, prefix := range []string{release.Name + "-" + release.Chart, release.Name, release.Chart} {
+			if prefix != "" && (name == prefix || strings.HasPrefix(name, prefix+"-")) {
+				name = strings.TrimPrefix(strings.TrimPrefix(name, prefix), "-")
+				break
+			}
+		}
+		if name == meta.Name {
+			// Not named after the release, e.g. with fullnameOverride
+			return ""
+		}
+		component = name
+	}
+	if component == release.Chart || component == release.Name {
+		return ""
+	}
+	return camelCase(component)
+}
+
+// camelCase turns a kebab-case name into the lowerCamelCase charts use for
+// values keys, e.g. query-frontend into queryFrontend.
+func camelCase(name string) string {
+	parts := strings.Split(name, "-")
+	for i := 1; i < len(parts); i++ {
+		if parts[i] != "" {
+			parts[i] = strings.ToUpper(parts[i][:1]) + parts[i][1:]
+		}
+	}
+	return strings.Join(parts, "")
+}
+
+// resources is a values resources block.
+type resources struct {
+	Limits   resourceValues `yaml:"limits"`
+	Requests resourceValues `yaml:"requests"`
+}
+
+type resourceValues struct {
+	CPU    string `yaml:"cpu"`
+	Memory string `yaml:"memory"`
+}
+
+// container is the recommendation for a container of a workload.
+type container struct {
+	workload  Workload
+	name      string
+	resources resources
+}
+
+// GenerateValues builds a values override per release from the usage-based
+// recommendations of containers in Helm-managed workloads. The main
+// container of a workload, the only one or the one named after the chart
+// or component, goes to <component>.resources or top-level resources;
+// other containers have no common values path and are listed in comments.
+func GenerateValues(results []analyzer.PodAnalysis, workloads map[string]Workload) ([]ValuesFile, error) {
+	// Sidecars are told apart by the number of containers, with or
+	// without a recommendation
+	counts := map[string]map[string]bool{}
+	for _, result := range results {
+		key := result.Namespace + "/" + result.WorkloadKind + "/" + result.WorkloadName
+		if counts[key] == nil {
+			counts[key] = map[string]bool{}
+		}
+		counts[key][result.ContainerName] = true
+	}
+
+	byRelease := map[Release][]*container{}
+	for _, r := range analyzer.WorkloadRecommendations(results) {
+		workload, ok := workloads[r.Namespace+"/"+r.WorkloadKind+"/"+r.WorkloadName]
+		if !ok {
+			continue
+		}
+		byRelease[workload.Release] = append(byRelease[workload.Release], &container{
+			workload: workload,
+			name:     r.Container,
+			resources: resources{
+				Limits:   resourceValues{CPU: r.CPULimit, Memory: r.MemoryLimit},
+				Requests: resourceValues{CPU: r.CPURequest, Memory: r.MemoryRequest},
+			},
+		})
+	}
+
+	releases := make([]Release, 0, len(byRelease))
+	for release := range byRelease {
+		releases = append(releases, release)
+	}
+	sort.Slice(releases, func(i, j int) bool {
+		if releases[i].Namespace != releases[j].Namespace {
+			return releases[i].Namespace < releases[j].Namespace
+		}
+		return releases[i].Name < releases[j].Name
+	})
+
+	files := make([]ValuesFile, 0, len(releases))
+	for _, release := range releases {
+		containers := byRelease[release]
+		// Workloads named after the release claim values paths first, so
+		// one renamed with fullnameOverride, whose path is a guess, does
+		// not take the path of the chart's main workload
+		sort.Slice(containers, func(i, j int) bool {
+			a, b := containers[i], containers[j]
+			if na, nb := namedAfter(a.workload, release), namedAfter(b.workload, release); na != nb {
+				return na
+			}
+			if a.workload.Name != b.workload.Name {
+				return a.workload.Name < b.workload.Name
+			}
+			return a.name < b.name
+		})
+
+		values := yaml.MapSlice{}
+		paths := map[string]string{}
+		var mapped, unmapped []string
+		for _, c := range containers {
+			workloadKey := c.workload.Namespace + "/" + c.workload.Kind + "/" + c.workload.Name
+			description := fmt.Sprintf("%s %s container %s", c.workload.Kind, c.workload.Name, c.name)
+			if !isMain(c, release, len(counts[workloadKey])) {
+				unmapped = append(unmapped, fmt.Sprintf("%s: %s", description, summary(c.resources)))
+				continue
+			}
+			path := "resources"
+			if c.workload.Component != "" {
+				path = c.workload.Component + ".resources"
+			}
+			if other, ok := paths[path]; ok {
+				unmapped = append(unmapped, fmt.Sprintf("%s (%s is taken by %s): %s", description, path, other, summary(c.resources)))
+				continue
+			}
+			paths[path] = description
+			mapped = append(mapped, fmt.Sprintf("%s: %s", path, description))
+			if c.workload.Component == "" {
+				values = append(values, yaml.MapItem{Key: "resources", Value: c.resources})
+			} else {
+				values = append(values, yaml.MapItem{Key: c.workload.Component, Value: yaml.MapSlice{{Key: "resources", Value: c.resources}}})
+			}
+		}
+
+		content, err := render(release, values, mapped, unmapped)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, ValuesFile{
+			Release: release,
+			Name:    release.Namespace + "." + release.Name + ".values.yaml",
+			Content: content,
+		})
+	}
+	return files, nil
+}
+
+// namedAfter reports whether a workload's name starts with its release or
+// chart name, as chart templates name workloads by default.
+func namedAfter(workload Workload, release Release) bool {
+	for _, prefix := range []string{release.Name, release.Chart} {
+		if prefix != "" && (workload.Name == prefix || strings.HasPrefix(workload.Name, prefix+"-")) {
+			return true
+		}
+	}
+	return false
+}
+
+// isMain reports whether a container is the one a chart's resources value
+// applies to.
+func isMain(c *container, release Release, containers int) bool {
+	return containers == 1 ||
+		c.name == release.Chart ||
+		c.workload.Component != "" && camelCase(c.name) == c.workload.Component
+}
+
+func render(release Release, values yaml.MapSlice, mapped, unmapped []string) ([]byte, error) {
+	var b strings.Builder
+	chart := ""
+	if release.Chart != "" {
+		chart = " (chart " + release.Chart + ")"
+	}
+	fmt.Fprintf(&b, "# Recommended resources for Helm release %s%s in namespace %s, generated by pod-limit-checker.\n", release.Name, chart, release.Namespace)
+	fmt.Fprintf(&b, "# Values paths follow common chart conventions; check them against the chart's values.yaml.\n")
+	fmt.Fprintf(&b, "# Apply with: helm upgrade %s <chart> -n %s --reuse-values -f %s.%s.values.yaml\n", release.Name, release.Namespace, release.Namespace, release.Name)
+	for _, line := range mapped {
+		fmt.Fprintf(&b, "#   %s\n", line)
+	}
+	if len(unmapped) > 0 {
+		fmt.Fprintf(&b, "#\n# No common values path, set these where the chart allows (cpu and memory as limit/request):\n")
+		for _, line := range unmapped {
+			fmt.Fprintf(&b, "#   %s\n", line)
+		}
+	}
+	if len(values) == 0 {
+		b.WriteString("{}\n")
+		return []byte(b.String()), nil
+	}
+	data, err := yaml.Marshal(values)
+	if err != nil {
+		return nil, err
+	}
+	b.Write(data)
+	return []byte(b.String()), nil
+}
+
+func summary(r resources) string {
+	return fmt.Sprintf("cpu %s/%s, memory %s/%s", r.Limits.CPU, r.Requests.CPU, r.Limits.Memory, r.Requests.Memory)
+}
@@ -0,0 +1,173 @@
+package helm
+
+import (
+	"context"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v2"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/kubernetes/scheme"
+
+	"pod-limit-checker/pkg/analyzer"
+)
+
+// releaseObjects decodes the workloads in testdata/release.yaml.
+func releaseObjects(t *testing.T) []runtime.Object {
+	t.Helper()
+	data, err := os.ReadFile("testdata/release.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var objects []runtime.Object
+	for _, doc := range strings.Split(string(data), "\n---\n") {
+		object, _, err := scheme.Codecs.UniversalDeserializer().Decode([]byte(doc), nil, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		objects = append(objects, object)
+	}
+	return objects
+}
+
+func recommendation(namespace, kind, name, container, cpu string) analyzer.PodAnalysis {
+	return analyzer.PodAnalysis{
+		Namespace:                namespace,
+		PodName:                  name + "-0",
+		ContainerName:            container,
+		WorkloadKind:             kind,
+		WorkloadName:             name,
+		RecommendedCPULimit:      cpu,
+		RecommendedCPURequest:    "100m",
+		RecommendedMemoryLimit:   "512Mi",
+		RecommendedMemoryRequest: "256Mi",
+	}
+}
+
+// resourcesPaths returns the dotted paths of the resources keys in values.
+func resourcesPaths(values map[interface{}]interface{}, prefix string) []string {
+	var paths []string
+	for key, value := range values {
+		path := prefix + key.(string)
+		if key == "resources" {
+			paths = append(paths, path)
+			continue
+		}
+		if nested, ok := value.(map[interface{}]interface{}); ok {
+			paths = append(paths, resourcesPaths(nested, path+".")...)
+		}
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+func TestFindWorkloads(t *testing.T) {
+	workloads, err := FindWorkloads(context.Background(), fake.NewSimpleClientset(releaseObjects(t)...), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	shop := Release{Name: "shop", Namespace: "shop", Chart: "shop"}
+	want := map[string]Workload{
+		"shop/Deployment/shop":                 {Kind: "Deployment", Namespace: "shop", Name: "shop", Release: shop},
+		"shop/Deployment/shop-worker":          {Kind: "Deployment", Namespace: "shop", Name: "shop-worker", Release: shop, Component: "worker"},
+		"shop/StatefulSet/shop-query-frontend": {Kind: "StatefulSet", Namespace: "shop", Name: "shop-query-frontend", Release: shop, Component: "queryFrontend"},
+		"shop/DaemonSet/node-agent":            {Kind: "DaemonSet", Namespace: "shop", Name: "node-agent", Release: shop},
+		// Helm 2 labels, without the release annotations
+		"data/StatefulSet/cache": {Kind: "StatefulSet", Namespace: "data", Name: "cache", Release: Release{Name: "cache", Namespace: "data", Chart: "redis"}},
+	}
+	if !reflect.DeepEqual(workloads, want) {
+		t.Errorf("workloads = %+v\nwant %+v", workloads, want)
+	}
+}
+
+func TestGenerateValues(t *testing.T) {
+	workloads, err := FindWorkloads(context.Background(), fake.NewSimpleClientset(releaseObjects(t)...), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	results := []analyzer.PodAnalysis{
+		recommendation("shop", "Deployment", "shop", "shop", "500m"),
+		// Replicas merge; the largest value wins
+		recommendation("shop", "Deployment", "shop", "shop", "700m"),
+		recommendation("shop", "Deployment", "shop", "metrics-exporter", "50m"),
+		recommendation("shop", "Deployment", "shop-worker", "worker", "1"),
+		recommendation("shop", "StatefulSet", "shop-query-frontend", "query-frontend", "250m"),
+		recommendation("shop", "DaemonSet", "node-agent", "agent", "100m"),
+		recommendation("shop", "Deployment", "legacy", "app", "200m"),
+		recommendation("data", "StatefulSet", "cache", "redis", "300m"),
+	}
+	files, err := GenerateValues(results, workloads)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 2 || files[0].Name != "data.cache.values.yaml" || files[1].Name != "shop.shop.values.yaml" {
+		t.Fatalf("files = %+v, want one per Helm release", files)
+	}
+
+	chartData, err := os.ReadFile("testdata/shop/values.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var chartValues map[interface{}]interface{}
+	if err := yaml.Unmarshal(chartData, &chartValues); err != nil {
+		t.Fatal(err)
+	}
+	var values map[interface{}]interface{}
+	if err := yaml.Unmarshal(files[1].Content, &values); err != nil {
+		t.Fatal(err)
+	}
+
+	// Every path written is one the chart has
+	paths := resourcesPaths(values, "")
+	if want := []string{"queryFrontend.resources", "resources", "worker.resources"}; !reflect.DeepEqual(paths, want) {
+		t.Errorf("values paths = %v, want %v", paths, want)
+	}
+	chartPaths := map[string]bool{}
+	for _, path := range resourcesPaths(chartValues, "") {
+		chartPaths[path] = true
+	}
+	for _, path := range paths {
+		if !chartPaths[path] {
+			t.Errorf("%s is not in the chart's values.yaml", path)
+		}
+	}
+
+	for path, cpu := range map[string]string{"resources": "700m", "worker.resources": "1", "queryFrontend.resources": "250m"} {
+		block := values
+		for _, key := range strings.Split(path, ".") {
+			block, _ = block[key].(map[interface{}]interface{})
+		}
+		limits, _ := block["limits"].(map[interface{}]interface{})
+		requests, _ := block["requests"].(map[interface{}]interface{})
+		if limits["cpu"] != cpu || limits["memory"] != "512Mi" || requests["cpu"] != "100m" || requests["memory"] != "256Mi" {
+			t.Errorf("%s = %v, want cpu limit %s", path, block, cpu)
+		}
+	}
+
+	// Containers without a values path are left to comments
+	content := string(files[1].Content)
+	for _, comment := range []string{
+		"#   Deployment shop container metrics-exporter: cpu 50m/100m, memory 512Mi/256Mi",
+		"#   DaemonSet node-agent container agent (resources is taken by Deployment shop container shop): cpu 100m/100m",
+		"# Apply with: helm upgrade shop <chart> -n shop --reuse-values -f shop.shop.values.yaml",
+	} {
+		if !strings.Contains(content, comment) {
+			t.Errorf("shop values lack %q:\n%s", comment, content)
+		}
+	}
+	if strings.Contains(content, "legacy") {
+		t.Errorf("workload outside Helm in the values:\n%s", content)
+	}
+
+	var cacheValues map[interface{}]interface{}
+	if err := yaml.Unmarshal(files[0].Content, &cacheValues); err != nil {
+		t.Fatal(err)
+	}
+	if paths := resourcesPaths(cacheValues, ""); !reflect.DeepEqual(paths, []string{"resources"}) {
+		t.Errorf("cache values paths = %v, want top-level resources", paths)
+	}
+}
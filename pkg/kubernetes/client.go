@@ -5,15 +5,24 @@ import (
 	"os"
 	"path/filepath"
 
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
 	metricsv "k8s.io/metrics/pkg/client/clientset/versioned"
 )
 
+// Client holds the API clients the checker uses. They are interfaces so
+// the fake clientsets of client-go and k8s.io/metrics can stand in for a
+// cluster.
 type Client struct {
-	Clientset     *kubernetes.Clientset
-	MetricsClient *metricsv.Clientset
+	Clientset kubernetes.Interface
+	// MetricsClient is nil when the metrics API client could not be created
+	MetricsClient metricsv.Interface
+	// DynamicClient reads custom resources such as VerticalPodAutoscalers
+	DynamicClient dynamic.Interface
+	// Host is the API server URL, used to identify the cluster in reports
+	Host string
 }
 
 func NewClient(kubeconfigPath string, quiet bool) (*Client, error) {
@@ -27,19 +36,28 @@ func NewClient(kubeconfigPath string, quiet bool) (*Client, error) {
 		return nil, fmt.Errorf("failed to create Kubernetes client: %v", err)
 	}
 
+	dynamicClient, err := dynamic.NewForConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create dynamic client: %v", err)
+	}
+
 	metricsClient, err := metricsv.NewForConfig(config)
 	if err != nil {
 		if !quiet {
 			fmt.Fprintln(os.Stderr, "⚠️  Metrics server not available, continuing without metrics")
 		}
 		return &Client{
-			Clientset: clientset,
+			Clientset:     clientset,
+			DynamicClient: dynamicClient,
+			Host:          config.Host,
 		}, nil
 	}
 
 	return &Client{
 		Clientset:     clientset,
 		MetricsClient: metricsClient,
+		DynamicClient: dynamicClient,
+		Host:          config.Host,
 	}, nil
 }
 
@@ -0,0 +1,168 @@
+package kustomize
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+
+	"pod-limit-checker/pkg/analyzer"
+)
+
+// File is a file of the generated overlay, relative to its directory.
+type File struct {
+	Name    string
+	Content []byte
+}
+
+// podSpecPaths are the workload kinds a patch can target, with their
+// apiVersion and the path to their pod spec.
+var podSpecPaths = map[string]struct {
+	apiVersion string
+	path       []string
+}{
+	"Deployment":  {"apps/v1", []string{"spec", "template", "spec"}},
+	"StatefulSet": {"apps/v1", []string{"spec", "template", "spec"}},
+	"DaemonSet":   {"apps/v1", []string{"spec", "template", "spec"}},
+	"ReplicaSet":  {"apps/v1", []string{"spec", "template", "spec"}},
+	"Job":         {"batch/v1", []string{"spec", "template", "spec"}},
+	"CronJob":     {"batch/v1", []string{"spec", "jobTemplate", "spec", "template", "spec"}},
+	"Pod":         {"v1", []string{"spec"}},
+}
+
+type kustomization struct {
+	APIVersion string   `yaml:"apiVersion"`
+	Kind       string   `yaml:"kind"`
+	Resources  []string `yaml:"resources"`
+	Patches    []patch  `yaml:"patches,omitempty"`
+}
+
+type patch struct {
+	Path   string      `yaml:"path"`
+	Target patchTarget `yaml:"target"`
+}
+
+type patchTarget struct {
+	Group   string `yaml:"group,omitempty"`
+	Version string `yaml:"version"`
+	Kind    string `yaml:"kind"`
+	Name    string `yaml:"name"`
+}
+
+type container struct {
+	Name      string    `yaml:"name"`
+	Resources resources `yaml:"resources"`
+}
+
+type resources struct {
+	Limits   resourceValues `yaml:"limits"`
+	Requests resourceValues `yaml:"requests"`
+}
+
+type resourceValues struct {
+	CPU    string `yaml:"cpu"`
+	Memory string `yaml:"memory"`
+}
+
+// GenerateOverlay renders a kustomize overlay applying the usage-based
+// recommendations on top of base, the path of the base relative to the
+// overlay: a kustomization.yaml and one strategic merge patch per workload.
+// Patches target workloads by kind and name, so the base's namespace does
+// not matter; recommendations from several namespaces for the same
+// workload are an error, generate one overlay per namespace instead. Jobs
+// started by a CronJob patch the CronJob.
+func GenerateOverlay(results []analyzer.PodAnalysis, base string) ([]File, error) {
+	type workload struct {
+		kind, name string
+	}
+	// A CronJob's Jobs share its containers; merged, the largest value wins
+	workloadResults := make([]analyzer.PodAnalysis, len(results))
+	for i, result := range results {
+		if name, ok := analyzer.CronJobName(result.WorkloadName); ok && result.WorkloadKind == "Job" {
+			result.WorkloadKind, result.WorkloadName = "CronJob", name
+		}
+		workloadResults[i] = result
+	}
+
+	containers := map[workload][]container{}
+	namespaces := map[workload]string{}
+	for _, r := range analyzer.WorkloadRecommendations(workloadResults) {
+		w := workload{r.WorkloadKind, r.WorkloadName}
+		if _, ok := podSpecPaths[w.kind]; !ok {
+			continue
+		}
+		if namespace, ok := namespaces[w]; ok && namespace != r.Namespace {
+			return nil, fmt.Errorf("%s %s is in namespaces %s and %s; generate an overlay per namespace", w.kind, w.name, namespace, r.Namespace)
+		}
+		namespaces[w] = r.Namespace
+		containers[w] = append(containers[w], container{
+			Name: r.Container,
+			Resources: resources{
+				Limits:   resourceValues{CPU: r.CPULimit, Memory: r.MemoryLimit},
+				Requests: resourceValues{CPU: r.CPURequest, Memory: r.MemoryRequest},
+			},
+		})
+	}
+
+	workloads := make([]workload, 0, len(containers))
+	for w := range containers {
+		workloads = append(workloads, w)
+	}
+	sort.Slice(workloads, func(i, j int) bool {
+		if workloads[i].kind != workloads[j].kind {
+			return workloads[i].kind < workloads[j].kind
+		}
+		return workloads[i].name < workloads[j].name
+	})
+
+	k := kustomization{
+		APIVersion: "kustomize.config.k8s.io/v1beta1",
+		Kind:       "Kustomization",
+		Resources:  []string{base},
+	}
+	var files []File
+	for _, w := range workloads {
+		spec := podSpecPaths[w.kind]
+		group, version := "", spec.apiVersion
+		if i := strings.Index(version, "/"); i >= 0 {
+			group, version = version[:i], version[i+1:]
+		}
+		name := strings.ToLower(w.kind) + "-" + w.name + ".yaml"
+		k.Patches = append(k.Patches, patch{
+			Path:   name,
+			Target: patchTarget{Group: group, Version: version, Kind: w.kind, Name: w.name},
+		})
+
+		content, err := renderPatch(spec.apiVersion, w.kind, w.name, spec.path, containers[w])
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, File{Name: name, Content: content})
+	}
+
+	data, err := yaml.Marshal(k)
+	if err != nil {
+		return nil, err
+	}
+	header := "# Generated by pod-limit-checker: applies the recommended resources to " + base + ".\n"
+	return append([]File{{Name: "kustomization.yaml", Content: append([]byte(header), data...)}}, files...), nil
+}
+
+// renderPatch renders a strategic merge patch setting the resources of the
+// containers, which are merged into the base by name.
+func renderPatch(apiVersion, kind, name string, path []string, containers []container) ([]byte, error) {
+	sort.Slice(containers, func(i, j int) bool { return containers[i].Name < containers[j].Name })
+
+	var body interface{} = yaml.MapSlice{{Key: "containers", Value: containers}}
+	for i := len(path) - 1; i >= 0; i-- {
+		body = yaml.MapSlice{{Key: path[i], Value: body}}
+	}
+	doc := yaml.MapSlice{
+		{Key: "apiVersion", Value: apiVersion},
+		{Key: "kind", Value: kind},
+		{Key: "metadata", Value: yaml.MapSlice{{Key: "name", Value: name}}},
+	}
+	doc = append(doc, body.(yaml.MapSlice)...)
+	return yaml.Marshal(doc)
+}
@@ -0,0 +1,158 @@
+package kustomize
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v2"
+	"sigs.k8s.io/kustomize/api/krusty"
+	"sigs.k8s.io/kustomize/kyaml/filesys"
+
+	"pod-limit-checker/pkg/analyzer"
+)
+
+func recommendation(kind, name, container, cpuLimit, memoryLimit string) analyzer.PodAnalysis {
+	return analyzer.PodAnalysis{
+		Namespace:                "shop",
+		PodName:                  name + "-0",
+		ContainerName:            container,
+		WorkloadKind:             kind,
+		WorkloadName:             name,
+		RecommendedCPULimit:      cpuLimit,
+		RecommendedCPURequest:    "250m",
+		RecommendedMemoryLimit:   memoryLimit,
+		RecommendedMemoryRequest: "256Mi",
+	}
+}
+
+// build writes the overlay next to the testdata base in an in-memory file
+// system and returns the objects kustomize builds from it, by kind/name.
+func build(t *testing.T, files []File) map[string]map[interface{}]interface{} {
+	t.Helper()
+	fs := filesys.MakeFsInMemory()
+	entries, err := os.ReadDir("testdata/base")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range entries {
+		data, err := os.ReadFile(filepath.Join("testdata/base", entry.Name()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := fs.WriteFile(filepath.Join("/app/base", entry.Name()), data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, file := range files {
+		if err := fs.WriteFile(filepath.Join("/app/overlay", file.Name), file.Content); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	resources, err := krusty.MakeKustomizer(krusty.MakeDefaultOptions()).Run(fs, "/app/overlay")
+	if err != nil {
+		t.Fatalf("kustomize build: %v", err)
+	}
+	out, err := resources.AsYaml()
+	if err != nil {
+		t.Fatal(err)
+	}
+	objects := map[string]map[interface{}]interface{}{}
+	for _, doc := range strings.Split(string(out), "\n---\n") {
+		var obj map[interface{}]interface{}
+		if err := yaml.Unmarshal([]byte(doc), &obj); err != nil {
+			t.Fatal(err)
+		}
+		metadata := obj["metadata"].(map[interface{}]interface{})
+		if metadata["namespace"] != "shop" {
+			t.Errorf("%s %s is in namespace %v, want the base's", obj["kind"], metadata["name"], metadata["namespace"])
+		}
+		objects[obj["kind"].(string)+"/"+metadata["name"].(string)] = obj
+	}
+	return objects
+}
+
+// field walks a decoded object by keys and sequence indexes.
+func field(t *testing.T, obj interface{}, path ...interface{}) interface{} {
+	t.Helper()
+	for _, key := range path {
+		switch value := obj.(type) {
+		case map[interface{}]interface{}:
+			obj = value[key]
+		case []interface{}:
+			obj = value[key.(int)]
+		default:
+			t.Fatalf("no %v in %v", key, obj)
+		}
+	}
+	return obj
+}
+
+func TestGenerateOverlayBuilds(t *testing.T) {
+	results := []analyzer.PodAnalysis{
+		recommendation("Deployment", "api", "app", "500m", "512Mi"),
+		// Replicas merge; the largest value wins
+		recommendation("Deployment", "api", "app", "400m", "768Mi"),
+		recommendation("Job", "report-29012345", "report", "1", "1Gi"),
+		// Not worth a patch without a recommendation
+		{Namespace: "shop", PodName: "api-0", ContainerName: "proxy", WorkloadKind: "Deployment", WorkloadName: "api"},
+	}
+	files, err := GenerateOverlay(results, "../base")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 3 {
+		t.Fatalf("generated %d files, want kustomization.yaml and 2 patches", len(files))
+	}
+	objects := build(t, files)
+	if len(objects) != 2 {
+		t.Fatalf("built %d objects, want the base's 2", len(objects))
+	}
+
+	api := objects["Deployment/api"]
+	containers := field(t, api, "spec", "template", "spec", "containers").([]interface{})
+	if len(containers) != 2 {
+		t.Fatalf("api has %d containers, want 2", len(containers))
+	}
+	for _, c := range containers {
+		name := field(t, c, "name")
+		resources := field(t, c, "resources")
+		want := map[string]map[string]string{
+			"app":   {"cpu": "500m", "memory": "768Mi"},
+			"proxy": {"cpu": "200m", "memory": "128Mi"},
+		}[name.(string)]
+		for resource, value := range want {
+			if got := field(t, resources, "limits", resource); got != value {
+				t.Errorf("%s limits.%s = %v, want %s", name, resource, got, value)
+			}
+		}
+		if name == "app" {
+			if got := field(t, resources, "requests", "cpu"); got != "250m" {
+				t.Errorf("app requests.cpu = %v, want 250m", got)
+			}
+			// Fields the patch does not set stay as in the base
+			if field(t, c, "image") != "example/api:1.4" || field(t, c, "ports", 0, "containerPort") != 8080 {
+				t.Errorf("app lost base fields: %v", c)
+			}
+		}
+	}
+	if replicas := field(t, api, "spec", "replicas"); replicas != 3 {
+		t.Errorf("replicas = %v, want 3", replicas)
+	}
+
+	report := field(t, objects["CronJob/report"], "spec", "jobTemplate", "spec", "template", "spec", "containers", 0)
+	if field(t, report, "resources", "limits", "memory") != "1Gi" || field(t, report, "image") != "example/report:2.0" {
+		t.Errorf("report container = %v", report)
+	}
+}
+
+func TestGenerateOverlayNamespaces(t *testing.T) {
+	other := recommendation("Deployment", "api", "app", "500m", "512Mi")
+	other.Namespace = "staging"
+	_, err := GenerateOverlay([]analyzer.PodAnalysis{recommendation("Deployment", "api", "app", "500m", "512Mi"), other}, "../base")
+	if err == nil || !strings.Contains(err.Error(), "generate an overlay per namespace") {
+		t.Errorf("error = %v, want an overlay per namespace", err)
+	}
+}
@@ -0,0 +1,148 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"text/template"
+	"time"
+
+	"k8s.io/client-go/util/flowcontrol"
+)
+
+const (
+	// maxAttempts bounds the posts of one message when the endpoint answers
+	// 429 or 5xx.
+	maxAttempts = 3
+	// maxRetryAfter caps the wait requested by a Retry-After header.
+	maxRetryAfter = time.Minute
+)
+
+// Target is a configured notification endpoint.
+type Target struct {
+	// Kind is slack, teams or webhook
+	Kind string
+	URL  string
+}
+
+// Options configure the notifiers built by New.
+type Options struct {
+	// Template renders the message; nil uses DefaultTemplate
+	Template *template.Template
+	// Rate is the maximum number of posts per second to one URL; Slack
+	// allows one message per second per incoming webhook
+	Rate float32
+	// Client sends the posts; nil uses a client with a 30s timeout
+	Client *http.Client
+}
+
+// New builds a notifier per target. Targets sharing a URL share its rate
+// limit.
+func New(targets []Target, options Options) ([]Notifier, error) {
+	if options.Template == nil {
+		tmpl, err := ParseTemplate("")
+		if err != nil {
+			return nil, err
+		}
+		options.Template = tmpl
+	}
+	if options.Rate <= 0 {
+		options.Rate = 1
+	}
+	if options.Client == nil {
+		options.Client = &http.Client{Timeout: 30 * time.Second}
+	}
+
+	limiters := map[string]flowcontrol.RateLimiter{}
+	notifiers := make([]Notifier, 0, len(targets))
+	for _, target := range targets {
+		limiter, ok := limiters[target.URL]
+		if !ok {
+			limiter = flowcontrol.NewTokenBucketRateLimiter(options.Rate, 1)
+			limiters[target.URL] = limiter
+		}
+		e := endpoint{url: target.URL, client: options.Client, limiter: limiter}
+
+		switch target.Kind {
+		case "slack":
+			notifiers = append(notifiers, &Slack{endpoint: e, template: options.Template})
+		case "teams":
+			notifiers = append(notifiers, &Teams{endpoint: e, template: options.Template})
+		case "webhook":
+			notifiers = append(notifiers, &Webhook{endpoint: e, template: options.Template})
+		default:
+			return nil, fmt.Errorf("unknown notifier %q (valid: slack, teams, webhook)", target.Kind)
+		}
+	}
+	return notifiers, nil
+}
+
+// endpoint posts JSON to a webhook URL under its rate limit, retrying when
+// the endpoint is throttling or failing.
+type endpoint struct {
+	url     string
+	client  *http.Client
+	limiter flowcontrol.RateLimiter
+}
+
+func (e endpoint) post(ctx context.Context, payload interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	for attempt := 1; ; attempt++ {
+		if err := e.limiter.Wait(ctx); err != nil {
+			return err
+		}
+		retryAfter, err := e.send(ctx, body)
+		if err == nil {
+			return nil
+		}
+		if retryAfter < 0 || attempt == maxAttempts {
+			return err
+		}
+		select {
+		case <-time.After(retryAfter):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// send makes one post. On failure it returns how long to wait before
+// retrying, or a negative duration when retrying will not help.
+func (e endpoint) send(ctx context.Context, body []byte) (time.Duration, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.url, bytes.NewReader(body))
+	if err != nil {
+		return -1, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return time.Second, err
+	}
+	defer resp.Body.Close()
+	message, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	if resp.StatusCode < 300 {
+		return 0, nil
+	}
+
+	err = fmt.Errorf("%s: %s", resp.Status, bytes.TrimSpace(message))
+	if resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode < 500 {
+		return -1, err
+	}
+	wait := time.Second
+	if seconds, parseErr := strconv.Atoi(resp.Header.Get("Retry-After")); parseErr == nil && seconds >= 0 {
+		wait = time.Duration(seconds) * time.Second
+	}
+	if wait > maxRetryAfter {
+		wait = maxRetryAfter
+	}
+	return wait, err
+}
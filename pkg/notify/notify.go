@@ -0,0 +1,140 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"pod-limit-checker/pkg/reporter"
+)
+
+// Notifier posts a digest of a run to a chat or HTTP endpoint.
+type Notifier interface {
+	// Name identifies the notifier in errors, e.g. "slack"
+	Name() string
+	Notify(ctx context.Context, digest Digest) error
+}
+
+// Digest summarizes a run for notifications. It is the data passed to
+// message templates and the body of generic webhook posts.
+type Digest struct {
+	Run     reporter.RunMetadata `json:"run"`
+	Summary reporter.Summary     `json:"summary"`
+	// HasBaseline is set when New and Resolved were computed against the
+	// report of a previous run
+	HasBaseline bool `json:"hasBaseline"`
+	// New holds HIGH and MEDIUM findings absent from the baseline, Resolved
+	// counts those of the baseline that are gone
+	New        []reporter.Finding `json:"new"`
+	NewOmitted int                `json:"newOmitted"`
+	Resolved   int                `json:"resolved"`
+	// Worst holds the HIGH and MEDIUM findings, HIGH first
+	Worst        []reporter.Finding `json:"worst"`
+	WorstOmitted int                `json:"worstOmitted"`
+}
+
+// BuildDigest compares report with baseline, which may be nil, and keeps
+// at most top findings in New and Worst. Findings are identified by
+// workload and container, so replicas count once and pods recreated since
+// the baseline are not new.
+func BuildDigest(report reporter.Report, baseline *reporter.Report, top int) Digest {
+	digest := Digest{
+		Run:         report.Metadata,
+		Summary:     report.Summary,
+		HasBaseline: baseline != nil,
+		New:         []reporter.Finding{},
+		Worst:       []reporter.Finding{},
+	}
+
+	current := riskyFindings(report.Findings)
+	sort.SliceStable(current, func(i, j int) bool {
+		return riskOrder(current[i].RiskLevel) < riskOrder(current[j].RiskLevel)
+	})
+	digest.Worst, digest.WorstOmitted = truncate(current, top)
+
+	if baseline == nil {
+		return digest
+	}
+	previous := map[string]bool{}
+	for _, finding := range riskyFindings(baseline.Findings) {
+		previous[FindingKey(finding)] = true
+	}
+	var added []reporter.Finding
+	for _, finding := range current {
+		key := FindingKey(finding)
+		if previous[key] {
+			delete(previous, key)
+			continue
+		}
+		added = append(added, finding)
+	}
+	digest.New, digest.NewOmitted = truncate(added, top)
+	digest.Resolved = len(previous)
+	return digest
+}
+
+// FindingKey identifies a finding across runs by namespace, workload and
+// container; bare pods are identified by name.
+func FindingKey(finding reporter.Finding) string {
+	return workload(finding) + "/" + finding.Container
+}
+
+// workload renders a finding's workload as namespace/Kind/name.
+func workload(finding reporter.Finding) string {
+	kind, name := finding.WorkloadKind, finding.WorkloadName
+	if kind == "" {
+		kind, name = "Pod", finding.Pod
+	}
+	return strings.Join([]string{finding.Namespace, kind, name}, "/")
+}
+
+// riskyFindings returns the HIGH and MEDIUM findings, one per FindingKey.
+func riskyFindings(findings []reporter.Finding) []reporter.Finding {
+	seen := map[string]bool{}
+	var risky []reporter.Finding
+	for _, finding := range findings {
+		if finding.RiskLevel != "HIGH" && finding.RiskLevel != "MEDIUM" {
+			continue
+		}
+		key := FindingKey(finding)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		risky = append(risky, finding)
+	}
+	return risky
+}
+
+func truncate(findings []reporter.Finding, top int) ([]reporter.Finding, int) {
+	if findings == nil {
+		findings = []reporter.Finding{}
+	}
+	if top <= 0 || len(findings) <= top {
+		return findings, 0
+	}
+	return findings[:top], len(findings) - top
+}
+
+func riskOrder(level string) int {
+	if level == "HIGH" {
+		return 0
+	}
+	return 1
+}
+
+// NotifyAll sends the digest to every notifier. A failing notifier does
+// not stop the others; their errors are returned together.
+func NotifyAll(ctx context.Context, notifiers []Notifier, digest Digest) error {
+	var failed []string
+	for _, notifier := range notifiers {
+		if err := notifier.Notify(ctx, digest); err != nil {
+			failed = append(failed, fmt.Sprintf("%s: %v", notifier.Name(), err))
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("failed to notify %s", strings.Join(failed, "; "))
+	}
+	return nil
+}
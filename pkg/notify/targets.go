@@ -0,0 +1,115 @@
+package notify
+
+import (
+	"context"
+	"strings"
+	"text/template"
+)
+
+// Slack posts the message to a Slack incoming webhook.
+type Slack struct {
+	endpoint
+	template *template.Template
+}
+
+// slackEscaper escapes the characters Slack treats as control sequences.
+var slackEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+var slackMarkup = markup{
+	bold: func(s string) string { return "*" + s + "*" },
+	code: func(s string) string { return "`" + s + "`" },
+}
+
+func (s *Slack) Name() string { return "slack" }
+
+func (s *Slack) Notify(ctx context.Context, digest Digest) error {
+	text, err := render(s.template, slackMarkup, digest)
+	if err != nil {
+		return err
+	}
+	return s.post(ctx, map[string]string{"text": slackEscaper.Replace(text)})
+}
+
+// Teams posts the message to a Microsoft Teams workflow webhook as an
+// Adaptive Card, one text block per line.
+type Teams struct {
+	endpoint
+	template *template.Template
+}
+
+var teamsMarkup = markup{
+	bold: func(s string) string { return "**" + s + "**" },
+	// Adaptive Cards have no inline code
+	code: func(s string) string { return s },
+}
+
+type adaptiveCardMessage struct {
+	Type        string                   `json:"type"`
+	Attachments []adaptiveCardAttachment `json:"attachments"`
+}
+
+type adaptiveCardAttachment struct {
+	ContentType string       `json:"contentType"`
+	Content     adaptiveCard `json:"content"`
+}
+
+type adaptiveCard struct {
+	Schema  string          `json:"$schema"`
+	Type    string          `json:"type"`
+	Version string          `json:"version"`
+	Body    []adaptiveBlock `json:"body"`
+}
+
+type adaptiveBlock struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+	Wrap bool   `json:"wrap"`
+}
+
+func (t *Teams) Name() string { return "teams" }
+
+func (t *Teams) Notify(ctx context.Context, digest Digest) error {
+	text, err := render(t.template, teamsMarkup, digest)
+	if err != nil {
+		return err
+	}
+	card := adaptiveCard{
+		Schema:  "http://adaptivecards.io/schemas/adaptive-card.json",
+		Type:    "AdaptiveCard",
+		Version: "1.4",
+	}
+	for _, line := range strings.Split(text, "\n") {
+		if strings.TrimSpace(line) != "" {
+			card.Body = append(card.Body, adaptiveBlock{Type: "TextBlock", Text: line, Wrap: true})
+		}
+	}
+	return t.post(ctx, adaptiveCardMessage{
+		Type: "message",
+		Attachments: []adaptiveCardAttachment{{
+			ContentType: "application/vnd.microsoft.card.adaptive",
+			Content:     card,
+		}},
+	})
+}
+
+// Webhook posts the rendered message and the digest as JSON, for
+// integrations other than chat.
+type Webhook struct {
+	endpoint
+	template *template.Template
+}
+
+type webhookPayload struct {
+	Text   string `json:"text"`
+	Digest Digest `json:"digest"`
+}
+
+func (w *Webhook) Name() string { return "webhook" }
+
+func (w *Webhook) Notify(ctx context.Context, digest Digest) error {
+	text, err := render(w.template, plainMarkup, digest)
+	if err != nil {
+		return err
+	}
+	return w.post(ctx, webhookPayload{Text: text, Digest: digest})
+}
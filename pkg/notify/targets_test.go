@@ -0,0 +1,218 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"pod-limit-checker/pkg/reporter"
+)
+
+// testDigest has one new HIGH finding with characters Slack escapes.
+func testDigest() Digest {
+	finding := reporter.Finding{
+		Namespace:    "web",
+		Pod:          "api-7d9f8-x2x4z",
+		Container:    "app",
+		WorkloadKind: "Deployment",
+		WorkloadName: "api",
+		RiskLevel:    "HIGH",
+		Suggestions:  []string{"❌ No resource limits set <memory & cpu>"},
+	}
+	return Digest{
+		Run:         reporter.RunMetadata{Timestamp: time.Date(2026, 10, 1, 6, 0, 0, 0, time.UTC), Cluster: "prod"},
+		Summary:     reporter.Summary{TotalContainers: 12, HighRisk: 1, MediumRisk: 2, NoLimits: 1},
+		HasBaseline: true,
+		New:         []reporter.Finding{finding},
+		Resolved:    3,
+		Worst:       []reporter.Finding{finding},
+	}
+}
+
+// recorder is an endpoint that answers with the queued statuses, then 200,
+// and keeps the bodies it received.
+type recorder struct {
+	mu       sync.Mutex
+	statuses []int
+	bodies   [][]byte
+}
+
+func (r *recorder) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	body, _ := io.ReadAll(req.Body)
+	if req.Method != http.MethodPost || req.Header.Get("Content-Type") != "application/json" {
+		http.Error(w, "want a JSON post", http.StatusBadRequest)
+		return
+	}
+	r.bodies = append(r.bodies, body)
+	if len(r.statuses) > 0 {
+		status := r.statuses[0]
+		r.statuses = r.statuses[1:]
+		w.Header().Set("Retry-After", "0")
+		http.Error(w, "invalid_payload", status)
+		return
+	}
+	w.Write([]byte("ok"))
+}
+
+// notify sends testDigest to one target served by rec and returns the error.
+func notify(t *testing.T, kind string, rec *recorder) error {
+	t.Helper()
+	server := httptest.NewServer(rec)
+	defer server.Close()
+
+	notifiers, err := New([]Target{{Kind: kind, URL: server.URL}}, Options{Rate: 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return notifiers[0].Notify(context.Background(), testDigest())
+}
+
+const wantText = `Pod limit audit of prod
+1 HIGH and 2 MEDIUM risk containers, 1 without limits
+1 new and 3 resolved since the previous run
+• HIGH web/Deployment/api container app: ❌ No resource limits set <memory & cpu>
+Worst findings
+• HIGH web/Deployment/api container app: ❌ No resource limits set <memory & cpu>`
+
+func TestSlack(t *testing.T) {
+	rec := &recorder{}
+	if err := notify(t, "slack", rec); err != nil {
+		t.Fatal(err)
+	}
+	if len(rec.bodies) != 1 {
+		t.Fatalf("got %d posts, want 1", len(rec.bodies))
+	}
+	var payload map[string]string
+	if err := json.Unmarshal(rec.bodies[0], &payload); err != nil {
+		t.Fatal(err)
+	}
+	want := strings.NewReplacer("Pod limit audit", "*Pod limit audit*", "Worst findings", "*Worst findings*",
+		"prod", "`prod`", "web/Deployment/api", "`web/Deployment/api`", "app:", "`app`:",
+		"<memory & cpu>", "&lt;memory &amp; cpu&gt;").Replace(wantText)
+	if len(payload) != 1 || payload["text"] != want {
+		t.Errorf("payload = %q, want text:\n%s", payload, want)
+	}
+}
+
+func TestTeams(t *testing.T) {
+	rec := &recorder{}
+	if err := notify(t, "teams", rec); err != nil {
+		t.Fatal(err)
+	}
+	var message adaptiveCardMessage
+	if err := json.Unmarshal(rec.bodies[0], &message); err != nil {
+		t.Fatal(err)
+	}
+	if message.Type != "message" || len(message.Attachments) != 1 {
+		t.Fatalf("message = %+v, want one attachment", message)
+	}
+	attachment := message.Attachments[0]
+	if attachment.ContentType != "application/vnd.microsoft.card.adaptive" || attachment.Content.Type != "AdaptiveCard" {
+		t.Errorf("attachment = %s %s, want an Adaptive Card", attachment.ContentType, attachment.Content.Type)
+	}
+
+	lines := strings.Split(strings.NewReplacer("Pod limit audit", "**Pod limit audit**", "Worst findings", "**Worst findings**").Replace(wantText), "\n")
+	body := attachment.Content.Body
+	if len(body) != len(lines) {
+		t.Fatalf("card has %d blocks, want %d: %+v", len(body), len(lines), body)
+	}
+	for i, block := range body {
+		if block.Type != "TextBlock" || !block.Wrap || block.Text != lines[i] {
+			t.Errorf("block %d = %+v, want wrapped TextBlock %q", i, block, lines[i])
+		}
+	}
+}
+
+func TestWebhook(t *testing.T) {
+	rec := &recorder{}
+	if err := notify(t, "webhook", rec); err != nil {
+		t.Fatal(err)
+	}
+	var payload struct {
+		Text   string                 `json:"text"`
+		Digest map[string]interface{} `json:"digest"`
+	}
+	if err := json.Unmarshal(rec.bodies[0], &payload); err != nil {
+		t.Fatal(err)
+	}
+	if payload.Text != wantText {
+		t.Errorf("text = %q, want %q", payload.Text, wantText)
+	}
+	for _, key := range []string{"run", "summary", "hasBaseline", "new", "newOmitted", "resolved", "worst", "worstOmitted"} {
+		if _, ok := payload.Digest[key]; !ok {
+			t.Errorf("digest lacks %s: %v", key, payload.Digest)
+		}
+	}
+	var digest Digest
+	data, _ := json.Marshal(payload.Digest)
+	if err := json.Unmarshal(data, &digest); err != nil {
+		t.Fatal(err)
+	}
+	if digest.Resolved != 3 || len(digest.New) != 1 || digest.New[0].Container != "app" {
+		t.Errorf("digest = %+v", digest)
+	}
+}
+
+func TestNotifyErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		statuses []int
+		posts    int
+		wantErr  string
+	}{
+		{name: "client error is not retried", statuses: []int{http.StatusBadRequest}, posts: 1, wantErr: "400 Bad Request: invalid_payload"},
+		{name: "gone", statuses: []int{http.StatusNotFound}, posts: 1, wantErr: "404 Not Found"},
+		{name: "throttled then accepted", statuses: []int{http.StatusTooManyRequests}, posts: 2},
+		{name: "server errors until the last attempt", statuses: []int{500, 502}, posts: 3},
+		{name: "server errors exhaust the attempts", statuses: []int{500, 502, 503, 500}, posts: maxAttempts, wantErr: "503 Service Unavailable"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, kind := range []string{"slack", "teams", "webhook"} {
+				rec := &recorder{statuses: append([]int(nil), tt.statuses...)}
+				err := notify(t, kind, rec)
+				if tt.wantErr == "" && err != nil {
+					t.Errorf("%s: %v", kind, err)
+				}
+				if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+					t.Errorf("%s: error = %v, want %q", kind, err, tt.wantErr)
+				}
+				if len(rec.bodies) != tt.posts {
+					t.Errorf("%s: got %d posts, want %d", kind, len(rec.bodies), tt.posts)
+				}
+			}
+		})
+	}
+}
+
+func TestNotifyAll(t *testing.T) {
+	ok, failing := &recorder{}, &recorder{statuses: []int{http.StatusForbidden}}
+	okServer, failingServer := httptest.NewServer(ok), httptest.NewServer(failing)
+	defer okServer.Close()
+	defer failingServer.Close()
+
+	notifiers, err := New([]Target{{Kind: "teams", URL: failingServer.URL}, {Kind: "slack", URL: okServer.URL}}, Options{Rate: 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = NotifyAll(context.Background(), notifiers, testDigest())
+	if err == nil || !strings.Contains(err.Error(), "teams: 403 Forbidden") {
+		t.Errorf("error = %v, want the teams failure", err)
+	}
+	// A failing notifier does not stop the others
+	if len(ok.bodies) != 1 {
+		t.Errorf("slack got %d posts, want 1", len(ok.bodies))
+	}
+
+	if _, err := New([]Target{{Kind: "email", URL: okServer.URL}}, Options{}); err == nil {
+		t.Error("unknown notifier kind accepted")
+	}
+}
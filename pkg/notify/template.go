@@ -0,0 +1,92 @@
+package notify
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+	"text/template"
+)
+
+// DefaultTemplate is the message posted when no template is configured.
+// Templates execute on a Digest; bold and code format text for the target
+// chat, workload renders a finding's namespace/Kind/name and first returns
+// its first suggestion.
+const DefaultTemplate = `{{define "finding"}}• {{.RiskLevel}} {{code (workload .)}} container {{code .Container}}{{with first .Suggestions}}: {{.}}{{end}}{{end -}}
+{{bold "Pod limit audit"}}{{with .Run.Cluster}} of {{code .}}{{end}}
+{{.Summary.HighRisk}} HIGH and {{.Summary.MediumRisk}} MEDIUM risk containers, {{.Summary.NoLimits}} without limits
+{{- if .HasBaseline}}
+{{add (len .New) .NewOmitted}} new and {{.Resolved}} resolved since the previous run
+{{- range .New}}
+{{template "finding" .}}
+{{- end}}
+{{- end}}
+{{- if .Worst}}
+{{bold "Worst findings"}}
+{{- range .Worst}}
+{{template "finding" .}}
+{{- end}}
+{{- if .WorstOmitted}}
+… and {{.WorstOmitted}} more
+{{- end}}
+{{- end}}
+`
+
+// markup formats text for one chat.
+type markup struct {
+	bold func(string) string
+	code func(string) string
+}
+
+var plainMarkup = markup{
+	bold: func(s string) string { return s },
+	code: func(s string) string { return s },
+}
+
+// templateFuncs are the functions available to templates; bold and code are
+// replaced per notifier.
+func templateFuncs(m markup) template.FuncMap {
+	return template.FuncMap{
+		"bold":     m.bold,
+		"code":     m.code,
+		"workload": workload,
+		"first": func(values []string) string {
+			if len(values) == 0 {
+				return ""
+			}
+			return values[0]
+		},
+		"add": func(a, b int) int { return a + b },
+	}
+}
+
+// ParseTemplate reads a message template from path, or returns the default
+// template when path is empty.
+func ParseTemplate(path string) (*template.Template, error) {
+	text := DefaultTemplate
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read notification template: %v", err)
+		}
+		text = string(data)
+	}
+	tmpl, err := template.New("message").Funcs(templateFuncs(plainMarkup)).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse notification template: %v", err)
+	}
+	return tmpl, nil
+}
+
+// render executes tmpl on the digest with the given markup.
+func render(tmpl *template.Template, m markup, digest Digest) (string, error) {
+	clone, err := tmpl.Clone()
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	if err := clone.Funcs(templateFuncs(m)).Execute(&buf, digest); err != nil {
+		return "", fmt.Errorf("failed to render notification: %v", err)
+	}
+	return strings.TrimSpace(buf.String()), nil
+}
@@ -0,0 +1,37 @@
+package reporter
+
+import (
+	"fmt"
+	"text/tabwriter"
+
+	"pod-limit-checker/pkg/cost"
+)
+
+// maxCostRows bounds the per-workload table in text output; JSON, YAML and
+// HTML always carry every line.
+const maxCostRows = 10
+
+func (r *Reporter) printCostEstimate(estimate *cost.Estimate) {
+	fmt.Fprintf(r.out, "\n💰 Estimated monthly cost (%s):\n", estimate.Currency)
+	fmt.Fprintf(r.out, "  Current (requests):      %.2f\n", estimate.Cluster.Current)
+	fmt.Fprintf(r.out, "  Projected (recommended): %.2f\n", estimate.Cluster.Projected)
+	fmt.Fprintf(r.out, "  Savings:                 %.2f\n", estimate.Cluster.Savings)
+
+	fmt.Fprintf(r.out, "\n  By namespace:\n")
+	r.printCostLines("NAMESPACE", estimate.ByNamespace, len(estimate.ByNamespace))
+
+	fmt.Fprintf(r.out, "\n  Top workloads by savings:\n")
+	r.printCostLines("WORKLOAD", estimate.ByWorkload, maxCostRows)
+}
+
+func (r *Reporter) printCostLines(header string, lines []cost.CostLine, limit int) {
+	w := tabwriter.NewWriter(r.out, 0, 0, 3, ' ', 0)
+	fmt.Fprintf(w, "  %s\tCURRENT\tPROJECTED\tSAVINGS\n", header)
+	for i, line := range lines {
+		if i >= limit {
+			break
+		}
+		fmt.Fprintf(w, "  %s\t%.2f\t%.2f\t%.2f\n", line.Name, line.Current, line.Projected, line.Savings)
+	}
+	w.Flush()
+}
@@ -0,0 +1,237 @@
+package reporter
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+
+	"pod-limit-checker/pkg/analyzer"
+)
+
+// Custom resources written by WriteCustomResources, defined in k8s/crd.yaml.
+var (
+	NamespaceReportResource = schema.GroupVersionResource{Group: "podlimitchecker.io", Version: "v1", Resource: "podlimitreports"}
+	ClusterReportResource   = schema.GroupVersionResource{Group: "podlimitchecker.io", Version: "v1", Resource: "clusterpodlimitreports"}
+)
+
+const (
+	ClusterReportKind = "ClusterPodLimitReport"
+	// NamespaceReportName is the name of the report in each namespace and
+	// ClusterReportName that of the cluster-wide summary.
+	NamespaceReportName = "pod-limit-checker"
+	ClusterReportName   = "cluster"
+	// ConditionCompliant is True when no container is HIGH or MEDIUM risk.
+	ConditionCompliant = "Compliant"
+
+	managedByLabel = "app.kubernetes.io/managed-by"
+	managedBy      = "pod-limit-checker"
+	// maxCustomReportFindings keeps a report well below the object size
+	// limit; the riskiest findings are kept.
+	maxCustomReportFindings = 500
+)
+
+// NamespaceReport is the PodLimitReport custom resource for one namespace.
+// It carries the namespace's rollup and the same findings as the JSON
+// report; run metadata is under run since metadata is the object's own.
+type NamespaceReport struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata"`
+	Run               RunMetadata `json:"run"`
+	Summary           RollupRow   `json:"summary"`
+	Findings          []Finding   `json:"findings"`
+	// FindingsOmitted counts findings dropped to stay within the size limit
+	FindingsOmitted int                `json:"findingsOmitted,omitempty"`
+	Status          CustomReportStatus `json:"status"`
+}
+
+// ClusterReport is the cluster-scoped ClusterPodLimitReport summarizing
+// every namespace.
+type ClusterReport struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata"`
+	Run               RunMetadata        `json:"run"`
+	Summary           RollupRow          `json:"summary"`
+	Namespaces        []RollupRow        `json:"namespaces"`
+	Status            CustomReportStatus `json:"status"`
+}
+
+// CustomReportStatus holds the report's conditions.
+type CustomReportStatus struct {
+	Conditions []metav1.Condition `json:"conditions"`
+}
+
+// WriteCustomResources creates or updates a PodLimitReport in every
+// analyzed namespace and deletes those left in namespaces without pods.
+// The ClusterPodLimitReport is only written when scope is "" (all
+// namespaces), since it would otherwise summarize part of the cluster.
+func (r *Reporter) WriteCustomResources(ctx context.Context, client dynamic.Interface, results []analyzer.PodAnalysis, scope string, showAll bool) error {
+	run := r.metadata
+	if run.Timestamp.IsZero() {
+		run.Timestamp = time.Now().UTC()
+	}
+
+	byNamespace := map[string][]analyzer.PodAnalysis{}
+	for _, result := range results {
+		byNamespace[result.Namespace] = append(byNamespace[result.Namespace], result)
+	}
+	rows := buildRollups(results, "", RollupSortName).ByNamespace
+
+	for _, row := range rows {
+		findings := make([]Finding, 0)
+		for _, result := range filterResults(byNamespace[row.Name], showAll) {
+			findings = append(findings, newFinding(result))
+		}
+		sort.SliceStable(findings, func(i, j int) bool {
+			return riskOrder(findings[i].RiskLevel) < riskOrder(findings[j].RiskLevel)
+		})
+
+		report := NamespaceReport{
+			TypeMeta:   metav1.TypeMeta{APIVersion: ReportAPIVersion, Kind: ReportKind},
+			ObjectMeta: reportMeta(NamespaceReportName, row.Name),
+			Run:        run,
+			Summary:    row,
+			Findings:   findings,
+		}
+		if len(findings) > maxCustomReportFindings {
+			report.Findings = findings[:maxCustomReportFindings]
+			report.FindingsOmitted = len(findings) - maxCustomReportFindings
+		}
+
+		resource := client.Resource(NamespaceReportResource).Namespace(row.Name)
+		if err := writeCustomResource(ctx, resource, &report, &report.Status, complianceCondition(row)); err != nil {
+			return fmt.Errorf("failed to write PodLimitReport in %s: %v", row.Name, err)
+		}
+	}
+
+	if err := pruneCustomResources(ctx, client, scope, byNamespace); err != nil {
+		return err
+	}
+
+	if scope != "" {
+		return nil
+	}
+	total := &rollupAccumulator{row: RollupRow{Name: ClusterReportName}}
+	for _, result := range results {
+		total.add(result)
+	}
+	summary := total.finish()
+	report := ClusterReport{
+		TypeMeta:   metav1.TypeMeta{APIVersion: ReportAPIVersion, Kind: ClusterReportKind},
+		ObjectMeta: reportMeta(ClusterReportName, ""),
+		Run:        run,
+		Summary:    summary,
+		Namespaces: rows,
+	}
+	if err := writeCustomResource(ctx, client.Resource(ClusterReportResource), &report, &report.Status, complianceCondition(summary)); err != nil {
+		return fmt.Errorf("failed to write ClusterPodLimitReport: %v", err)
+	}
+	return nil
+}
+
+func reportMeta(name, namespace string) metav1.ObjectMeta {
+	return metav1.ObjectMeta{
+		Name:      name,
+		Namespace: namespace,
+		Labels:    map[string]string{managedByLabel: managedBy},
+	}
+}
+
+// complianceCondition derives the Compliant condition from a rollup.
+func complianceCondition(row RollupRow) metav1.Condition {
+	if row.HighRisk == 0 && row.MediumRisk == 0 {
+		return metav1.Condition{
+			Type:    ConditionCompliant,
+			Status:  metav1.ConditionTrue,
+			Reason:  "AllContainersLowRisk",
+			Message: fmt.Sprintf("All %d containers are LOW risk", row.Containers),
+		}
+	}
+	return metav1.Condition{
+		Type:    ConditionCompliant,
+		Status:  metav1.ConditionFalse,
+		Reason:  "RiskyContainers",
+		Message: fmt.Sprintf("%d HIGH and %d MEDIUM risk of %d containers", row.HighRisk, row.MediumRisk, row.Containers),
+	}
+}
+
+// writeCustomResource creates or updates obj. The existing conditions are
+// carried over so an unchanged condition keeps its lastTransitionTime.
+func writeCustomResource(ctx context.Context, resource dynamic.ResourceInterface, obj metav1.Object, status *CustomReportStatus, condition metav1.Condition) error {
+	existing, err := resource.Get(ctx, obj.GetName(), metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		existing, err = nil, nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if existing != nil {
+		if data, err := json.Marshal(existing.Object["status"]); err == nil {
+			var previous CustomReportStatus
+			if json.Unmarshal(data, &previous) == nil {
+				status.Conditions = previous.Conditions
+			}
+		}
+	}
+	meta.SetStatusCondition(&status.Conditions, condition)
+
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return err
+	}
+	u := &unstructured.Unstructured{}
+	if err := u.UnmarshalJSON(data); err != nil {
+		return err
+	}
+
+	if existing == nil {
+		_, err = resource.Create(ctx, u, metav1.CreateOptions{})
+		return err
+	}
+	u.SetResourceVersion(existing.GetResourceVersion())
+	_, err = resource.Update(ctx, u, metav1.UpdateOptions{})
+	return err
+}
+
+// pruneCustomResources deletes our PodLimitReports in namespaces of the
+// scope that no longer have analyzed pods.
+func pruneCustomResources(ctx context.Context, client dynamic.Interface, scope string, analyzed map[string][]analyzer.PodAnalysis) error {
+	list, err := client.Resource(NamespaceReportResource).Namespace(scope).List(ctx, metav1.ListOptions{
+		LabelSelector: managedByLabel + "=" + managedBy,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to list PodLimitReports: %v", err)
+	}
+	for _, item := range list.Items {
+		if _, ok := analyzed[item.GetNamespace()]; ok {
+			continue
+		}
+		err := client.Resource(NamespaceReportResource).Namespace(item.GetNamespace()).Delete(ctx, item.GetName(), metav1.DeleteOptions{})
+		if err != nil && !apierrors.IsNotFound(err) {
+			return fmt.Errorf("failed to delete stale PodLimitReport in %s: %v", item.GetNamespace(), err)
+		}
+	}
+	return nil
+}
+
+// riskOrder sorts HIGH first.
+func riskOrder(level string) int {
+	switch level {
+	case "HIGH":
+		return 0
+	case "MEDIUM":
+		return 1
+	case "LOW":
+		return 2
+	}
+	return 3
+}
@@ -0,0 +1,236 @@
+package reporter
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+
+	"pod-limit-checker/pkg/analyzer"
+)
+
+func newReportClient(objects ...runtime.Object) *dynamicfake.FakeDynamicClient {
+	return dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{
+		NamespaceReportResource: ReportKind + "List",
+		ClusterReportResource:   ClusterReportKind + "List",
+	}, objects...)
+}
+
+// reportObject builds an existing PodLimitReport; labels nil leaves it
+// unmanaged.
+func reportObject(namespace string, labels map[string]string, conditions ...metav1.Condition) *unstructured.Unstructured {
+	u := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": ReportAPIVersion,
+		"kind":       ReportKind,
+		"metadata": map[string]interface{}{
+			"name":            NamespaceReportName,
+			"namespace":       namespace,
+			"resourceVersion": "7",
+		},
+	}}
+	u.SetLabels(labels)
+	if len(conditions) > 0 {
+		data, err := json.Marshal(CustomReportStatus{Conditions: conditions})
+		if err != nil {
+			panic(err)
+		}
+		var status map[string]interface{}
+		if err := json.Unmarshal(data, &status); err != nil {
+			panic(err)
+		}
+		u.Object["status"] = status
+	}
+	return u
+}
+
+var managedLabels = map[string]string{managedByLabel: managedBy}
+
+// getReport reads a report written to the fake into out.
+func getReport(t *testing.T, client *dynamicfake.FakeDynamicClient, gvr schema.GroupVersionResource, namespace, name string, out interface{}) {
+	t.Helper()
+	u, err := client.Resource(gvr).Namespace(namespace).Get(context.Background(), name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := u.MarshalJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func writeReports(t *testing.T, client *dynamicfake.FakeDynamicClient, results []analyzer.PodAnalysis, scope string) {
+	t.Helper()
+	r := NewReporter("json", io.Discard)
+	r.SetMetadata(testMetadata)
+	if err := r.WriteCustomResources(context.Background(), client, results, scope, false); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWriteCustomResourcesCreate(t *testing.T) {
+	client := newReportClient()
+	writeReports(t, client, sampleResults(), "")
+
+	var web NamespaceReport
+	getReport(t, client, NamespaceReportResource, "web", NamespaceReportName, &web)
+	if web.Labels[managedByLabel] != managedBy {
+		t.Errorf("labels = %v, want %s=%s", web.Labels, managedByLabel, managedBy)
+	}
+	if !web.Run.Timestamp.Equal(testMetadata.Timestamp) {
+		t.Errorf("run timestamp = %v, want %v", web.Run.Timestamp, testMetadata.Timestamp)
+	}
+	if web.Summary.Name != "web" || web.Summary.HighRisk != 1 || len(web.Findings) != 1 || web.Findings[0].Container != "app" {
+		t.Errorf("web report = %+v", web)
+	}
+	compliant := meta.FindStatusCondition(web.Status.Conditions, ConditionCompliant)
+	if compliant == nil || compliant.Status != metav1.ConditionFalse || compliant.Reason != "RiskyContainers" {
+		t.Errorf("Compliant condition = %+v, want False RiskyContainers", compliant)
+	}
+
+	// The LOW risk container is counted but not listed
+	var jobs NamespaceReport
+	getReport(t, client, NamespaceReportResource, "jobs", NamespaceReportName, &jobs)
+	if jobs.Summary.Containers != 1 || len(jobs.Findings) != 0 {
+		t.Errorf("jobs report summary %+v with %d findings, want 1 container and no findings", jobs.Summary, len(jobs.Findings))
+	}
+	if compliant := meta.FindStatusCondition(jobs.Status.Conditions, ConditionCompliant); compliant == nil || compliant.Status != metav1.ConditionTrue {
+		t.Errorf("jobs Compliant condition = %+v, want True", compliant)
+	}
+
+	var cluster ClusterReport
+	getReport(t, client, ClusterReportResource, "", ClusterReportName, &cluster)
+	if cluster.Summary.Containers != len(sampleResults()) || len(cluster.Namespaces) != 3 {
+		t.Errorf("cluster report summary %+v with namespaces %+v", cluster.Summary, cluster.Namespaces)
+	}
+}
+
+func TestWriteCustomResourcesNamespaceScope(t *testing.T) {
+	client := newReportClient()
+	var web []analyzer.PodAnalysis
+	for _, result := range sampleResults() {
+		if result.Namespace == "web" {
+			web = append(web, result)
+		}
+	}
+	writeReports(t, client, web, "web")
+
+	_, err := client.Resource(ClusterReportResource).Get(context.Background(), ClusterReportName, metav1.GetOptions{})
+	if !apierrors.IsNotFound(err) {
+		t.Errorf("ClusterPodLimitReport written for a namespace scope (err %v)", err)
+	}
+}
+
+// TestWriteCustomResourcesKeepsTransitionTime checks that an update only
+// moves lastTransitionTime when the condition's status changes.
+func TestWriteCustomResourcesKeepsTransitionTime(t *testing.T) {
+	since := metav1.NewTime(time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC))
+	client := newReportClient(
+		// web stays non-compliant
+		reportObject("web", managedLabels, metav1.Condition{
+			Type: ConditionCompliant, Status: metav1.ConditionFalse, Reason: "RiskyContainers",
+			Message: "1 HIGH and 0 MEDIUM risk of 1 containers", LastTransitionTime: since,
+		}, metav1.Condition{
+			Type: "Ready", Status: metav1.ConditionTrue, Reason: "Other", LastTransitionTime: since,
+		}),
+		// jobs becomes compliant
+		reportObject("jobs", managedLabels, metav1.Condition{
+			Type: ConditionCompliant, Status: metav1.ConditionFalse, Reason: "RiskyContainers", LastTransitionTime: since,
+		}),
+	)
+	writeReports(t, client, sampleResults(), "")
+
+	var web NamespaceReport
+	getReport(t, client, NamespaceReportResource, "web", NamespaceReportName, &web)
+	compliant := meta.FindStatusCondition(web.Status.Conditions, ConditionCompliant)
+	if compliant == nil || !compliant.LastTransitionTime.Equal(&since) {
+		t.Errorf("unchanged Compliant condition = %+v, want lastTransitionTime %v", compliant, since)
+	}
+	if meta.FindStatusCondition(web.Status.Conditions, "Ready") == nil {
+		t.Errorf("conditions = %+v, other conditions were dropped", web.Status.Conditions)
+	}
+	if len(web.Findings) != 1 {
+		t.Errorf("updated report has %d findings, want 1", len(web.Findings))
+	}
+
+	var jobs NamespaceReport
+	getReport(t, client, NamespaceReportResource, "jobs", NamespaceReportName, &jobs)
+	compliant = meta.FindStatusCondition(jobs.Status.Conditions, ConditionCompliant)
+	if compliant == nil || compliant.Status != metav1.ConditionTrue || compliant.LastTransitionTime.Equal(&since) {
+		t.Errorf("changed Compliant condition = %+v, want True with a new lastTransitionTime", compliant)
+	}
+}
+
+func TestWriteCustomResourcesPrune(t *testing.T) {
+	client := newReportClient(
+		reportObject("web", managedLabels),
+		// Its pods are gone
+		reportObject("retired", managedLabels),
+		// Not ours
+		reportObject("manual", nil),
+	)
+	writeReports(t, client, sampleResults(), "")
+
+	for namespace, want := range map[string]bool{"web": true, "ml": true, "jobs": true, "retired": false, "manual": true} {
+		_, err := client.Resource(NamespaceReportResource).Namespace(namespace).Get(context.Background(), NamespaceReportName, metav1.GetOptions{})
+		if exists := err == nil; exists != want {
+			t.Errorf("report in %s exists = %v, want %v (err %v)", namespace, exists, want, err)
+		}
+	}
+
+	// A namespace scope only prunes its own namespace
+	client = newReportClient(reportObject("retired", managedLabels), reportObject("web", managedLabels))
+	writeReports(t, client, nil, "web")
+	for namespace, want := range map[string]bool{"web": false, "retired": true} {
+		_, err := client.Resource(NamespaceReportResource).Namespace(namespace).Get(context.Background(), NamespaceReportName, metav1.GetOptions{})
+		if exists := err == nil; exists != want {
+			t.Errorf("scoped run: report in %s exists = %v, want %v", namespace, exists, want)
+		}
+	}
+}
+
+func TestWriteCustomResourcesTruncates(t *testing.T) {
+	var results []analyzer.PodAnalysis
+	for i := 0; i < maxCustomReportFindings+20; i++ {
+		// Interleave the risks so truncation has to keep the riskiest
+		risk := "MEDIUM"
+		if i%5 == 0 {
+			risk = "HIGH"
+		}
+		results = append(results, analyzer.PodAnalysis{
+			Namespace:     "batch",
+			PodName:       fmt.Sprintf("worker-%d", i),
+			ContainerName: "worker",
+			HasLimits:     true,
+			RiskLevel:     risk,
+		})
+	}
+	client := newReportClient()
+	writeReports(t, client, results, "")
+
+	var report NamespaceReport
+	getReport(t, client, NamespaceReportResource, "batch", NamespaceReportName, &report)
+	if len(report.Findings) != maxCustomReportFindings || report.FindingsOmitted != 20 {
+		t.Fatalf("report has %d findings and %d omitted, want %d and 20", len(report.Findings), report.FindingsOmitted, maxCustomReportFindings)
+	}
+	for i, finding := range report.Findings[:len(results)/5] {
+		if finding.RiskLevel != "HIGH" {
+			t.Fatalf("finding %d is %s, want every HIGH finding first", i, finding.RiskLevel)
+		}
+	}
+	if report.Summary.Containers != len(results) {
+		t.Errorf("summary counts %d containers, want all %d", report.Summary.Containers, len(results))
+	}
+}
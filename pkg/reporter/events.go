@@ -0,0 +1,307 @@
+package reporter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/record"
+
+	"pod-limit-checker/pkg/analyzer"
+)
+
+const (
+	// EventComponent is the source of the Events recorded by RecordEvents.
+	EventComponent = "pod-limit-checker"
+	// Event reasons, one per risk level.
+	ReasonHighRisk   = "HighRiskResources"
+	ReasonMediumRisk = "MediumRiskResources"
+)
+
+// eventTarget is a workload with HIGH or MEDIUM risk containers and the
+// problems found in them, keyed by container name so replicas count once.
+type eventTarget struct {
+	namespace  string
+	kind       string
+	name       string
+	pod        string
+	containers map[string]map[string][]string // risk level -> container -> problems
+}
+
+// RecordEvents records a Warning Event on every workload with HIGH or
+// MEDIUM risk containers, so the findings show up in kubectl describe.
+// There is one Event per workload and risk level, and none is recorded when
+// an identical one from an earlier run still exists; the API server expires
+// Events after its --event-ttl (1h by default).
+//
+// It returns the number of Events recorded. Events that could not be
+// recorded are reported in the error and recorded on the next run; the
+// caller should not treat them as fatal.
+func RecordEvents(ctx context.Context, client kubernetes.Interface, results []analyzer.PodAnalysis) (int, error) {
+	targets := eventTargets(results)
+	if len(targets) == 0 {
+		return 0, nil
+	}
+
+	sink := &countingSink{
+		EventSink: &corev1client.EventSinkImpl{Interface: client.CoreV1().Events("")},
+		results:   make(chan sinkResult, len(targets)*2),
+	}
+	broadcaster := record.NewBroadcaster(record.WithContext(ctx))
+	defer broadcaster.Shutdown()
+	broadcaster.StartRecordingToSink(sink)
+	recorder := broadcaster.NewRecorder(scheme.Scheme, v1.EventSource{Component: EventComponent})
+
+	existing := map[string]map[string]bool{}
+	pending := 0
+	var errs []error
+	for _, target := range targets {
+		ref, err := eventReference(ctx, client, target)
+		if apierrors.IsNotFound(err) {
+			// Deleted since the pods were listed
+			continue
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to get %s %s/%s: %v", target.kind, target.namespace, target.name, err))
+			continue
+		}
+
+		seen, ok := existing[target.namespace]
+		if !ok {
+			if seen, err = recordedEvents(ctx, client, target.namespace); err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			existing[target.namespace] = seen
+		}
+
+		for _, level := range []string{"HIGH", "MEDIUM"} {
+			containers := target.containers[level]
+			if len(containers) == 0 {
+				continue
+			}
+			reason := ReasonHighRisk
+			if level == "MEDIUM" {
+				reason = ReasonMediumRisk
+			}
+			message := eventMessage(level, containers)
+			if seen[eventKey(string(ref.UID), reason, message)] {
+				continue
+			}
+			recorder.Event(ref, v1.EventTypeWarning, reason, message)
+			pending++
+		}
+	}
+
+	// The recorder writes asynchronously; wait for every write before the
+	// broadcaster is shut down
+	recorded := 0
+	for written := 0; written < pending; written++ {
+		select {
+		case result := <-sink.results:
+			if result.err != nil {
+				errs = append(errs, fmt.Errorf("failed to record %s Event on %s %s/%s: %v",
+					result.event.Reason, result.event.InvolvedObject.Kind, result.event.Namespace, result.event.InvolvedObject.Name, result.err))
+				continue
+			}
+			recorded++
+		case <-ctx.Done():
+			errs = append(errs, fmt.Errorf("timed out recording Events: %d of %d written", written, pending))
+			return recorded, errors.Join(errs...)
+		}
+	}
+	return recorded, errors.Join(errs...)
+}
+
+// sinkResult is the outcome of writing an Event.
+type sinkResult struct {
+	event *v1.Event
+	err   error
+}
+
+// countingSink reports the outcome of every write to results, so
+// RecordEvents knows when the recorder is done. Failed writes are reported
+// there and hidden from the recorder, which would otherwise retry them for
+// minutes while holding back the other Events; they are recorded on the
+// next run instead.
+type countingSink struct {
+	record.EventSink
+	results chan sinkResult
+}
+
+func (s *countingSink) Create(event *v1.Event) (*v1.Event, error) {
+	written, err := s.EventSink.Create(event)
+	return s.report(event, written, err)
+}
+
+func (s *countingSink) Update(event *v1.Event) (*v1.Event, error) {
+	written, err := s.EventSink.Update(event)
+	return s.report(event, written, err)
+}
+
+func (s *countingSink) Patch(event *v1.Event, data []byte) (*v1.Event, error) {
+	written, err := s.EventSink.Patch(event, data)
+	return s.report(event, written, err)
+}
+
+func (s *countingSink) report(event, written *v1.Event, err error) (*v1.Event, error) {
+	s.results <- sinkResult{event: event, err: err}
+	if err != nil {
+		return event, nil
+	}
+	return written, nil
+}
+
+// eventTargets groups the HIGH and MEDIUM risk containers by workload, in
+// namespace, kind and name order.
+func eventTargets(results []analyzer.PodAnalysis) []*eventTarget {
+	byWorkload := map[string]*eventTarget{}
+	var targets []*eventTarget
+	for _, result := range results {
+		if result.RiskLevel != "HIGH" && result.RiskLevel != "MEDIUM" {
+			continue
+		}
+		kind, name := result.WorkloadKind, result.WorkloadName
+		if kind == "" {
+			kind, name = "Pod", result.PodName
+		}
+		key := result.Namespace + "/" + kind + "/" + name
+		target, ok := byWorkload[key]
+		if !ok {
+			target = &eventTarget{
+				namespace:  result.Namespace,
+				kind:       kind,
+				name:       name,
+				pod:        result.PodName,
+				containers: map[string]map[string][]string{},
+			}
+			byWorkload[key] = target
+			targets = append(targets, target)
+		}
+		if target.containers[result.RiskLevel] == nil {
+			target.containers[result.RiskLevel] = map[string][]string{}
+		}
+		target.containers[result.RiskLevel][result.ContainerName] = containerProblems(result)
+	}
+
+	sort.Slice(targets, func(i, j int) bool {
+		a, b := targets[i], targets[j]
+		if a.namespace != b.namespace {
+			return a.namespace < b.namespace
+		}
+		if a.kind != b.kind {
+			return a.kind < b.kind
+		}
+		return a.name < b.name
+	})
+	return targets
+}
+
+// containerProblems describes a container's risk from its spec only, so the
+// message of a workload that has not changed is the same on every run and
+// deduplication works. Usage-based suggestions are left to the report.
+func containerProblems(result analyzer.PodAnalysis) []string {
+	if !result.HasLimits {
+		return []string{"no resource limits"}
+	}
+	var problems []string
+	for _, name := range []v1.ResourceName{v1.ResourceCPU, v1.ResourceMemory} {
+		if _, ok := result.CurrentLimits[name]; !ok {
+			problems = append(problems, fmt.Sprintf("no %s limit", name))
+		}
+	}
+	if result.QoSViolation {
+		problems = append(problems, fmt.Sprintf("QoS %s, %s required", result.QoSClass, result.RequiredQoSClass))
+	}
+	if len(problems) == 0 {
+		problems = append(problems, "limits outside policy")
+	}
+	return problems
+}
+
+// eventMessage lists the containers at a risk level and their problems.
+func eventMessage(level string, containers map[string][]string) string {
+	names := make([]string, 0, len(containers))
+	for name := range containers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	parts := make([]string, 0, len(names))
+	for _, name := range names {
+		parts = append(parts, fmt.Sprintf("%s (%s)", name, strings.Join(containers[name], ", ")))
+	}
+	return fmt.Sprintf("%s risk resource settings in containers: %s; run pod-limit-checker for recommendations",
+		level, strings.Join(parts, "; "))
+}
+
+// eventReference fetches the workload for its UID, without which kubectl
+// describe does not show the Event. Kinds the typed client does not know,
+// such as custom controllers, get the Event on one of their pods instead.
+func eventReference(ctx context.Context, client kubernetes.Interface, target *eventTarget) (*v1.ObjectReference, error) {
+	var (
+		object     metav1.Object
+		apiVersion = "apps/v1"
+		kind       = target.kind
+		err        error
+	)
+	opts := metav1.GetOptions{}
+	switch target.kind {
+	case "Deployment":
+		object, err = client.AppsV1().Deployments(target.namespace).Get(ctx, target.name, opts)
+	case "StatefulSet":
+		object, err = client.AppsV1().StatefulSets(target.namespace).Get(ctx, target.name, opts)
+	case "DaemonSet":
+		object, err = client.AppsV1().DaemonSets(target.namespace).Get(ctx, target.name, opts)
+	case "ReplicaSet":
+		object, err = client.AppsV1().ReplicaSets(target.namespace).Get(ctx, target.name, opts)
+	case "Job":
+		apiVersion = "batch/v1"
+		object, err = client.BatchV1().Jobs(target.namespace).Get(ctx, target.name, opts)
+	default:
+		apiVersion, kind = "v1", "Pod"
+		object, err = client.CoreV1().Pods(target.namespace).Get(ctx, target.pod, opts)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &v1.ObjectReference{
+		APIVersion:      apiVersion,
+		Kind:            kind,
+		Namespace:       object.GetNamespace(),
+		Name:            object.GetName(),
+		UID:             object.GetUID(),
+		ResourceVersion: object.GetResourceVersion(),
+	}, nil
+}
+
+// recordedEvents returns the keys of the Events recorded in a namespace by
+// earlier runs that have not expired yet.
+func recordedEvents(ctx context.Context, client kubernetes.Interface, namespace string) (map[string]bool, error) {
+	list, err := client.CoreV1().Events(namespace).List(ctx, metav1.ListOptions{
+		FieldSelector: fields.OneTermEqualSelector("source", EventComponent).String(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list Events in %s: %v", namespace, err)
+	}
+	seen := map[string]bool{}
+	for _, event := range list.Items {
+		if event.Source.Component == EventComponent {
+			seen[eventKey(string(event.InvolvedObject.UID), event.Reason, event.Message)] = true
+		}
+	}
+	return seen, nil
+}
+
+func eventKey(uid, reason, message string) string {
+	return uid + "\x00" + reason + "\x00" + message
+}
@@ -0,0 +1,162 @@
+package reporter
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	clienttesting "k8s.io/client-go/testing"
+
+	"pod-limit-checker/pkg/analyzer"
+)
+
+func eventWorkloads() []runtime.Object {
+	return []runtime.Object{
+		&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "api", Namespace: "web", UID: "uid-api"}},
+		&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "worker-0", Namespace: "jobs", UID: "uid-worker"}},
+	}
+}
+
+// eventResults has a HIGH and a MEDIUM risk container in web/api, a bare
+// pod with a MEDIUM risk container and a LOW risk container.
+func eventResults() []analyzer.PodAnalysis {
+	return []analyzer.PodAnalysis{
+		{Namespace: "web", PodName: "api-1", ContainerName: "app", WorkloadKind: "Deployment", WorkloadName: "api", RiskLevel: "HIGH"},
+		{Namespace: "web", PodName: "api-2", ContainerName: "app", WorkloadKind: "Deployment", WorkloadName: "api", RiskLevel: "HIGH"},
+		{Namespace: "web", PodName: "api-1", ContainerName: "proxy", WorkloadKind: "Deployment", WorkloadName: "api", RiskLevel: "MEDIUM",
+			HasLimits: true, CurrentLimits: resourceList("memory", "64Mi")},
+		{Namespace: "jobs", PodName: "worker-0", ContainerName: "worker", WorkloadKind: "Pod", WorkloadName: "worker-0", RiskLevel: "MEDIUM",
+			HasLimits: true, CurrentLimits: resourceList("cpu", "1")},
+		{Namespace: "web", PodName: "cache-0", ContainerName: "redis", WorkloadKind: "StatefulSet", WorkloadName: "cache", RiskLevel: "LOW", HasLimits: true},
+	}
+}
+
+func listEvents(t *testing.T, client *fake.Clientset) []v1.Event {
+	t.Helper()
+	list, err := client.CoreV1().Events("").List(context.Background(), metav1.ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return list.Items
+}
+
+func TestRecordEvents(t *testing.T) {
+	client := fake.NewSimpleClientset(eventWorkloads()...)
+	ctx := context.Background()
+
+	recorded, err := RecordEvents(ctx, client, eventResults())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if recorded != 3 {
+		t.Fatalf("recorded %d Events, want 3", recorded)
+	}
+	byReason := map[string]v1.Event{}
+	for _, event := range listEvents(t, client) {
+		if event.Type != v1.EventTypeWarning || event.Source.Component != EventComponent {
+			t.Errorf("event %+v is not a %s Warning", event, EventComponent)
+		}
+		byReason[event.InvolvedObject.Kind+"/"+event.Reason] = event
+	}
+	high := byReason["Deployment/"+ReasonHighRisk]
+	if high.InvolvedObject.UID != "uid-api" || high.Namespace != "web" {
+		t.Errorf("HIGH event involves %+v in %s, want the api Deployment", high.InvolvedObject, high.Namespace)
+	}
+	// Replicas count once
+	if want := "HIGH risk resource settings in containers: app (no resource limits);"; !strings.HasPrefix(high.Message, want) {
+		t.Errorf("HIGH message = %q, want prefix %q", high.Message, want)
+	}
+	if medium := byReason["Pod/"+ReasonMediumRisk]; !strings.Contains(medium.Message, "worker (no memory limit)") {
+		t.Errorf("bare pod event = %+v", medium)
+	}
+
+	// Nothing new while the Events exist
+	if recorded, err := RecordEvents(ctx, client, eventResults()); err != nil || recorded != 0 {
+		t.Errorf("rerun recorded %d Events (err %v), want 0", recorded, err)
+	}
+	// A changed finding is recorded again; the unchanged ones are not
+	results := eventResults()
+	results[2].CurrentLimits = resourceList("cpu", "500m")
+	if recorded, err := RecordEvents(ctx, client, results); err != nil || recorded != 1 {
+		t.Fatalf("recorded %d Events (err %v), want 1", recorded, err)
+	}
+
+	// Once the API server expired them, the findings show up again
+	for _, event := range listEvents(t, client) {
+		if err := client.CoreV1().Events(event.Namespace).Delete(ctx, event.Name, metav1.DeleteOptions{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if recorded, err := RecordEvents(ctx, client, eventResults()); err != nil || recorded != 3 {
+		t.Errorf("rerun after expiry recorded %d Events (err %v), want 3", recorded, err)
+	}
+	// Workloads are only read
+	for _, action := range client.Actions() {
+		if action.GetResource().Resource != "events" && action.GetVerb() != "get" {
+			t.Errorf("unexpected %s of %s", action.GetVerb(), action.GetResource().Resource)
+		}
+	}
+}
+
+// TestRecordEventsExistingEvents checks that an Event recorded by an
+// earlier run is not duplicated while it exists.
+func TestRecordEventsExistingEvents(t *testing.T) {
+	target := eventTargets(eventResults()[:2])[0]
+	existing := &v1.Event{
+		ObjectMeta:     metav1.ObjectMeta{Name: "api.1", Namespace: "web"},
+		InvolvedObject: v1.ObjectReference{Kind: "Deployment", Namespace: "web", Name: "api", UID: "uid-api"},
+		Reason:         ReasonHighRisk,
+		Message:        eventMessage("HIGH", target.containers["HIGH"]),
+		Source:         v1.EventSource{Component: EventComponent},
+	}
+	client := fake.NewSimpleClientset(append(eventWorkloads(), existing)...)
+
+	recorded, err := RecordEvents(context.Background(), client, eventResults()[:2])
+	if err != nil || recorded != 0 {
+		t.Errorf("recorded %d Events (err %v), want 0", recorded, err)
+	}
+	if events := listEvents(t, client); len(events) != 1 {
+		t.Errorf("events = %+v, want the existing one", events)
+	}
+}
+
+// TestRecordEventsFailure checks that a failed write is reported without
+// blocking on the recorder's retries and is recorded on the next run.
+func TestRecordEventsFailure(t *testing.T) {
+	client := fake.NewSimpleClientset(eventWorkloads()...)
+	client.PrependReactor("create", "events", func(action clienttesting.Action) (bool, runtime.Object, error) {
+		event := action.(clienttesting.CreateAction).GetObject().(*v1.Event)
+		if event.InvolvedObject.Kind == "Deployment" {
+			return true, nil, errors.New("events is forbidden")
+		}
+		return false, nil, nil
+	})
+	ctx := context.Background()
+
+	recorded, err := RecordEvents(ctx, client, eventResults())
+	if err == nil || !strings.Contains(err.Error(), "events is forbidden") {
+		t.Errorf("error = %v, want the failed writes", err)
+	}
+	// The pod's Event is still recorded
+	if recorded != 1 {
+		t.Errorf("recorded %d Events, want 1", recorded)
+	}
+	client.ReactionChain = client.ReactionChain[1:]
+	if recorded, err := RecordEvents(ctx, client, eventResults()); err != nil || recorded != 2 {
+		t.Errorf("retry recorded %d Events (err %v), want 2", recorded, err)
+	}
+}
+
+func TestRecordEventsDeletedWorkload(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	recorded, err := RecordEvents(context.Background(), client, eventResults())
+	if err != nil || recorded != 0 {
+		t.Errorf("recorded %d Events (err %v), want none for deleted workloads", recorded, err)
+	}
+}
@@ -0,0 +1,174 @@
+package reporter
+
+import (
+	"io"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	"pod-limit-checker/pkg/analyzer"
+	"pod-limit-checker/pkg/cost"
+)
+
+// testMetadata is fixed so rendered reports are stable.
+var testMetadata = RunMetadata{
+	Timestamp:   time.Date(2026, 10, 1, 6, 0, 0, 0, time.UTC),
+	Cluster:     "https://10.0.0.1:6443",
+	ToolVersion: "test",
+	Flags:       map[string]string{"threshold": "0.8"},
+}
+
+func quantity(s string) *resource.Quantity {
+	q := resource.MustParse(s)
+	return &q
+}
+
+func resourceList(pairs ...string) v1.ResourceList {
+	list := v1.ResourceList{}
+	for i := 0; i+1 < len(pairs); i += 2 {
+		list[v1.ResourceName(pairs[i])] = resource.MustParse(pairs[i+1])
+	}
+	return list
+}
+
+// sampleResults covers a HIGH, a MEDIUM and a LOW risk container with the
+// optional analysis views populated.
+func sampleResults() []analyzer.PodAnalysis {
+	return []analyzer.PodAnalysis{
+		{
+			Namespace:           "web",
+			PodName:             "api-7d9f8-x2x4z",
+			ContainerName:       "app",
+			NodeName:            "node-1",
+			WorkloadKind:        "Deployment",
+			WorkloadName:        "api",
+			Labels:              map[string]string{"team": "storefront"},
+			CurrentRequests:     resourceList("cpu", "100m"),
+			CurrentUsage:        &analyzer.ResourceUsage{CPU: quantity("120m"), Memory: quantity("200Mi")},
+			Suggestions:         []string{"❌ No resource limits set", "☕ JVM heap -Xmx2g exceeds the recommended memory limit"},
+			RiskLevel:           "HIGH",
+			Age:                 "3d",
+			HasRequests:         true,
+			QoSClass:            "Burstable",
+			RecommendedQoSClass: "Burstable",
+			VPA: &analyzer.VPARecommendation{
+				Name:       "api",
+				UpdateMode: "Off",
+				VPABounds: analyzer.VPABounds{
+					Target:     resourceList("cpu", "150m", "memory", "256Mi"),
+					LowerBound: resourceList("cpu", "100m", "memory", "200Mi"),
+					UpperBound: resourceList("cpu", "400m", "memory", "512Mi"),
+				},
+			},
+			HPAImpacts: []analyzer.HPAImpact{{
+				HPA:                  "api",
+				Resource:             v1.ResourceCPU,
+				TargetUtilization:    80,
+				CurrentThreshold:     "80m",
+				RecommendedThreshold: "115m",
+				ScalesDifferently:    true,
+			}},
+			RecommendedCPULimit:       "300m",
+			RecommendedCPURequest:     "144m",
+			RecommendedMemoryLimit:    "500Mi",
+			RecommendedMemoryRequest:  "240Mi",
+			Runtime:                   "jvm",
+			RuntimeSetting:            "-Xmx2g",
+			RecommendedRuntimeSetting: "-XX:MaxRAMPercentage=75",
+			ExampleYAML:               "        resources:\n          limits:\n            cpu: \"300m\"\n            memory: \"500Mi\"",
+		},
+		{
+			Namespace:                "ml",
+			PodName:                  "trainer-0",
+			ContainerName:            "trainer",
+			NodeName:                 "gpu-1",
+			WorkloadKind:             "StatefulSet",
+			WorkloadName:             "trainer",
+			Labels:                   map[string]string{"team": "research"},
+			HasLimits:                true,
+			HasRequests:              true,
+			CPULimitRatio:            8,
+			MemoryLimitRatio:         1,
+			IdleCPUMilli:             1800,
+			IdleMemoryBytes:          3 << 30,
+			CurrentLimits:            resourceList("cpu", "4", "memory", "4Gi", "nvidia.com/gpu", "1"),
+			CurrentRequests:          resourceList("cpu", "500m", "memory", "4Gi", "nvidia.com/gpu", "1"),
+			CurrentUsage:             &analyzer.ResourceUsage{CPU: quantity("200m"), Memory: quantity("1Gi")},
+			Suggestions:              []string{"📐 CPU limit is 8.0x request, policy allows at most 4.0x", "🚫 Pod QoS is Burstable, namespaces labelled tier=critical require Guaranteed"},
+			RiskLevel:                "MEDIUM",
+			Age:                      "12h",
+			QoSClass:                 "Burstable",
+			RecommendedQoSClass:      "Guaranteed",
+			RequiredQoSClass:         "Guaranteed",
+			QoSViolation:             true,
+			RecommendedCPULimit:      "500m",
+			RecommendedCPURequest:    "500m",
+			RecommendedMemoryLimit:   "2560Mi",
+			RecommendedMemoryRequest: "2560Mi",
+		},
+		{
+			Namespace:        "jobs",
+			PodName:          "worker-0",
+			ContainerName:    "worker",
+			WorkloadKind:     "Pod",
+			WorkloadName:     "worker-0",
+			HasLimits:        true,
+			HasRequests:      true,
+			CPULimitRatio:    4,
+			MemoryLimitRatio: 4,
+			CurrentLimits:    resourceList("cpu", "1", "memory", "1Gi"),
+			CurrentRequests:  resourceList("cpu", "250m", "memory", "256Mi"),
+			Suggestions:      []string{},
+			RiskLevel:        "LOW",
+			Age:              "5m",
+			QoSClass:         "Burstable",
+		},
+	}
+}
+
+// sampleNodes is a node view matching sampleResults.
+func sampleNodes() []analyzer.NodeAnalysis {
+	usage := int64(3 << 30)
+	cpu := int64(1500)
+	return []analyzer.NodeAnalysis{{
+		Name:              "node-1",
+		AllocatableCPU:    2000,
+		AllocatableMemory: 4 << 30,
+		RequestsCPU:       1100,
+		RequestsMemory:    2 << 30,
+		LimitsCPU:         4000,
+		LimitsMemory:      5 << 30,
+		UsageCPU:          &cpu,
+		UsageMemory:       &usage,
+		PodCount:          2,
+		UnboundedPods:     1,
+		Overcommitted:     true,
+		Reasons:           []string{"CPU limits at 200% of allocatable"},
+		TopContributors: []analyzer.NodeContributor{
+			{Namespace: "web", PodName: "api-7d9f8-x2x4z", RequestsCPU: 100, Unbounded: true},
+			// No findings row, so the HTML report does not link it
+			{Namespace: "kube-system", PodName: "coredns-5d78c-9bq2k", RequestsCPU: 100, LimitsCPU: 200},
+		},
+	}}
+}
+
+// sampleCost is a cost estimate matching sampleResults.
+func sampleCost() *cost.Estimate {
+	return &cost.Estimate{
+		Currency:    "USD",
+		Cluster:     cost.CostLine{Name: "cluster", Current: 120, Projected: 80, Savings: 40},
+		ByNamespace: []cost.CostLine{{Name: "ml", Current: 100, Projected: 60, Savings: 40}},
+		ByWorkload:  []cost.CostLine{{Name: "ml/StatefulSet/trainer", Current: 100, Projected: 60, Savings: 40}},
+	}
+}
+
+// sampleReporter renders sampleResults with every optional view enabled.
+func sampleReporter(format string, out io.Writer) *Reporter {
+	r := NewReporter(format, out)
+	r.SetMetadata(testMetadata)
+	r.SetRollup("team", RollupSortWorst)
+	r.SetCostEstimate(sampleCost())
+	r.SetNodeAnalysis(sampleNodes())
+	return r
+}
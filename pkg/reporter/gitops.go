@@ -0,0 +1,76 @@
+package reporter
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"text/tabwriter"
+
+	"gopkg.in/yaml.v2"
+
+	"pod-limit-checker/pkg/gitops"
+)
+
+// GenerateRemediationReport renders the manifest changes of a GitOps
+// remediation and, once committed, the branch and commit.
+func (r *Reporter) GenerateRemediationReport(plan *gitops.Plan) error {
+	switch strings.ToLower(r.format) {
+	case "json":
+		data, err := json.MarshalIndent(plan, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(r.out, string(data))
+		return nil
+	case "yaml":
+		data, err := yaml.Marshal(plan)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(r.out, string(data))
+		return nil
+	default:
+		r.printRemediationPlan(plan)
+		return nil
+	}
+}
+
+func (r *Reporter) printRemediationPlan(plan *gitops.Plan) {
+	if len(plan.Changes) == 0 {
+		fmt.Fprintln(r.out, "✅ No manifest needs changing.")
+		return
+	}
+
+	fmt.Fprintf(r.out, "📝 Manifest changes in %s:\n\n", plan.Repo)
+	w := tabwriter.NewWriter(r.out, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(w, "  NAMESPACE\tWORKLOAD\tCONTAINER\tFILE\tCPU (LIMIT/REQUEST)\tMEMORY (LIMIT/REQUEST)\tSTATUS")
+	var skipped int
+	for _, c := range plan.Changes {
+		file := "-"
+		if c.File != "" {
+			file = fmt.Sprintf("%s:%d", c.File, c.Line)
+		}
+		status := "planned"
+		switch {
+		case c.Skipped != "":
+			status = "skipped: " + c.Skipped
+			skipped++
+		case plan.Commit != "":
+			status = "committed"
+		}
+		if len(c.SharedWith) > 0 {
+			status += " (also used in " + strings.Join(c.SharedWith, ", ") + ")"
+		}
+		fmt.Fprintf(w, "  %s\t%s/%s\t%s\t%s\t%s → %s\t%s → %s\t%s\n",
+			c.Namespace, c.Kind, c.Name, c.Container, file,
+			pair(c.Current.CPULimit, c.Current.CPURequest), pair(c.Target.CPULimit, c.Target.CPURequest),
+			pair(c.Current.MemoryLimit, c.Current.MemoryRequest), pair(c.Target.MemoryLimit, c.Target.MemoryRequest),
+			status)
+	}
+	w.Flush()
+
+	fmt.Fprintf(r.out, "\n  %d container(s), %d without an editable manifest\n", len(plan.Changes), skipped)
+	if plan.Commit != "" {
+		fmt.Fprintf(r.out, "  Committed %s on branch %s\n", plan.Commit, plan.Branch)
+	}
+}
@@ -0,0 +1,96 @@
+package reporter
+
+import (
+	"fmt"
+	"sort"
+	"text/tabwriter"
+
+	"pod-limit-checker/pkg/analyzer"
+)
+
+// HPAImpact is an HPA utilization target on one of a container's
+// resources. Thresholds are the per-container usage at which the target is
+// reached with the current and the recommended request.
+type HPAImpact struct {
+	HPA                  string `json:"hpa" yaml:"hpa"`
+	Resource             string `json:"resource" yaml:"resource"`
+	TargetUtilization    int32  `json:"targetUtilization" yaml:"targetUtilization"`
+	CurrentThreshold     string `json:"currentThreshold" yaml:"currentThreshold"`
+	RecommendedThreshold string `json:"recommendedThreshold,omitempty" yaml:"recommendedThreshold,omitempty"`
+	ScalesDifferently    bool   `json:"scalesDifferently" yaml:"scalesDifferently"`
+}
+
+// HPAChange is a workload whose HPA would scale at a different absolute
+// usage after applying the recommended requests.
+type HPAChange struct {
+	Namespace    string    `json:"namespace" yaml:"namespace"`
+	WorkloadKind string    `json:"workloadKind" yaml:"workloadKind"`
+	WorkloadName string    `json:"workloadName" yaml:"workloadName"`
+	Container    string    `json:"container" yaml:"container"`
+	Impact       HPAImpact `json:"impact" yaml:"impact"`
+}
+
+func newHPAImpacts(impacts []analyzer.HPAImpact) []HPAImpact {
+	if len(impacts) == 0 {
+		return nil
+	}
+	converted := make([]HPAImpact, 0, len(impacts))
+	for _, impact := range impacts {
+		converted = append(converted, HPAImpact{
+			HPA:                  impact.HPA,
+			Resource:             string(impact.Resource),
+			TargetUtilization:    impact.TargetUtilization,
+			CurrentThreshold:     impact.CurrentThreshold,
+			RecommendedThreshold: impact.RecommendedThreshold,
+			ScalesDifferently:    impact.ScalesDifferently,
+		})
+	}
+	return converted
+}
+
+// buildHPAChanges lists each workload container once per resource whose
+// scaling point moves. Pods of the same workload share a spec, so the first
+// pod seen stands for the workload.
+func buildHPAChanges(results []analyzer.PodAnalysis) []HPAChange {
+	seen := map[string]bool{}
+	var changes []HPAChange
+	for _, result := range results {
+		for _, impact := range newHPAImpacts(result.HPAImpacts) {
+			if !impact.ScalesDifferently {
+				continue
+			}
+			key := fmt.Sprintf("%s/%s/%s/%s/%s", result.Namespace, result.WorkloadKind, result.WorkloadName, result.ContainerName, impact.Resource)
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			changes = append(changes, HPAChange{
+				Namespace:    result.Namespace,
+				WorkloadKind: result.WorkloadKind,
+				WorkloadName: result.WorkloadName,
+				Container:    result.ContainerName,
+				Impact:       impact,
+			})
+		}
+	}
+
+	sort.SliceStable(changes, func(i, j int) bool {
+		if changes[i].Namespace != changes[j].Namespace {
+			return changes[i].Namespace < changes[j].Namespace
+		}
+		return changes[i].WorkloadName < changes[j].WorkloadName
+	})
+	return changes
+}
+
+func (r *Reporter) printHPAChanges(changes []HPAChange) {
+	fmt.Fprintf(r.out, "\n📈 Workloads that would scale differently after applying recommendations:\n")
+	w := tabwriter.NewWriter(r.out, 0, 0, 3, ' ', 0)
+	fmt.Fprintf(w, "  WORKLOAD\tCONTAINER\tHPA\tTARGET\tSCALES AT NOW\tSCALES AT AFTER\n")
+	for _, change := range changes {
+		fmt.Fprintf(w, "  %s/%s/%s\t%s\t%s\t%d%% %s\t%s\t%s\n",
+			change.Namespace, change.WorkloadKind, change.WorkloadName, change.Container, change.Impact.HPA,
+			change.Impact.TargetUtilization, change.Impact.Resource, change.Impact.CurrentThreshold, change.Impact.RecommendedThreshold)
+	}
+	w.Flush()
+}
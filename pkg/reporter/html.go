@@ -0,0 +1,201 @@
+package reporter
+
+import (
+	"html/template"
+	"strings"
+
+	"pod-limit-checker/pkg/analyzer"
+)
+
+var htmlTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"join":            strings.Join,
+	"lower":           strings.ToLower,
+	"optionalPercent": optionalPercent,
+	"podAnchor":       func(namespace, pod string) string { return "" },
+	"podLink":         func(namespace, pod string) string { return "" },
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Pod Limit Report</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; width: 100%; }
+th, td { border: 1px solid #ccc; padding: 4px 8px; text-align: left; vertical-align: top; }
+th { background: #f0f0f0; }
+.high { background: #fdd; }
+.medium { background: #ffd; }
+.low { background: #dfd; }
+</style>
+</head>
+<body>
+<h1>Pod Limit Report</h1>
+<p>Generated {{.Metadata.Timestamp.Format "2006-01-02 15:04:05 MST"}}{{if .Metadata.Cluster}} for {{.Metadata.Cluster}}{{end}} by pod-limit-checker {{.Metadata.ToolVersion}}</p>
+
+<h2>Summary</h2>
+<table>
+<tr><th>Total containers</th><td>{{.Summary.TotalContainers}}</td></tr>
+<tr><th>High risk</th><td>{{.Summary.HighRisk}}</td></tr>
+<tr><th>Medium risk</th><td>{{.Summary.MediumRisk}}</td></tr>
+<tr><th>Low risk</th><td>{{.Summary.LowRisk}}</td></tr>
+<tr><th>No limits set</th><td>{{.Summary.NoLimits}}</td></tr>
+<tr><th>No requests set</th><td>{{.Summary.NoRequests}}</td></tr>
+<tr><th>With usage metrics</th><td>{{.Summary.WithUsageData}}</td></tr>
+</table>
+
+{{with .Rollups}}
+<h2>By namespace</h2>
+{{template "rollup" .ByNamespace}}
+<h2>By owner (label {{.OwnerLabel}})</h2>
+{{template "rollup" .ByOwner}}
+{{end}}
+
+{{with .IdleReserved}}
+<h2>Idle reserved capacity</h2>
+<table>
+<tr><th>Namespace</th><th>Containers</th><th>CPU</th><th>Memory</th></tr>
+{{range .}}
+<tr><td>{{.Namespace}}</td><td>{{.Containers}}</td><td>{{.CPU}}</td><td>{{.Memory}}</td></tr>
+{{end}}
+</table>
+{{end}}
+
+{{with .HPAChanges}}
+<h2>Workloads that would scale differently</h2>
+<table>
+<tr><th>Workload</th><th>Container</th><th>HPA</th><th>Target</th><th>Scales at now</th><th>Scales at after</th></tr>
+{{range .}}
+<tr><td>{{.Namespace}}/{{.WorkloadKind}}/{{.WorkloadName}}</td><td>{{.Container}}</td><td>{{.Impact.HPA}}</td><td>{{.Impact.TargetUtilization}}% {{.Impact.Resource}}</td><td>{{.Impact.CurrentThreshold}}</td><td>{{.Impact.RecommendedThreshold}}</td></tr>
+{{end}}
+</table>
+{{end}}
+
+{{with .Cost}}
+<h2>Estimated monthly cost ({{.Currency}})</h2>
+<table>
+<tr><th>Current (requests)</th><td>{{printf "%.2f" .Cluster.Current}}</td></tr>
+<tr><th>Projected (recommended)</th><td>{{printf "%.2f" .Cluster.Projected}}</td></tr>
+<tr><th>Savings</th><td>{{printf "%.2f" .Cluster.Savings}}</td></tr>
+</table>
+<h3>By namespace</h3>
+{{template "cost" .ByNamespace}}
+<h3>By workload</h3>
+{{template "cost" .ByWorkload}}
+{{end}}
+
+{{with .Nodes}}
+<h2>Nodes</h2>
+<table>
+<tr><th>Node</th><th>Pods</th><th>CPU allocatable</th><th>CPU requests</th><th>CPU limits</th><th>CPU usage</th><th>Memory allocatable</th><th>Memory requests</th><th>Memory limits</th><th>Memory usage</th><th>Status</th><th>Top pods</th></tr>
+{{range .}}
+<tr class="{{if or .Overcommitted .EvictionProne}}high{{else}}low{{end}}">
+<td>{{.Name}}</td>
+<td>{{.Pods}}</td>
+<td>{{.CPU.Allocatable}}</td>
+<td>{{.CPU.Requests}} ({{printf "%.0f%%" .CPU.RequestsPercent}})</td>
+<td>{{.CPU.Limits}} ({{printf "%.0f%%" .CPU.LimitsPercent}})</td>
+<td>{{if .CPU.UsagePercent}}{{.CPU.Usage}} ({{optionalPercent .CPU.UsagePercent}}){{else}}-{{end}}</td>
+<td>{{.Memory.Allocatable}}</td>
+<td>{{.Memory.Requests}} ({{printf "%.0f%%" .Memory.RequestsPercent}})</td>
+<td>{{.Memory.Limits}} ({{printf "%.0f%%" .Memory.LimitsPercent}})</td>
+<td>{{if .Memory.UsagePercent}}{{.Memory.Usage}} ({{optionalPercent .Memory.UsagePercent}}){{else}}-{{end}}</td>
+<td>{{if .Reasons}}{{join .Reasons "; "}}{{else}}OK{{end}}</td>
+<td>{{range .TopPods}}{{with podLink .Namespace .Pod}}<a href="#{{.}}">{{end}}{{.Namespace}}/{{.Pod}}{{if podLink .Namespace .Pod}}</a>{{end}}{{if .Unbounded}} (no limits){{end}}<br>{{end}}</td>
+</tr>
+{{end}}
+</table>
+{{end}}
+
+<h2>Findings</h2>
+{{if .Findings}}
+<table>
+<tr><th>Namespace</th><th>Pod</th><th>Container</th><th>Age</th><th>Limits</th><th>Usage</th><th>Recommended limits</th><th>Recommended requests</th><th>VPA target</th><th>QoS</th><th>Risk</th><th>Suggestions</th></tr>
+{{range .Findings}}
+<tr class="{{lower .RiskLevel}}"{{with podAnchor .Namespace .Pod}} id="{{.}}"{{end}}>
+<td>{{.Namespace}}</td>
+<td>{{.Pod}}</td>
+<td>{{.Container}}</td>
+<td>{{.Age}}</td>
+<td>{{if .HasLimits}}{{template "resources" .Limits}}{{else}}None{{end}}</td>
+<td>{{with .Usage}}{{template "resources" .}}{{end}}</td>
+<td>{{with .Recommendation}}{{template "resources" .Limits}}{{end}}</td>
+<td>{{with .Recommendation}}{{template "resources" .Requests}}{{end}}</td>
+<td>{{with .VPA}}{{.Name}} ({{.UpdateMode}})<br>{{template "resources" .Target}}{{end}}</td>
+<td>{{with .QoS}}{{.Current}}{{if and .Recommended (ne .Recommended .Current)}} → {{.Recommended}}{{end}}{{if .Violation}} (requires {{.Required}}){{end}}{{else}}-{{end}}</td>
+<td>{{.RiskLevel}}</td>
+<td>{{join .Suggestions "; "}}</td>
+</tr>
+{{end}}
+</table>
+{{else}}
+<p>All pods have proper resource limits configured.</p>
+{{end}}
+</body>
+</html>
+{{define "resources"}}{{with .CPU}}CPU: {{.}}<br>{{end}}{{with .Memory}}Mem: {{.}}<br>{{end}}{{with .EphemeralStorage}}Eph: {{.}}<br>{{end}}{{range $name, $value := .Extended}}{{$name}}: {{$value}}<br>{{end}}{{end}}
+{{define "rollup"}}
+<table>
+<tr><th>Name</th><th>Containers</th><th>High</th><th>Medium</th><th>Low</th><th>Compliance</th><th>CPU requested / used / recommended</th><th>Memory requested / used / recommended</th></tr>
+{{range .}}
+<tr>
+<td>{{.Name}}</td>
+<td>{{.Containers}}</td>
+<td>{{.HighRisk}}</td>
+<td>{{.MediumRisk}}</td>
+<td>{{.LowRisk}}</td>
+<td>{{printf "%.1f%%" .CompliancePercent}}</td>
+<td>{{.CPU.Requested}} / {{.CPU.Used}} / {{.CPU.Recommended}}</td>
+<td>{{.Memory.Requested}} / {{.Memory.Used}} / {{.Memory.Recommended}}</td>
+</tr>
+{{end}}
+</table>
+{{end}}
+{{define "cost"}}
+<table>
+<tr><th>Name</th><th>Current</th><th>Projected</th><th>Savings</th></tr>
+{{range .}}
+<tr><td>{{.Name}}</td><td>{{printf "%.2f" .Current}}</td><td>{{printf "%.2f" .Projected}}</td><td>{{printf "%.2f" .Savings}}</td></tr>
+{{end}}
+</table>
+{{end}}
+`))
+
+func (r *Reporter) generateHTML(results []analyzer.PodAnalysis) error {
+	tmpl, err := htmlTemplate.Clone()
+	if err != nil {
+		return err
+	}
+
+	report := r.buildReport(results)
+	// Node top pods link only to pods that have a findings row to land on
+	anchors := map[string]bool{}
+	for _, finding := range report.Findings {
+		anchors[podID(finding.Namespace, finding.Pod)] = true
+	}
+
+	// Only the first row of each pod carries its anchor so ids stay unique
+	seen := map[string]bool{}
+	tmpl.Funcs(template.FuncMap{
+		"podAnchor": func(namespace, pod string) string {
+			id := podID(namespace, pod)
+			if seen[id] {
+				return ""
+			}
+			seen[id] = true
+			return id
+		},
+		"podLink": func(namespace, pod string) string {
+			if id := podID(namespace, pod); anchors[id] {
+				return id
+			}
+			return ""
+		},
+	})
+
+	return tmpl.Execute(r.out, report)
+}
+
+// podID is the HTML anchor of a pod's findings.
+func podID(namespace, pod string) string {
+	return "pod-" + namespace + "-" + pod
+}
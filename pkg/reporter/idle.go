@@ -0,0 +1,80 @@
+package reporter
+
+import (
+	"fmt"
+	"sort"
+	"text/tabwriter"
+
+	"pod-limit-checker/pkg/analyzer"
+)
+
+// IdleReserved is the requested but unused capacity of one namespace,
+// summed over containers with usage metrics.
+type IdleReserved struct {
+	Namespace  string `json:"namespace" yaml:"namespace"`
+	Containers int    `json:"containers" yaml:"containers"`
+	CPU        string `json:"cpu" yaml:"cpu"`
+	Memory     string `json:"memory" yaml:"memory"`
+}
+
+type idleTotals struct {
+	containers int
+	cpuMilli   int64
+	memBytes   int64
+}
+
+// buildIdleReserved sums idle requests per namespace, largest idle CPU
+// first. Namespaces without idle capacity are omitted.
+func buildIdleReserved(results []analyzer.PodAnalysis) []IdleReserved {
+	totals := map[string]*idleTotals{}
+	for _, result := range results {
+		if result.IdleCPUMilli == 0 && result.IdleMemoryBytes == 0 {
+			continue
+		}
+		t, ok := totals[result.Namespace]
+		if !ok {
+			t = &idleTotals{}
+			totals[result.Namespace] = t
+		}
+		t.containers++
+		t.cpuMilli += result.IdleCPUMilli
+		t.memBytes += result.IdleMemoryBytes
+	}
+
+	namespaces := make([]string, 0, len(totals))
+	for ns := range totals {
+		namespaces = append(namespaces, ns)
+	}
+	sort.Slice(namespaces, func(i, j int) bool {
+		a, b := totals[namespaces[i]], totals[namespaces[j]]
+		if a.cpuMilli != b.cpuMilli {
+			return a.cpuMilli > b.cpuMilli
+		}
+		if a.memBytes != b.memBytes {
+			return a.memBytes > b.memBytes
+		}
+		return namespaces[i] < namespaces[j]
+	})
+
+	var idle []IdleReserved
+	for _, ns := range namespaces {
+		t := totals[ns]
+		idle = append(idle, IdleReserved{
+			Namespace:  ns,
+			Containers: t.containers,
+			CPU:        cpuString(t.cpuMilli),
+			Memory:     memoryString(t.memBytes),
+		})
+	}
+	return idle
+}
+
+func (r *Reporter) printIdleReserved(idle []IdleReserved) {
+	fmt.Fprintf(r.out, "\n💤 Idle reserved capacity (requested but unused):\n")
+	w := tabwriter.NewWriter(r.out, 0, 0, 3, ' ', 0)
+	fmt.Fprintf(w, "  NAMESPACE\tCONTAINERS\tCPU\tMEMORY\n")
+	for _, row := range idle {
+		fmt.Fprintf(w, "  %s\t%d\t%s\t%s\n", row.Namespace, row.Containers, row.CPU, row.Memory)
+	}
+	w.Flush()
+}
@@ -0,0 +1,49 @@
+package reporter
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"pod-limit-checker/pkg/analyzer"
+)
+
+func TestIdleReserved(t *testing.T) {
+	results := []analyzer.PodAnalysis{
+		{Namespace: "web", ContainerName: "app", IdleCPUMilli: 400, IdleMemoryBytes: 256 << 20},
+		{Namespace: "web", ContainerName: "proxy", IdleCPUMilli: 100},
+		{Namespace: "ml", ContainerName: "trainer", IdleCPUMilli: 1800, IdleMemoryBytes: 3 << 30},
+		// Same idle CPU as web, ordered by memory
+		{Namespace: "jobs", ContainerName: "worker", IdleCPUMilli: 500, IdleMemoryBytes: 1 << 30},
+		// Fully used or without metrics
+		{Namespace: "web", ContainerName: "sidecar"},
+		{Namespace: "cache", ContainerName: "redis"},
+	}
+	idle := buildIdleReserved(results)
+	want := []IdleReserved{
+		{Namespace: "ml", Containers: 1, CPU: "1800m", Memory: "3Gi"},
+		{Namespace: "jobs", Containers: 1, CPU: "500m", Memory: "1Gi"},
+		{Namespace: "web", Containers: 2, CPU: "500m", Memory: "256Mi"},
+	}
+	if !reflect.DeepEqual(idle, want) {
+		t.Errorf("idle reserved = %+v, want %+v", idle, want)
+	}
+
+	var out bytes.Buffer
+	r := NewReporter("table", &out)
+	r.printIdleReserved(idle)
+	wantTable := `
+💤 Idle reserved capacity (requested but unused):
+  NAMESPACE   CONTAINERS   CPU     MEMORY
+  ml          1            1800m   3Gi
+  jobs        1            500m    1Gi
+  web         2            500m    256Mi
+`
+	if out.String() != wantTable {
+		t.Errorf("table:\n%s\nwant:\n%s", out.String(), wantTable)
+	}
+
+	if idle := buildIdleReserved(results[4:]); idle != nil {
+		t.Errorf("idle reserved without idle capacity = %+v, want none", idle)
+	}
+}
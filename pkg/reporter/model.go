@@ -0,0 +1,356 @@
+package reporter
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"gopkg.in/yaml.v2"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	"pod-limit-checker/pkg/analyzer"
+	"pod-limit-checker/pkg/cost"
+)
+
+// Envelope identifiers for machine-readable reports. Bump the API version
+// whenever a field is removed or changes meaning; adding optional fields
+// is backwards compatible and does not require a bump.
+const (
+	ReportAPIVersion = "podlimitchecker.io/v1"
+	ReportKind       = "PodLimitReport"
+)
+
+// Report is the stable output model used for JSON and YAML output. It is
+// deliberately decoupled from analyzer.PodAnalysis so internal changes do
+// not break consumers.
+type Report struct {
+	APIVersion string         `json:"apiVersion" yaml:"apiVersion"`
+	Kind       string         `json:"kind" yaml:"kind"`
+	Metadata   RunMetadata    `json:"metadata" yaml:"metadata"`
+	Summary    Summary        `json:"summary" yaml:"summary"`
+	Rollups    *Rollups       `json:"rollups,omitempty" yaml:"rollups,omitempty"`
+	Cost       *cost.Estimate `json:"cost,omitempty" yaml:"cost,omitempty"`
+	Nodes      []NodeFinding  `json:"nodes,omitempty" yaml:"nodes,omitempty"`
+	// IdleReserved covers every analyzed container, not just the findings
+	IdleReserved []IdleReserved `json:"idleReserved,omitempty" yaml:"idleReserved,omitempty"`
+	// HPAChanges covers every analyzed container, not just the findings
+	HPAChanges []HPAChange `json:"hpaChanges,omitempty" yaml:"hpaChanges,omitempty"`
+	Findings   []Finding   `json:"findings" yaml:"findings"`
+}
+
+// RunMetadata describes the run that produced a report.
+type RunMetadata struct {
+	Timestamp   time.Time         `json:"timestamp" yaml:"timestamp"`
+	Cluster     string            `json:"cluster,omitempty" yaml:"cluster,omitempty"`
+	ToolVersion string            `json:"toolVersion" yaml:"toolVersion"`
+	Flags       map[string]string `json:"flags,omitempty" yaml:"flags,omitempty"`
+}
+
+// Summary holds cluster-wide counters over the reported findings.
+type Summary struct {
+	TotalContainers int `json:"totalContainers" yaml:"totalContainers"`
+	HighRisk        int `json:"highRisk" yaml:"highRisk"`
+	MediumRisk      int `json:"mediumRisk" yaml:"mediumRisk"`
+	LowRisk         int `json:"lowRisk" yaml:"lowRisk"`
+	NoLimits        int `json:"noLimits" yaml:"noLimits"`
+	NoRequests      int `json:"noRequests" yaml:"noRequests"`
+	WithUsageData   int `json:"withUsageData" yaml:"withUsageData"`
+}
+
+// Finding is the per-container result of the analysis.
+type Finding struct {
+	Namespace      string          `json:"namespace" yaml:"namespace"`
+	Pod            string          `json:"pod" yaml:"pod"`
+	Container      string          `json:"container" yaml:"container"`
+	Node           string          `json:"node,omitempty" yaml:"node,omitempty"`
+	WorkloadKind   string          `json:"workloadKind,omitempty" yaml:"workloadKind,omitempty"`
+	WorkloadName   string          `json:"workloadName,omitempty" yaml:"workloadName,omitempty"`
+	Age            string          `json:"age" yaml:"age"`
+	RiskLevel      string          `json:"riskLevel" yaml:"riskLevel"`
+	HasLimits      bool            `json:"hasLimits" yaml:"hasLimits"`
+	HasRequests    bool            `json:"hasRequests" yaml:"hasRequests"`
+	LimitRatios    *LimitRatios    `json:"limitRequestRatios,omitempty" yaml:"limitRequestRatios,omitempty"`
+	QoS            *QoS            `json:"qos,omitempty" yaml:"qos,omitempty"`
+	Limits         ResourceValues  `json:"limits" yaml:"limits"`
+	Requests       *ResourceValues `json:"requests,omitempty" yaml:"requests,omitempty"`
+	Usage          *ResourceValues `json:"usage,omitempty" yaml:"usage,omitempty"`
+	IdleReserved   *ResourceValues `json:"idleReserved,omitempty" yaml:"idleReserved,omitempty"`
+	Recommendation *Recommendation `json:"recommendation,omitempty" yaml:"recommendation,omitempty"`
+	Runtime        *Runtime        `json:"runtime,omitempty" yaml:"runtime,omitempty"`
+	VPA            *VPAFinding     `json:"vpa,omitempty" yaml:"vpa,omitempty"`
+	HPA            []HPAImpact     `json:"hpa,omitempty" yaml:"hpa,omitempty"`
+	Suggestions    []string        `json:"suggestions" yaml:"suggestions"`
+}
+
+// LimitRatios holds limit-to-request ratios per resource. A resource
+// without a limit has no ratio and is omitted.
+type LimitRatios struct {
+	CPU    float64 `json:"cpu,omitempty" yaml:"cpu,omitempty"`
+	Memory float64 `json:"memory,omitempty" yaml:"memory,omitempty"`
+}
+
+// QoS describes the pod's QoS class now, after applying recommendations,
+// and as required by policy.
+type QoS struct {
+	Current     string `json:"current" yaml:"current"`
+	Recommended string `json:"recommended,omitempty" yaml:"recommended,omitempty"`
+	Required    string `json:"required,omitempty" yaml:"required,omitempty"`
+	Violation   bool   `json:"violation" yaml:"violation"`
+}
+
+// ResourceValues holds quantities in canonical Kubernetes string form
+// (e.g. "250m", "128Mi") so they round-trip through resource.ParseQuantity.
+type ResourceValues struct {
+	CPU              string `json:"cpu,omitempty" yaml:"cpu,omitempty"`
+	Memory           string `json:"memory,omitempty" yaml:"memory,omitempty"`
+	EphemeralStorage string `json:"ephemeralStorage,omitempty" yaml:"ephemeralStorage,omitempty"`
+	// Extended holds extended resources (e.g. nvidia.com/gpu) and hugepages
+	// keyed by resource name.
+	Extended map[string]string `json:"extended,omitempty" yaml:"extended,omitempty"`
+}
+
+// Runtime is the language runtime detected from the container spec with
+// its memory setting and the setting recommended for the memory limit.
+type Runtime struct {
+	Name               string `json:"name" yaml:"name"`
+	Setting            string `json:"setting,omitempty" yaml:"setting,omitempty"`
+	RecommendedSetting string `json:"recommendedSetting,omitempty" yaml:"recommendedSetting,omitempty"`
+}
+
+// VPAFinding is the recommendation of the VerticalPodAutoscaler targeting
+// the container's workload. Bounds are empty until the VPA has produced a
+// recommendation.
+type VPAFinding struct {
+	Name       string         `json:"name" yaml:"name"`
+	UpdateMode string         `json:"updateMode" yaml:"updateMode"`
+	Target     ResourceValues `json:"target" yaml:"target"`
+	LowerBound ResourceValues `json:"lowerBound" yaml:"lowerBound"`
+	UpperBound ResourceValues `json:"upperBound" yaml:"upperBound"`
+}
+
+// Recommendation holds the usage-based limits and requests for a container.
+type Recommendation struct {
+	Limits   ResourceValues `json:"limits" yaml:"limits"`
+	Requests ResourceValues `json:"requests" yaml:"requests"`
+}
+
+// LoadReport reads a JSON or YAML report written by a previous run.
+func LoadReport(path string) (*Report, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read report: %v", err)
+	}
+
+	report := &Report{}
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("{")) {
+		err = json.Unmarshal(data, report)
+	} else {
+		err = yaml.Unmarshal(data, report)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse report %s: %v", path, err)
+	}
+	if report.APIVersion != ReportAPIVersion || report.Kind != ReportKind {
+		return nil, fmt.Errorf("%s is not a %s %s", path, ReportAPIVersion, ReportKind)
+	}
+
+	return report, nil
+}
+
+func (r *Reporter) buildReport(results []analyzer.PodAnalysis) Report {
+	report := Report{
+		APIVersion:   ReportAPIVersion,
+		Kind:         ReportKind,
+		Metadata:     r.metadata,
+		Summary:      summarize(results),
+		Rollups:      r.rollups,
+		Cost:         r.costEstimate,
+		Nodes:        r.nodeFindings(),
+		IdleReserved: r.idle,
+		HPAChanges:   r.hpaChanges,
+		Findings:     make([]Finding, 0, len(results)),
+	}
+	if report.Metadata.Timestamp.IsZero() {
+		report.Metadata.Timestamp = time.Now().UTC()
+	}
+
+	for _, result := range results {
+		report.Findings = append(report.Findings, newFinding(result))
+	}
+
+	return report
+}
+
+func (r *Reporter) nodeFindings() []NodeFinding {
+	if r.nodes == nil {
+		return nil
+	}
+	findings := make([]NodeFinding, 0, len(r.nodes))
+	for _, node := range r.nodes {
+		findings = append(findings, newNodeFinding(node))
+	}
+	return findings
+}
+
+func newFinding(result analyzer.PodAnalysis) Finding {
+	finding := Finding{
+		Namespace:    result.Namespace,
+		Pod:          result.PodName,
+		Container:    result.ContainerName,
+		Node:         result.NodeName,
+		WorkloadKind: result.WorkloadKind,
+		WorkloadName: result.WorkloadName,
+		Age:          result.Age,
+		RiskLevel:    result.RiskLevel,
+		HasLimits:    result.HasLimits,
+		HasRequests:  result.HasRequests,
+		HPA:          newHPAImpacts(result.HPAImpacts),
+		Limits:       resourceValuesFromList(result.CurrentLimits),
+		Suggestions:  result.Suggestions,
+	}
+	if finding.Suggestions == nil {
+		finding.Suggestions = []string{}
+	}
+
+	if result.QoSClass != "" {
+		finding.QoS = &QoS{
+			Current:     result.QoSClass,
+			Recommended: result.RecommendedQoSClass,
+			Required:    result.RequiredQoSClass,
+			Violation:   result.QoSViolation,
+		}
+	}
+
+	if len(result.CurrentRequests) > 0 {
+		requests := resourceValuesFromList(result.CurrentRequests)
+		finding.Requests = &requests
+	}
+
+	if result.CPULimitRatio > 0 || result.MemoryLimitRatio > 0 {
+		finding.LimitRatios = &LimitRatios{CPU: result.CPULimitRatio, Memory: result.MemoryLimitRatio}
+	}
+
+	if result.CurrentUsage != nil {
+		finding.Usage = &ResourceValues{
+			CPU:              quantityString(result.CurrentUsage.CPU),
+			Memory:           quantityString(result.CurrentUsage.Memory),
+			EphemeralStorage: quantityString(result.CurrentUsage.EphemeralStorage),
+		}
+	}
+
+	if result.IdleCPUMilli > 0 || result.IdleMemoryBytes > 0 {
+		finding.IdleReserved = &ResourceValues{
+			CPU:    cpuString(result.IdleCPUMilli),
+			Memory: memoryString(result.IdleMemoryBytes),
+		}
+	}
+
+	if result.RecommendedCPULimit != "" && result.RecommendedMemoryLimit != "" {
+		finding.Recommendation = &Recommendation{
+			Limits: ResourceValues{
+				CPU:              result.RecommendedCPULimit,
+				Memory:           result.RecommendedMemoryLimit,
+				EphemeralStorage: result.RecommendedEphemeralStorageLimit,
+			},
+			Requests: ResourceValues{
+				CPU:              result.RecommendedCPURequest,
+				Memory:           result.RecommendedMemoryRequest,
+				EphemeralStorage: result.RecommendedEphemeralStorageRequest,
+			},
+		}
+	}
+
+	if result.Runtime != "" {
+		finding.Runtime = &Runtime{
+			Name:               result.Runtime,
+			Setting:            result.RuntimeSetting,
+			RecommendedSetting: result.RecommendedRuntimeSetting,
+		}
+	}
+
+	if vpa := result.VPA; vpa != nil {
+		finding.VPA = &VPAFinding{
+			Name:       vpa.Name,
+			UpdateMode: vpa.UpdateMode,
+			Target:     resourceValuesFromList(vpa.Target),
+			LowerBound: resourceValuesFromList(vpa.LowerBound),
+			UpperBound: resourceValuesFromList(vpa.UpperBound),
+		}
+	}
+
+	return finding
+}
+
+func resourceValuesFromList(list v1.ResourceList) ResourceValues {
+	var values ResourceValues
+	if cpu, ok := list[v1.ResourceCPU]; ok {
+		values.CPU = cpu.String()
+	}
+	if mem, ok := list[v1.ResourceMemory]; ok {
+		values.Memory = mem.String()
+	}
+	if eph, ok := list[v1.ResourceEphemeralStorage]; ok {
+		values.EphemeralStorage = eph.String()
+	}
+	for name, quantity := range list {
+		if !analyzer.IsSpecialResource(name) {
+			continue
+		}
+		if values.Extended == nil {
+			values.Extended = map[string]string{}
+		}
+		values.Extended[string(name)] = quantity.String()
+	}
+	return values
+}
+
+// specialResourceNames returns the extended and hugepages resources of a
+// resource list in name order.
+func specialResourceNames(list v1.ResourceList) []v1.ResourceName {
+	var names []v1.ResourceName
+	for name := range list {
+		if analyzer.IsSpecialResource(name) {
+			names = append(names, name)
+		}
+	}
+	sort.Slice(names, func(i, j int) bool { return names[i] < names[j] })
+	return names
+}
+
+func quantityString(q *resource.Quantity) string {
+	if q == nil {
+		return ""
+	}
+	return q.String()
+}
+
+func summarize(results []analyzer.PodAnalysis) Summary {
+	summary := Summary{TotalContainers: len(results)}
+
+	for _, result := range results {
+		switch result.RiskLevel {
+		case "HIGH":
+			summary.HighRisk++
+		case "MEDIUM":
+			summary.MediumRisk++
+		case "LOW":
+			summary.LowRisk++
+		}
+		if !result.HasLimits {
+			summary.NoLimits++
+		}
+		if !result.HasRequests {
+			summary.NoRequests++
+		}
+		if result.CurrentUsage != nil && result.CurrentUsage.CPU != nil && result.CurrentUsage.Memory != nil {
+			summary.WithUsageData++
+		}
+	}
+
+	return summary
+}
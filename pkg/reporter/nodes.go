@@ -0,0 +1,154 @@
+package reporter
+
+import (
+	"fmt"
+	"strings"
+	"text/tabwriter"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	"pod-limit-checker/pkg/analyzer"
+)
+
+// NodeFinding is the per-node overcommit result.
+type NodeFinding struct {
+	Name          string             `json:"name" yaml:"name"`
+	CPU           NodeResource       `json:"cpu" yaml:"cpu"`
+	Memory        NodeResource       `json:"memory" yaml:"memory"`
+	Pods          int                `json:"pods" yaml:"pods"`
+	UnboundedPods int                `json:"unboundedPods" yaml:"unboundedPods"`
+	Overcommitted bool               `json:"overcommitted" yaml:"overcommitted"`
+	EvictionProne bool               `json:"evictionProne" yaml:"evictionProne"`
+	Reasons       []string           `json:"reasons" yaml:"reasons"`
+	TopPods       []NodePodReference `json:"topPods" yaml:"topPods"`
+}
+
+// NodeResource compares one resource's commitments with node allocatable.
+// Percentages are relative to allocatable.
+type NodeResource struct {
+	Allocatable     string   `json:"allocatable" yaml:"allocatable"`
+	Requests        string   `json:"requests" yaml:"requests"`
+	Limits          string   `json:"limits" yaml:"limits"`
+	Usage           string   `json:"usage,omitempty" yaml:"usage,omitempty"`
+	RequestsPercent float64  `json:"requestsPercent" yaml:"requestsPercent"`
+	LimitsPercent   float64  `json:"limitsPercent" yaml:"limitsPercent"`
+	UsagePercent    *float64 `json:"usagePercent,omitempty" yaml:"usagePercent,omitempty"`
+}
+
+// NodePodReference points at a pod contributing to a node's commitments;
+// namespace and pod match the corresponding findings.
+type NodePodReference struct {
+	Namespace string `json:"namespace" yaml:"namespace"`
+	Pod       string `json:"pod" yaml:"pod"`
+	CPULimit  string `json:"cpuLimit" yaml:"cpuLimit"`
+	MemLimit  string `json:"memoryLimit" yaml:"memoryLimit"`
+	Unbounded bool   `json:"unbounded" yaml:"unbounded"`
+}
+
+func newNodeFinding(node analyzer.NodeAnalysis) NodeFinding {
+	finding := NodeFinding{
+		Name:          node.Name,
+		CPU:           nodeResource(node.AllocatableCPU, node.RequestsCPU, node.LimitsCPU, node.UsageCPU, cpuString),
+		Memory:        nodeResource(node.AllocatableMemory, node.RequestsMemory, node.LimitsMemory, node.UsageMemory, memoryString),
+		Pods:          node.PodCount,
+		UnboundedPods: node.UnboundedPods,
+		Overcommitted: node.Overcommitted,
+		EvictionProne: node.EvictionProne,
+		Reasons:       node.Reasons,
+		TopPods:       []NodePodReference{},
+	}
+	if finding.Reasons == nil {
+		finding.Reasons = []string{}
+	}
+
+	for _, c := range node.TopContributors {
+		finding.TopPods = append(finding.TopPods, NodePodReference{
+			Namespace: c.Namespace,
+			Pod:       c.PodName,
+			CPULimit:  cpuString(c.LimitsCPU),
+			MemLimit:  memoryString(c.LimitsMemory),
+			Unbounded: c.Unbounded,
+		})
+	}
+
+	return finding
+}
+
+func nodeResource(allocatable, requests, limits int64, usage *int64, format func(int64) string) NodeResource {
+	res := NodeResource{
+		Allocatable:     format(allocatable),
+		Requests:        format(requests),
+		Limits:          format(limits),
+		RequestsPercent: percentOf(requests, allocatable),
+		LimitsPercent:   percentOf(limits, allocatable),
+	}
+	if usage != nil {
+		res.Usage = format(*usage)
+		pct := percentOf(*usage, allocatable)
+		res.UsagePercent = &pct
+	}
+	return res
+}
+
+func cpuString(milli int64) string {
+	return resource.NewMilliQuantity(milli, resource.DecimalSI).String()
+}
+
+func memoryString(bytes int64) string {
+	return resource.NewQuantity(bytes, resource.BinarySI).String()
+}
+
+func percentOf(value, total int64) float64 {
+	if total <= 0 {
+		return 0
+	}
+	return float64(value) / float64(total) * 100
+}
+
+func (r *Reporter) printNodes(nodes []NodeFinding) {
+	fmt.Fprintf(r.out, "\n🖥️  Nodes:\n")
+
+	w := tabwriter.NewWriter(r.out, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(w, "  NODE\tPODS\tCPU REQ%\tCPU LIM%\tCPU USE%\tMEM REQ%\tMEM LIM%\tMEM USE%\tSTATUS")
+	for _, node := range nodes {
+		status := "🟢OK"
+		if node.Overcommitted || node.EvictionProne {
+			status = "🔴" + strings.Join(node.Reasons, ", ")
+		}
+		fmt.Fprintf(w, "  %s\t%d\t%.0f%%\t%.0f%%\t%s\t%.0f%%\t%.0f%%\t%s\t%s\n",
+			node.Name,
+			node.Pods,
+			node.CPU.RequestsPercent,
+			node.CPU.LimitsPercent,
+			optionalPercent(node.CPU.UsagePercent),
+			node.Memory.RequestsPercent,
+			node.Memory.LimitsPercent,
+			optionalPercent(node.Memory.UsagePercent),
+			status,
+		)
+	}
+	w.Flush()
+
+	for _, node := range nodes {
+		if !node.Overcommitted && !node.EvictionProne {
+			continue
+		}
+		fmt.Fprintf(r.out, "\n  %s top contributors (%d of %d pods without full limits):\n",
+			node.Name, node.UnboundedPods, node.Pods)
+		for _, pod := range node.TopPods {
+			unbounded := ""
+			if pod.Unbounded {
+				unbounded = " ❌ missing limits"
+			}
+			fmt.Fprintf(r.out, "    %s/%s: CPU limit %s, memory limit %s%s\n",
+				pod.Namespace, pod.Pod, pod.CPULimit, pod.MemLimit, unbounded)
+		}
+	}
+}
+
+func optionalPercent(pct *float64) string {
+	if pct == nil {
+		return "-"
+	}
+	return fmt.Sprintf("%.0f%%", *pct)
+}
@@ -3,26 +3,37 @@ package reporter
 import (
 	"encoding/json"
 	"fmt"
-	"os"
+	"io"
 	"strings"
 	"text/tabwriter"
 
 	"gopkg.in/yaml.v2"
 
 	"pod-limit-checker/pkg/analyzer"
+	"pod-limit-checker/pkg/cost"
 
 	v1 "k8s.io/api/core/v1"
 )
 
 type Reporter struct {
 	format       string
+	out          io.Writer
 	verbose      bool
 	showExamples bool
 	quiet        bool
+	metadata     RunMetadata
+	ownerLabel   string
+	rollupSort   string
+	rollups      *Rollups
+	costEstimate *cost.Estimate
+	nodes        []analyzer.NodeAnalysis
+	idle         []IdleReserved
+	hpaChanges   []HPAChange
 }
 
-func NewReporter(format string) *Reporter {
-	return &Reporter{format: format, showExamples: true}
+// NewReporter creates a reporter that renders the given format to out.
+func NewReporter(format string, out io.Writer) *Reporter {
+	return &Reporter{format: format, out: out, showExamples: true}
 }
 
 func (r *Reporter) SetQuiet(quiet bool) {
@@ -37,23 +48,40 @@ func (r *Reporter) SetShowExamples(showExamples bool) {
 	r.showExamples = showExamples
 }
 
+// SetMetadata sets the run metadata embedded in JSON and YAML reports.
+func (r *Reporter) SetMetadata(metadata RunMetadata) {
+	r.metadata = metadata
+}
+
+// SetRollup enables the namespace and owner rollup view. Containers are
+// grouped by the pod label ownerLabel and rows are ordered by sortBy
+// (RollupSortWorst or RollupSortName).
+func (r *Reporter) SetRollup(ownerLabel, sortBy string) {
+	r.ownerLabel = ownerLabel
+	r.rollupSort = sortBy
+}
+
+// SetCostEstimate attaches a cost estimate to the report.
+func (r *Reporter) SetCostEstimate(estimate *cost.Estimate) {
+	r.costEstimate = estimate
+}
+
+// SetNodeAnalysis adds the node overcommit view to the report.
+func (r *Reporter) SetNodeAnalysis(nodes []analyzer.NodeAnalysis) {
+	r.nodes = nodes
+}
+
 func (r *Reporter) GenerateReport(results []analyzer.PodAnalysis, showAll bool) error {
-	// Filter results if not showing all
-	filteredResults := results
-	if !showAll {
-		filteredResults = []analyzer.PodAnalysis{}
-		for _, result := range results {
-			if !result.HasLimits || result.RiskLevel == "HIGH" || result.RiskLevel == "MEDIUM" {
-				filteredResults = append(filteredResults, result)
-			}
-		}
-	}
+	r.aggregate(results)
+	filteredResults := filterResults(results, showAll)
 
 	switch strings.ToLower(r.format) {
 	case "json":
 		return r.generateJSON(filteredResults)
 	case "yaml":
 		return r.generateYAML(filteredResults)
+	case "html":
+		return r.generateHTML(filteredResults)
 	case "table":
 		fallthrough
 	default:
@@ -61,26 +89,61 @@ func (r *Reporter) GenerateReport(results []analyzer.PodAnalysis, showAll bool)
 	}
 }
 
+// BuildReport returns the report that GenerateReport renders as JSON or
+// YAML, for sinks that consume it directly.
+func (r *Reporter) BuildReport(results []analyzer.PodAnalysis, showAll bool) Report {
+	r.aggregate(results)
+	return r.buildReport(filterResults(results, showAll))
+}
+
+// aggregate computes the report-level views. Rollups always cover every
+// container so compliance is meaningful; over-requested containers usually
+// have limits, so idle capacity is also taken from the unfiltered results.
+func (r *Reporter) aggregate(results []analyzer.PodAnalysis) {
+	r.rollups = nil
+	if r.ownerLabel != "" {
+		r.rollups = buildRollups(results, r.ownerLabel, r.rollupSort)
+	}
+	r.idle = buildIdleReserved(results)
+	r.hpaChanges = buildHPAChanges(results)
+}
+
+// filterResults keeps the containers worth reporting: those without limits
+// or at HIGH or MEDIUM risk, unless showAll is set.
+func filterResults(results []analyzer.PodAnalysis, showAll bool) []analyzer.PodAnalysis {
+	if showAll {
+		return results
+	}
+	filtered := []analyzer.PodAnalysis{}
+	for _, result := range results {
+		if !result.HasLimits || result.RiskLevel == "HIGH" || result.RiskLevel == "MEDIUM" {
+			filtered = append(filtered, result)
+		}
+	}
+	return filtered
+}
+
 func (r *Reporter) generateTable(results []analyzer.PodAnalysis) error {
 	if len(results) == 0 {
-		fmt.Println("✅ All pods have proper resource limits configured.")
+		fmt.Fprintln(r.out, "✅ All pods have proper resource limits configured.")
+		r.printViews()
 		return nil
 	}
 
-	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+	w := tabwriter.NewWriter(r.out, 0, 0, 3, ' ', 0)
 
 	if r.verbose {
 		// Verbose mode - detailed output
 		for i, result := range results {
 			if i > 0 {
-				fmt.Println()
+				fmt.Fprintln(r.out)
 			}
 			r.printPodDetails(&result, w)
 		}
 	} else {
 		// Compact mode - just the table
-		fmt.Fprintln(w, "NAMESPACE\tPOD\tCONTAINER\tAGE\tLIMITS\tREQUESTS\tRISK\tSUGGESTIONS")
-		fmt.Fprintln(w, "---------\t---\t---------\t---\t------\t--------\t----\t----------")
+		fmt.Fprintln(w, "NAMESPACE\tPOD\tCONTAINER\tAGE\tLIMITS\tREQUESTS\tQOS\tRISK\tSUGGESTIONS")
+		fmt.Fprintln(w, "---------\t---\t---------\t---\t------\t--------\t---\t----\t----------")
 
 		for _, result := range results {
 			limitsStr := "None"
@@ -92,6 +155,13 @@ func (r *Reporter) generateTable(results []analyzer.PodAnalysis) error {
 				if mem, ok := result.CurrentLimits[v1.ResourceMemory]; ok {
 					limitParts = append(limitParts, fmt.Sprintf("Mem:%s", mem.String()))
 				}
+				if eph, ok := result.CurrentLimits[v1.ResourceEphemeralStorage]; ok {
+					limitParts = append(limitParts, fmt.Sprintf("Eph:%s", eph.String()))
+				}
+				for _, name := range specialResourceNames(result.CurrentLimits) {
+					quantity := result.CurrentLimits[name]
+					limitParts = append(limitParts, fmt.Sprintf("%s:%s", name, quantity.String()))
+				}
 				limitsStr = strings.Join(limitParts, ", ")
 			}
 
@@ -119,13 +189,14 @@ func (r *Reporter) generateTable(results []analyzer.PodAnalysis) error {
 				riskIcon = "🟢"
 			}
 
-			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s%s\t%s\n",
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s%s\t%s\n",
 				result.Namespace,
 				result.PodName,
 				result.ContainerName,
 				result.Age,
 				limitsStr,
 				requestsStr,
+				qosString(result),
 				riskIcon,
 				result.RiskLevel,
 				suggestion,
@@ -138,51 +209,95 @@ func (r *Reporter) generateTable(results []analyzer.PodAnalysis) error {
 	// Print summary
 	r.printSummary(results)
 
+	r.printViews()
+
 	// Print examples for pods without limits (if requested and we have usage data)
 	if r.showExamples {
 		r.printSpecificExamples(results)
 	}
 
 	if !r.verbose && len(results) > 0 {
-		fmt.Printf("\n💡 Tip: Use --verbose flag to see detailed recommendations\n")
+		fmt.Fprintf(r.out, "\n💡 Tip: Use --verbose flag to see detailed recommendations\n")
 	}
 
 	return nil
 }
 
+// printViews prints the report-level views. They cover every container, so
+// they are printed even when no container is worth listing.
+func (r *Reporter) printViews() {
+	if r.rollups != nil {
+		r.printRollups(r.rollups)
+	}
+
+	if len(r.idle) > 0 {
+		r.printIdleReserved(r.idle)
+	}
+
+	if len(r.hpaChanges) > 0 {
+		r.printHPAChanges(r.hpaChanges)
+	}
+
+	if r.costEstimate != nil {
+		r.printCostEstimate(r.costEstimate)
+	}
+
+	if r.nodes != nil {
+		r.printNodes(r.nodeFindings())
+	}
+}
+
 func (r *Reporter) printPodDetails(result *analyzer.PodAnalysis, w *tabwriter.Writer) {
-	fmt.Printf("📦 Pod: %s/%s\n", result.Namespace, result.PodName)
-	fmt.Printf("  Container: %s (Age: %s)\n", result.ContainerName, result.Age)
+	fmt.Fprintf(r.out, "📦 Pod: %s/%s\n", result.Namespace, result.PodName)
+	fmt.Fprintf(r.out, "  Container: %s (Age: %s)\n", result.ContainerName, result.Age)
 
 	// Current configuration
-	fmt.Printf("  Current configuration:\n")
+	fmt.Fprintf(r.out, "  Current configuration:\n")
 	if len(result.CurrentLimits) > 0 {
-		fmt.Printf("    Limits:\n")
+		fmt.Fprintf(r.out, "    Limits:\n")
 		if cpu, ok := result.CurrentLimits[v1.ResourceCPU]; ok {
-			fmt.Printf("      CPU: %s\n", cpu.String())
+			fmt.Fprintf(r.out, "      CPU: %s\n", cpu.String())
 		} else {
-			fmt.Printf("      CPU: ❌ Not set\n")
+			fmt.Fprintf(r.out, "      CPU: ❌ Not set\n")
 		}
 		if mem, ok := result.CurrentLimits[v1.ResourceMemory]; ok {
-			fmt.Printf("      Memory: %s\n", mem.String())
+			fmt.Fprintf(r.out, "      Memory: %s\n", mem.String())
 		} else {
-			fmt.Printf("      Memory: ❌ Not set\n")
+			fmt.Fprintf(r.out, "      Memory: ❌ Not set\n")
+		}
+		if eph, ok := result.CurrentLimits[v1.ResourceEphemeralStorage]; ok {
+			fmt.Fprintf(r.out, "      Ephemeral storage: %s\n", eph.String())
+		}
+		for _, name := range specialResourceNames(result.CurrentLimits) {
+			quantity := result.CurrentLimits[name]
+			fmt.Fprintf(r.out, "      %s: %s\n", name, quantity.String())
 		}
 	} else {
-		fmt.Printf("    Limits: ❌ None\n")
+		fmt.Fprintf(r.out, "    Limits: ❌ None\n")
 	}
 
 	if result.HasRequests {
-		fmt.Printf("    Requests: ✅ Set\n")
+		fmt.Fprintf(r.out, "    Requests: ✅ Set\n")
 	} else {
-		fmt.Printf("    Requests: ⚠️ Not set\n")
+		fmt.Fprintf(r.out, "    Requests: ⚠️ Not set\n")
+	}
+	if result.CPULimitRatio > 0 || result.MemoryLimitRatio > 0 {
+		fmt.Fprintf(r.out, "    Limit/request ratio: CPU %s, memory %s\n",
+			ratioString(result.CPULimitRatio), ratioString(result.MemoryLimitRatio))
 	}
 
 	// Current usage if available
 	if result.CurrentUsage != nil && result.CurrentUsage.CPU != nil && result.CurrentUsage.Memory != nil {
-		fmt.Printf("  Current usage:\n")
-		fmt.Printf("    CPU: %s\n", result.CurrentUsage.CPU.String())
-		fmt.Printf("    Memory: %s\n", result.CurrentUsage.Memory.String())
+		fmt.Fprintf(r.out, "  Current usage:\n")
+		fmt.Fprintf(r.out, "    CPU: %s\n", result.CurrentUsage.CPU.String())
+		fmt.Fprintf(r.out, "    Memory: %s\n", result.CurrentUsage.Memory.String())
+	}
+	if result.IdleCPUMilli > 0 || result.IdleMemoryBytes > 0 {
+		fmt.Fprintf(r.out, "    Idle reserved: CPU %s, memory %s\n",
+			cpuString(result.IdleCPUMilli), memoryString(result.IdleMemoryBytes))
+	}
+	if result.CurrentUsage != nil && result.CurrentUsage.EphemeralStorage != nil {
+		fmt.Fprintf(r.out, "  Ephemeral storage usage: %s\n", result.CurrentUsage.EphemeralStorage.String())
 	}
 
 	// Risk level
@@ -195,68 +310,111 @@ func (r *Reporter) printPodDetails(result *analyzer.PodAnalysis, w *tabwriter.Wr
 	case "LOW":
 		riskIcon = "🟢"
 	}
-	fmt.Printf("  Risk level: %s%s\n", riskIcon, result.RiskLevel)
+	fmt.Fprintf(r.out, "  Risk level: %s%s\n", riskIcon, result.RiskLevel)
+
+	// QoS class, now and after applying recommendations
+	fmt.Fprintf(r.out, "  QoS class: %s", result.QoSClass)
+	if result.RecommendedQoSClass != "" && result.RecommendedQoSClass != result.QoSClass {
+		fmt.Fprintf(r.out, " (%s after recommendations)", result.RecommendedQoSClass)
+	}
+	if result.RequiredQoSClass != "" {
+		fmt.Fprintf(r.out, ", policy requires %s", result.RequiredQoSClass)
+	}
+	fmt.Fprintln(r.out)
 
 	// Suggestions
 	if len(result.Suggestions) > 0 {
-		fmt.Printf("  Suggestions:\n")
+		fmt.Fprintf(r.out, "  Suggestions:\n")
 		for _, suggestion := range result.Suggestions {
-			fmt.Printf("    - %s\n", suggestion)
+			fmt.Fprintf(r.out, "    - %s\n", suggestion)
 		}
 	}
 
 	// Specific recommendations if we have usage data
 	if result.RecommendedCPULimit != "" && result.RecommendedMemoryLimit != "" {
-		fmt.Printf("  Recommended limits (based on current usage):\n")
-		fmt.Printf("    CPU: %s (request: %s)\n",
+		fmt.Fprintf(r.out, "  Recommended limits (based on current usage):\n")
+		fmt.Fprintf(r.out, "    CPU: %s (request: %s)\n",
 			result.RecommendedCPULimit, result.RecommendedCPURequest)
-		fmt.Printf("    Memory: %s (request: %s)\n",
+		fmt.Fprintf(r.out, "    Memory: %s (request: %s)\n",
 			result.RecommendedMemoryLimit, result.RecommendedMemoryRequest)
+		if result.RecommendedEphemeralStorageLimit != "" {
+			fmt.Fprintf(r.out, "    Ephemeral storage: %s (request: %s)\n",
+				result.RecommendedEphemeralStorageLimit, result.RecommendedEphemeralStorageRequest)
+		}
 
 		if result.ExampleYAML != "" {
-			fmt.Printf("  Example YAML to add to container spec:\n")
-			fmt.Printf("%s\n", result.ExampleYAML)
+			fmt.Fprintf(r.out, "  Example YAML to add to container spec:\n")
+			fmt.Fprintf(r.out, "%s\n", result.ExampleYAML)
 		}
 	}
-}
 
-func (r *Reporter) printSummary(results []analyzer.PodAnalysis) {
-	fmt.Printf("\n📊 Summary:\n")
-	fmt.Printf("  Total containers analyzed: %d\n", len(results))
-
-	highRisk := 0
-	mediumRisk := 0
-	lowRisk := 0
-	noLimits := 0
-	noRequests := 0
-	withUsageData := 0
-
-	for _, result := range results {
-		switch result.RiskLevel {
-		case "HIGH":
-			highRisk++
-		case "MEDIUM":
-			mediumRisk++
-		case "LOW":
-			lowRisk++
+	// Language runtime memory setting
+	if result.Runtime != "" {
+		setting := result.RuntimeSetting
+		if setting == "" {
+			setting = "no memory setting"
 		}
-		if !result.HasLimits {
-			noLimits++
+		fmt.Fprintf(r.out, "  Runtime: %s (%s)", result.Runtime, setting)
+		if result.RecommendedRuntimeSetting != "" {
+			fmt.Fprintf(r.out, ", recommended %s", result.RecommendedRuntimeSetting)
 		}
-		if !result.HasRequests {
-			noRequests++
+		fmt.Fprintln(r.out)
+	}
+
+	// HPA scaling points, now and with the recommended requests
+	for _, impact := range result.HPAImpacts {
+		fmt.Fprintf(r.out, "  HPA %s: %d%% %s, scales at %s per container", impact.HPA, impact.TargetUtilization, impact.Resource, impact.CurrentThreshold)
+		if impact.ScalesDifferently {
+			fmt.Fprintf(r.out, " (%s after recommendations)", impact.RecommendedThreshold)
+		}
+		fmt.Fprintln(r.out)
+	}
+
+	// The VPA's view next to ours
+	if vpa := result.VPA; vpa != nil {
+		fmt.Fprintf(r.out, "  VPA %s (%s):\n", vpa.Name, vpa.UpdateMode)
+		if len(vpa.Target) == 0 {
+			fmt.Fprintf(r.out, "    No recommendation yet\n")
+			return
 		}
-		if result.CurrentUsage != nil && result.CurrentUsage.CPU != nil && result.CurrentUsage.Memory != nil {
-			withUsageData++
+		for _, name := range []v1.ResourceName{v1.ResourceCPU, v1.ResourceMemory} {
+			fmt.Fprintf(r.out, "    %s: target %s (lower %s, upper %s)\n", name,
+				listValue(vpa.Target, name), listValue(vpa.LowerBound, name), listValue(vpa.UpperBound, name))
 		}
 	}
+}
 
-	fmt.Printf("  🔴 High risk (no limits): %d\n", highRisk)
-	fmt.Printf("  🟡 Medium risk: %d\n", mediumRisk)
-	fmt.Printf("  🟢 Low risk: %d\n", lowRisk)
-	fmt.Printf("  ❌ No limits set: %d\n", noLimits)
-	fmt.Printf("  ⚠️  No requests set: %d\n", noRequests)
-	fmt.Printf("  📊 With usage metrics: %d\n", withUsageData)
+func listValue(list v1.ResourceList, name v1.ResourceName) string {
+	if quantity, ok := list[name]; ok {
+		return quantity.String()
+	}
+	return "-"
+}
+
+// qosString shows the current QoS class, with the projected class when the
+// recommendations would change it and a marker when policy is violated.
+func qosString(result analyzer.PodAnalysis) string {
+	qos := result.QoSClass
+	if result.RecommendedQoSClass != "" && result.RecommendedQoSClass != result.QoSClass {
+		qos += "→" + result.RecommendedQoSClass
+	}
+	if result.QoSViolation {
+		qos += " 🚫"
+	}
+	return qos
+}
+
+func (r *Reporter) printSummary(results []analyzer.PodAnalysis) {
+	summary := summarize(results)
+
+	fmt.Fprintf(r.out, "\n📊 Summary:\n")
+	fmt.Fprintf(r.out, "  Total containers analyzed: %d\n", summary.TotalContainers)
+	fmt.Fprintf(r.out, "  🔴 High risk (no limits): %d\n", summary.HighRisk)
+	fmt.Fprintf(r.out, "  🟡 Medium risk: %d\n", summary.MediumRisk)
+	fmt.Fprintf(r.out, "  🟢 Low risk: %d\n", summary.LowRisk)
+	fmt.Fprintf(r.out, "  ❌ No limits set: %d\n", summary.NoLimits)
+	fmt.Fprintf(r.out, "  ⚠️  No requests set: %d\n", summary.NoRequests)
+	fmt.Fprintf(r.out, "  📊 With usage metrics: %d\n", summary.WithUsageData)
 }
 
 func (r *Reporter) printSpecificExamples(results []analyzer.PodAnalysis) {
@@ -270,15 +428,15 @@ func (r *Reporter) printSpecificExamples(results []analyzer.PodAnalysis) {
 	}
 
 	if len(podsNeedingExamples) > 0 {
-		fmt.Printf("\n🔧 Specific fixes for pods without limits (based on current usage):\n")
+		fmt.Fprintf(r.out, "\n🔧 Specific fixes for pods without limits (based on current usage):\n")
 		for _, result := range podsNeedingExamples {
-			fmt.Printf("\n  %s/%s/%s:\n",
+			fmt.Fprintf(r.out, "\n  %s/%s/%s:\n",
 				result.Namespace, result.PodName, result.ContainerName)
-			fmt.Printf("    Current CPU usage: %s → Suggested: limit=%s, request=%s\n",
+			fmt.Fprintf(r.out, "    Current CPU usage: %s → Suggested: limit=%s, request=%s\n",
 				result.CurrentUsage.CPU.String(),
 				result.RecommendedCPULimit,
 				result.RecommendedCPURequest)
-			fmt.Printf("    Current memory usage: %s → Suggested: limit=%s, request=%s\n",
+			fmt.Fprintf(r.out, "    Current memory usage: %s → Suggested: limit=%s, request=%s\n",
 				result.CurrentUsage.Memory.String(),
 				result.RecommendedMemoryLimit,
 				result.RecommendedMemoryRequest)
@@ -287,19 +445,26 @@ func (r *Reporter) printSpecificExamples(results []analyzer.PodAnalysis) {
 }
 
 func (r *Reporter) generateJSON(results []analyzer.PodAnalysis) error {
-	data, err := json.MarshalIndent(results, "", "  ")
+	data, err := json.MarshalIndent(r.buildReport(results), "", "  ")
 	if err != nil {
 		return err
 	}
-	fmt.Println(string(data))
+	fmt.Fprintln(r.out, string(data))
 	return nil
 }
 
 func (r *Reporter) generateYAML(results []analyzer.PodAnalysis) error {
-	data, err := yaml.Marshal(results)
+	data, err := yaml.Marshal(r.buildReport(results))
 	if err != nil {
 		return err
 	}
-	fmt.Println(string(data))
+	fmt.Fprintln(r.out, string(data))
 	return nil
 }
+
+func ratioString(ratio float64) string {
+	if ratio == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%.1fx", ratio)
+}
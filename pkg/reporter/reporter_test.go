@@ -0,0 +1,130 @@
+package reporter
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gopkg.in/yaml.v2"
+
+	"pod-limit-checker/pkg/analyzer"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata/golden")
+
+// golden compares got with testdata/golden/name, or rewrites it with -update.
+func golden(t *testing.T, name string, got []byte) {
+	t.Helper()
+	path := filepath.Join("testdata", "golden", name)
+	if *update {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("%v (run go test -update to create it)", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("%s differs from the golden file, run go test -update and review the diff:\n%s", name, got)
+	}
+}
+
+func TestGenerateReport(t *testing.T) {
+	for _, tt := range []struct {
+		format  string
+		showAll bool
+		file    string
+	}{
+		{"table", false, "report.table"},
+		{"table", true, "report-all.table"},
+		{"json", true, "report.json"},
+		{"yaml", true, "report.yaml"},
+		{"html", true, "report.html"},
+	} {
+		t.Run(tt.file, func(t *testing.T) {
+			var out bytes.Buffer
+			r := sampleReporter(tt.format, &out)
+			r.SetVerbose(true)
+			if err := r.GenerateReport(sampleResults(), tt.showAll); err != nil {
+				t.Fatal(err)
+			}
+			golden(t, tt.file, out.Bytes())
+		})
+	}
+}
+
+// TestGenerateReportSinks renders one analysis into several writers, as
+// repeated --output flags do, and checks they do not affect each other.
+func TestGenerateReportSinks(t *testing.T) {
+	results := sampleResults()
+	outputs := map[string]*bytes.Buffer{}
+	for _, format := range []string{"table", "json", "yaml"} {
+		outputs[format] = &bytes.Buffer{}
+		r := sampleReporter(format, outputs[format])
+		r.SetVerbose(true)
+		if err := r.GenerateReport(results, true); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for format, file := range map[string]string{"table": "report-all.table", "json": "report.json", "yaml": "report.yaml"} {
+		golden(t, file, outputs[format].Bytes())
+	}
+}
+
+func TestQuietTable(t *testing.T) {
+	var out bytes.Buffer
+	r := NewReporter("table", &out)
+	r.SetQuiet(true)
+	if err := r.GenerateReport(sampleResults(), false); err != nil {
+		t.Fatal(err)
+	}
+	golden(t, "report-quiet.table", out.Bytes())
+}
+
+// TestGenerateReportNoFindings checks that the report-level views are
+// printed when every container has limits and no finding is listed.
+func TestGenerateReportNoFindings(t *testing.T) {
+	var out bytes.Buffer
+	r := sampleReporter("table", &out)
+	if err := r.GenerateReport(sampleResults()[2:], false); err != nil {
+		t.Fatal(err)
+	}
+	golden(t, "report-no-findings.table", out.Bytes())
+}
+
+// TestFindingOmitsEmpty checks that a container without requests or a QoS
+// class has neither key in the structured report.
+func TestFindingOmitsEmpty(t *testing.T) {
+	result := sampleResults()[0]
+	result.CurrentRequests = nil
+	result.QoSClass = ""
+	for format, unmarshal := range map[string]func([]byte, interface{}) error{"json": json.Unmarshal, "yaml": yaml.Unmarshal} {
+		var out bytes.Buffer
+		if err := sampleReporter(format, &out).GenerateReport([]analyzer.PodAnalysis{result}, true); err != nil {
+			t.Fatal(err)
+		}
+		var report struct {
+			Findings []map[string]interface{} `json:"findings" yaml:"findings"`
+		}
+		if err := unmarshal(out.Bytes(), &report); err != nil {
+			t.Fatal(err)
+		}
+		if len(report.Findings) != 1 {
+			t.Fatalf("%s report has %d findings, want 1", format, len(report.Findings))
+		}
+		for _, key := range []string{"requests", "qos"} {
+			if value, ok := report.Findings[0][key]; ok {
+				t.Errorf("%s finding has %s: %v", format, key, value)
+			}
+		}
+	}
+}
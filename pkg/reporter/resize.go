@@ -0,0 +1,87 @@
+package reporter
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"text/tabwriter"
+
+	"gopkg.in/yaml.v2"
+
+	"pod-limit-checker/pkg/resize"
+)
+
+// GenerateResizeReport renders an in-place resize plan and, after it has
+// been applied, its outcome.
+func (r *Reporter) GenerateResizeReport(plan *resize.Plan) error {
+	switch strings.ToLower(r.format) {
+	case "json":
+		data, err := json.MarshalIndent(plan, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(r.out, string(data))
+		return nil
+	case "yaml":
+		data, err := yaml.Marshal(plan)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(r.out, string(data))
+		return nil
+	default:
+		r.printResizePlan(plan)
+		return nil
+	}
+}
+
+func (r *Reporter) printResizePlan(plan *resize.Plan) {
+	if !plan.Supported {
+		fmt.Fprintf(r.out, "⚠️  The cluster does not serve pods/resize; recommendations must be rolled out through the workloads.\n\n")
+	}
+	if len(plan.Containers) == 0 {
+		fmt.Fprintln(r.out, "✅ No running container needs resizing.")
+		return
+	}
+
+	fmt.Fprintf(r.out, "↕️  In-place resize plan:\n\n")
+	w := tabwriter.NewWriter(r.out, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(w, "  NAMESPACE\tPOD\tCONTAINER\tCPU (LIMIT/REQUEST)\tMEMORY (LIMIT/REQUEST)\tRESTART\tSTATUS")
+	var restarts, skipped int
+	for _, c := range plan.Containers {
+		restart := "no"
+		if c.Restart {
+			restart = "yes"
+			restarts++
+		}
+		status := "planned"
+		switch {
+		case c.Skipped != "":
+			status = "skipped: " + c.Skipped
+			skipped++
+		case c.Error != "":
+			status = "failed: " + c.Error
+		case c.Applied:
+			status = "applied"
+		}
+		fmt.Fprintf(w, "  %s\t%s\t%s\t%s → %s\t%s → %s\t%s\t%s\n",
+			c.Namespace, c.Pod, c.Container,
+			pair(c.Current.CPULimit, c.Current.CPURequest), pair(c.Target.CPULimit, c.Target.CPURequest),
+			pair(c.Current.MemoryLimit, c.Current.MemoryRequest), pair(c.Target.MemoryLimit, c.Target.MemoryRequest),
+			restart, status)
+	}
+	w.Flush()
+
+	fmt.Fprintf(r.out, "\n  %d container(s), %d restart on resize, %d cannot be resized in place\n",
+		len(plan.Containers), restarts, skipped)
+}
+
+func pair(limit, request string) string {
+	if limit == "" {
+		limit = "-"
+	}
+	if request == "" {
+		request = "-"
+	}
+	return limit + "/" + request
+}
@@ -0,0 +1,189 @@
+package reporter
+
+import (
+	"fmt"
+	"sort"
+	"text/tabwriter"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	"pod-limit-checker/pkg/analyzer"
+)
+
+// Rollup sort orders accepted by SetRollup.
+const (
+	RollupSortWorst = "worst"
+	RollupSortName  = "name"
+)
+
+// noOwner groups containers whose pod lacks the owner label.
+const noOwner = "<none>"
+
+// Rollups groups findings by namespace and by owner label.
+type Rollups struct {
+	OwnerLabel  string      `json:"ownerLabel" yaml:"ownerLabel"`
+	ByNamespace []RollupRow `json:"byNamespace" yaml:"byNamespace"`
+	ByOwner     []RollupRow `json:"byOwner" yaml:"byOwner"`
+}
+
+// RollupRow aggregates the containers of one namespace or owner.
+type RollupRow struct {
+	Name       string `json:"name" yaml:"name"`
+	Containers int    `json:"containers" yaml:"containers"`
+	HighRisk   int    `json:"highRisk" yaml:"highRisk"`
+	MediumRisk int    `json:"mediumRisk" yaml:"mediumRisk"`
+	LowRisk    int    `json:"lowRisk" yaml:"lowRisk"`
+	// CompliancePercent is the share of containers rated LOW risk.
+	CompliancePercent float64        `json:"compliancePercent" yaml:"compliancePercent"`
+	CPU               RollupResource `json:"cpu" yaml:"cpu"`
+	Memory            RollupResource `json:"memory" yaml:"memory"`
+}
+
+// RollupResource totals one resource for a rollup row. Recommended sums
+// recommended requests and only covers containers with usage data.
+type RollupResource struct {
+	Requested   string `json:"requested" yaml:"requested"`
+	Used        string `json:"used" yaml:"used"`
+	Recommended string `json:"recommended" yaml:"recommended"`
+}
+
+type rollupAccumulator struct {
+	row            RollupRow
+	cpuRequested   int64
+	cpuUsed        int64
+	cpuRecommended int64
+	memRequested   int64
+	memUsed        int64
+	memRecommended int64
+}
+
+func (acc *rollupAccumulator) add(result analyzer.PodAnalysis) {
+	acc.row.Containers++
+	switch result.RiskLevel {
+	case "HIGH":
+		acc.row.HighRisk++
+	case "MEDIUM":
+		acc.row.MediumRisk++
+	case "LOW":
+		acc.row.LowRisk++
+	}
+
+	if cpu, ok := result.CurrentRequests[v1.ResourceCPU]; ok {
+		acc.cpuRequested += cpu.MilliValue()
+	}
+	if mem, ok := result.CurrentRequests[v1.ResourceMemory]; ok {
+		acc.memRequested += mem.Value()
+	}
+
+	if result.CurrentUsage != nil {
+		if result.CurrentUsage.CPU != nil {
+			acc.cpuUsed += result.CurrentUsage.CPU.MilliValue()
+		}
+		if result.CurrentUsage.Memory != nil {
+			acc.memUsed += result.CurrentUsage.Memory.Value()
+		}
+	}
+
+	if q, err := resource.ParseQuantity(result.RecommendedCPURequest); err == nil {
+		acc.cpuRecommended += q.MilliValue()
+	}
+	if q, err := resource.ParseQuantity(result.RecommendedMemoryRequest); err == nil {
+		acc.memRecommended += q.Value()
+	}
+}
+
+func (acc *rollupAccumulator) finish() RollupRow {
+	row := acc.row
+	if row.Containers > 0 {
+		row.CompliancePercent = float64(row.LowRisk) / float64(row.Containers) * 100
+	}
+	row.CPU = RollupResource{
+		Requested:   resource.NewMilliQuantity(acc.cpuRequested, resource.DecimalSI).String(),
+		Used:        resource.NewMilliQuantity(acc.cpuUsed, resource.DecimalSI).String(),
+		Recommended: resource.NewMilliQuantity(acc.cpuRecommended, resource.DecimalSI).String(),
+	}
+	row.Memory = RollupResource{
+		Requested:   resource.NewQuantity(acc.memRequested, resource.BinarySI).String(),
+		Used:        resource.NewQuantity(acc.memUsed, resource.BinarySI).String(),
+		Recommended: resource.NewQuantity(acc.memRecommended, resource.BinarySI).String(),
+	}
+	return row
+}
+
+func buildRollups(results []analyzer.PodAnalysis, ownerLabel, sortBy string) *Rollups {
+	byNamespace := map[string]*rollupAccumulator{}
+	byOwner := map[string]*rollupAccumulator{}
+
+	for _, result := range results {
+		rollupGroup(byNamespace, result.Namespace).add(result)
+
+		owner := result.Labels[ownerLabel]
+		if owner == "" {
+			owner = noOwner
+		}
+		rollupGroup(byOwner, owner).add(result)
+	}
+
+	return &Rollups{
+		OwnerLabel:  ownerLabel,
+		ByNamespace: finishRollup(byNamespace, sortBy),
+		ByOwner:     finishRollup(byOwner, sortBy),
+	}
+}
+
+func rollupGroup(groups map[string]*rollupAccumulator, name string) *rollupAccumulator {
+	acc, ok := groups[name]
+	if !ok {
+		acc = &rollupAccumulator{row: RollupRow{Name: name}}
+		groups[name] = acc
+	}
+	return acc
+}
+
+func finishRollup(groups map[string]*rollupAccumulator, sortBy string) []RollupRow {
+	rows := make([]RollupRow, 0, len(groups))
+	for _, acc := range groups {
+		rows = append(rows, acc.finish())
+	}
+
+	sort.Slice(rows, func(i, j int) bool {
+		if sortBy == RollupSortWorst {
+			if rows[i].CompliancePercent != rows[j].CompliancePercent {
+				return rows[i].CompliancePercent < rows[j].CompliancePercent
+			}
+			if rows[i].HighRisk != rows[j].HighRisk {
+				return rows[i].HighRisk > rows[j].HighRisk
+			}
+		}
+		return rows[i].Name < rows[j].Name
+	})
+
+	return rows
+}
+
+func (r *Reporter) printRollups(rollups *Rollups) {
+	fmt.Fprintf(r.out, "\n🏷️  By namespace:\n")
+	r.printRollupTable("NAMESPACE", rollups.ByNamespace)
+
+	fmt.Fprintf(r.out, "\n👥 By owner (label %q):\n", rollups.OwnerLabel)
+	r.printRollupTable("OWNER", rollups.ByOwner)
+}
+
+func (r *Reporter) printRollupTable(header string, rows []RollupRow) {
+	w := tabwriter.NewWriter(r.out, 0, 0, 3, ' ', 0)
+	fmt.Fprintf(w, "  %s\tCONTAINERS\tHIGH\tMEDIUM\tLOW\tCOMPLIANCE\tCPU REQ/USED/REC\tMEM REQ/USED/REC\n", header)
+	for _, row := range rows {
+		fmt.Fprintf(w, "  %s\t%d\t%d\t%d\t%d\t%.1f%%\t%s/%s/%s\t%s/%s/%s\n",
+			row.Name,
+			row.Containers,
+			row.HighRisk,
+			row.MediumRisk,
+			row.LowRisk,
+			row.CompliancePercent,
+			row.CPU.Requested, row.CPU.Used, row.CPU.Recommended,
+			row.Memory.Requested, row.Memory.Used, row.Memory.Recommended,
+		)
+	}
+	w.Flush()
+}
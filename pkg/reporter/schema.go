@@ -0,0 +1,98 @@
+package reporter
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// SchemaID is the $id of the published JSON Schema for Report.
+const SchemaID = "https://github.com/diablinux/pod-limit-checker/schema/report-v1.schema.json"
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// JSONSchema returns the JSON Schema (draft 2020-12) describing Report,
+// generated from the Go types so the two cannot drift apart.
+func JSONSchema() ([]byte, error) {
+	schema := schemaFor(reflect.TypeOf(Report{}))
+	schema["$schema"] = "https://json-schema.org/draft/2020-12/schema"
+	schema["$id"] = SchemaID
+	schema["title"] = ReportKind
+
+	props := schema["properties"].(map[string]interface{})
+	props["apiVersion"] = map[string]interface{}{"const": ReportAPIVersion}
+	props["kind"] = map[string]interface{}{"const": ReportKind}
+
+	return json.MarshalIndent(schema, "", "  ")
+}
+
+func schemaFor(t reflect.Type) map[string]interface{} {
+	if t == timeType {
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		return schemaFor(t.Elem())
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int32, reflect.Int64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.Slice:
+		return map[string]interface{}{"type": "array", "items": schemaFor(t.Elem())}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": schemaFor(t.Elem())}
+	case reflect.Struct:
+		properties := map[string]interface{}{}
+		required := []string{}
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if field.PkgPath != "" {
+				continue
+			}
+			name, omitempty := jsonFieldName(field)
+			if name == "-" {
+				continue
+			}
+			properties[name] = schemaFor(field.Type)
+			if !omitempty && field.Type.Kind() != reflect.Ptr {
+				required = append(required, name)
+			}
+		}
+		schema := map[string]interface{}{
+			"type":       "object",
+			"properties": properties,
+		}
+		if len(required) > 0 {
+			schema["required"] = required
+		}
+		return schema
+	default:
+		panic(fmt.Sprintf("reporter: no JSON Schema mapping for %s", t))
+	}
+}
+
+func jsonFieldName(field reflect.StructField) (string, bool) {
+	tag := field.Tag.Get("json")
+	if tag == "" {
+		return field.Name, false
+	}
+	parts := strings.Split(tag, ",")
+	name := parts[0]
+	if name == "" {
+		name = field.Name
+	}
+	omitempty := false
+	for _, opt := range parts[1:] {
+		if opt == "omitempty" {
+			omitempty = true
+		}
+	}
+	return name, omitempty
+}
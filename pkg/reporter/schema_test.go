@@ -0,0 +1,342 @@
+package reporter
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/santhosh-tekuri/jsonschema/v5"
+)
+
+// publishedSchema is the schema consumers validate against.
+const publishedSchema = "../../schema/report-v1.schema.json"
+
+// initialSchema is report-v1.schema.json as first published. Later v1
+// schemas may add properties but must accept everything it accepted.
+const initialSchema = "testdata/v1/report-initial.schema.json"
+
+func TestJSONSchemaIsPublished(t *testing.T) {
+	generated, err := JSONSchema()
+	if err != nil {
+		t.Fatal(err)
+	}
+	published, err := os.ReadFile(publishedSchema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(bytes.TrimSpace(generated), bytes.TrimSpace(published)) {
+		t.Errorf("%s is stale, run make schema", publishedSchema)
+	}
+}
+
+func TestSchemaFor(t *testing.T) {
+	type nested struct {
+		Value string `json:"value"`
+	}
+	type sample struct {
+		Name       string            `json:"name"`
+		Optional   string            `json:"optional,omitempty"`
+		Count      int64             `json:"count"`
+		Ratio      float64           `json:"ratio,omitempty"`
+		Enabled    bool              `json:"enabled"`
+		When       time.Time         `json:"when"`
+		Tags       []string          `json:"tags"`
+		Labels     map[string]string `json:"labels,omitempty"`
+		Nested     nested            `json:"nested"`
+		Pointer    *nested           `json:"pointer"`
+		Untagged   string
+		Skipped    string `json:"-"`
+		unexported string
+	}
+
+	got := schemaFor(reflect.TypeOf(sample{}))
+	want := map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"name":     map[string]interface{}{"type": "string"},
+			"optional": map[string]interface{}{"type": "string"},
+			"count":    map[string]interface{}{"type": "integer"},
+			"ratio":    map[string]interface{}{"type": "number"},
+			"enabled":  map[string]interface{}{"type": "boolean"},
+			"when":     map[string]interface{}{"type": "string", "format": "date-time"},
+			"tags":     map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}},
+			"labels":   map[string]interface{}{"type": "object", "additionalProperties": map[string]interface{}{"type": "string"}},
+			"nested": map[string]interface{}{
+				"type":       "object",
+				"properties": map[string]interface{}{"value": map[string]interface{}{"type": "string"}},
+				"required":   []string{"value"},
+			},
+			"pointer": map[string]interface{}{
+				"type":       "object",
+				"properties": map[string]interface{}{"value": map[string]interface{}{"type": "string"}},
+				"required":   []string{"value"},
+			},
+			"Untagged": map[string]interface{}{"type": "string"},
+		},
+		// omitempty and pointer fields are optional
+		"required": []string{"name", "count", "enabled", "when", "tags", "nested", "Untagged"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		gotJSON, _ := json.MarshalIndent(got, "", "  ")
+		wantJSON, _ := json.MarshalIndent(want, "", "  ")
+		t.Errorf("schema =\n%s\nwant\n%s", gotJSON, wantJSON)
+	}
+}
+
+func TestSchemaForUnsupportedType(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("schemaFor(chan int) did not panic")
+		}
+	}()
+	schemaFor(reflect.TypeOf(make(chan int)))
+}
+
+// TestSchemaKeepsInitialV1 fails on changes that need a new apiVersion:
+// removing a property, changing its type or making a field required.
+func TestSchemaKeepsInitialV1(t *testing.T) {
+	initial := readSchema(t, initialSchema)
+	generated, err := JSONSchema()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var current map[string]interface{}
+	if err := json.Unmarshal(generated, &current); err != nil {
+		t.Fatal(err)
+	}
+	for _, problem := range incompatibilities("", initial, current) {
+		t.Error(problem)
+	}
+}
+
+func TestIncompatibilities(t *testing.T) {
+	old := map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"a": map[string]interface{}{"type": "string"},
+			"b": map[string]interface{}{"type": "integer"},
+		},
+		"required": []interface{}{"a"},
+	}
+	tests := []struct {
+		name    string
+		current map[string]interface{}
+		want    []string
+	}{
+		{
+			name: "optional property added",
+			current: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"a": map[string]interface{}{"type": "string"},
+					"b": map[string]interface{}{"type": "integer"},
+					"c": map[string]interface{}{"type": "string"},
+				},
+				"required": []interface{}{"a"},
+			},
+		},
+		{
+			name: "required property added",
+			current: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"a": map[string]interface{}{"type": "string"},
+					"b": map[string]interface{}{"type": "integer"},
+					"c": map[string]interface{}{"type": "string"},
+				},
+				"required": []interface{}{"a", "c"},
+			},
+			want: []string{"/: c became required"},
+		},
+		{
+			name: "property removed and retyped",
+			current: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"a": map[string]interface{}{"type": "integer"},
+				},
+				"required": []interface{}{"a"},
+			},
+			want: []string{"/a: type changed from string to integer", "/: b was removed"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := incompatibilities("", old, tt.current)
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("incompatibilities = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestReportsValidate validates reports written by earlier v1 releases,
+// kept in testdata/v1 and never regenerated, and one rendered now.
+func TestReportsValidate(t *testing.T) {
+	schema := compileSchema(t)
+
+	files, err := filepath.Glob("testdata/v1/report-*.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	reports := map[string][]byte{}
+	for _, file := range files {
+		if strings.HasSuffix(file, ".schema.json") {
+			continue
+		}
+		data, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		reports[file] = data
+	}
+	if len(reports) < 2 {
+		t.Fatalf("found %d v1 fixture reports, want at least 2", len(reports))
+	}
+
+	var current bytes.Buffer
+	if err := sampleReporter("json", &current).GenerateReport(sampleResults(), true); err != nil {
+		t.Fatal(err)
+	}
+	reports["current"] = current.Bytes()
+
+	for name, data := range reports {
+		t.Run(filepath.Base(name), func(t *testing.T) {
+			decoder := json.NewDecoder(bytes.NewReader(data))
+			decoder.UseNumber()
+			var report interface{}
+			if err := decoder.Decode(&report); err != nil {
+				t.Fatal(err)
+			}
+			if err := schema.Validate(report); err != nil {
+				t.Errorf("%#v", err)
+			}
+
+			// Earlier reports must also still load
+			var loaded Report
+			if err := json.Unmarshal(data, &loaded); err != nil {
+				t.Fatal(err)
+			}
+			if loaded.APIVersion != ReportAPIVersion || len(loaded.Findings) == 0 {
+				t.Errorf("loaded %s with %d findings", loaded.APIVersion, len(loaded.Findings))
+			}
+		})
+	}
+}
+
+func compileSchema(t *testing.T) *jsonschema.Schema {
+	t.Helper()
+	generated, err := JSONSchema()
+	if err != nil {
+		t.Fatal(err)
+	}
+	compiler := jsonschema.NewCompiler()
+	compiler.Draft = jsonschema.Draft2020
+	if err := compiler.AddResource(SchemaID, bytes.NewReader(generated)); err != nil {
+		t.Fatal(err)
+	}
+	schema, err := compiler.Compile(SchemaID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return schema
+}
+
+func readSchema(t *testing.T, path string) map[string]interface{} {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var schema map[string]interface{}
+	if err := json.Unmarshal(data, &schema); err != nil {
+		t.Fatal(err)
+	}
+	return schema
+}
+
+// incompatibilities lists the ways current rejects documents old accepts,
+// as far as the generated schemas can express them.
+func incompatibilities(path string, old, current map[string]interface{}) []string {
+	var problems []string
+	at := path
+	if at == "" {
+		at = "/"
+	}
+	for _, keyword := range []string{"type", "const", "format"} {
+		if value, ok := old[keyword]; ok && !reflect.DeepEqual(value, current[keyword]) {
+			problems = append(problems, at+": "+keyword+" changed from "+toString(value)+" to "+toString(current[keyword]))
+		}
+	}
+
+	required := map[string]bool{}
+	for _, name := range asSlice(old["required"]) {
+		required[toString(name)] = true
+	}
+	for _, name := range asSlice(current["required"]) {
+		if !required[toString(name)] {
+			problems = append(problems, at+": "+toString(name)+" became required")
+		}
+	}
+
+	oldProperties, _ := old["properties"].(map[string]interface{})
+	currentProperties, _ := current["properties"].(map[string]interface{})
+	names := make([]string, 0, len(oldProperties))
+	for name := range oldProperties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		currentProperty, ok := currentProperties[name].(map[string]interface{})
+		if !ok {
+			problems = append(problems, at+": "+name+" was removed")
+			continue
+		}
+		problems = append(problems, incompatibilities(path+"/"+name, oldProperties[name].(map[string]interface{}), currentProperty)...)
+	}
+
+	for _, keyword := range []string{"items", "additionalProperties"} {
+		oldSchema, ok := old[keyword].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		currentSchema, ok := current[keyword].(map[string]interface{})
+		if !ok {
+			problems = append(problems, at+": "+keyword+" was removed")
+			continue
+		}
+		problems = append(problems, incompatibilities(path+"/"+keyword, oldSchema, currentSchema)...)
+	}
+	return problems
+}
+
+func asSlice(value interface{}) []interface{} {
+	switch v := value.(type) {
+	case []interface{}:
+		return v
+	case []string:
+		s := make([]interface{}, len(v))
+		for i := range v {
+			s[i] = v[i]
+		}
+		return s
+	}
+	return nil
+}
+
+func toString(value interface{}) string {
+	if value == nil {
+		return "nothing"
+	}
+	if s, ok := value.(string); ok {
+		return s
+	}
+	data, _ := json.Marshal(value)
+	return string(data)
+}
@@ -0,0 +1,58 @@
+package reporter
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"text/tabwriter"
+
+	"gopkg.in/yaml.v2"
+
+	"pod-limit-checker/pkg/simulator"
+)
+
+// GenerateSimulationReport renders the outcome of a bin-packing simulation.
+func (r *Reporter) GenerateSimulationReport(result *simulator.Result) error {
+	switch strings.ToLower(r.format) {
+	case "json":
+		data, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(r.out, string(data))
+		return nil
+	case "yaml":
+		data, err := yaml.Marshal(result)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(r.out, string(data))
+		return nil
+	default:
+		r.printSimulation(result)
+		return nil
+	}
+}
+
+func (r *Reporter) printSimulation(result *simulator.Result) {
+	fmt.Fprintf(r.out, "🧮 Bin-packing simulation (%d pods, %d schedulable nodes):\n\n",
+		result.PodsSimulated, result.NodesAvailable)
+
+	w := tabwriter.NewWriter(r.out, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(w, "  \tCURRENT\tRECOMMENDED")
+	fmt.Fprintf(w, "  Nodes needed\t%d\t%d\n", result.Current.NodesNeeded, result.Recommended.NodesNeeded)
+	fmt.Fprintf(w, "  CPU requests\t%s\t%s\n", result.Current.RequestsCPU, result.Recommended.RequestsCPU)
+	fmt.Fprintf(w, "  Memory requests\t%s\t%s\n", result.Current.RequestsMemory, result.Recommended.RequestsMemory)
+	fmt.Fprintf(w, "  Unschedulable pods\t%d\t%d\n", len(result.Current.Unschedulable), len(result.Recommended.Unschedulable))
+	w.Flush()
+
+	if len(result.NewlyUnschedulable) == 0 {
+		fmt.Fprintf(r.out, "\n✅ All pods that fit today still fit with the recommended requests.\n")
+		return
+	}
+
+	fmt.Fprintf(r.out, "\n❌ Pods that would become unschedulable:\n")
+	for _, pod := range result.NewlyUnschedulable {
+		fmt.Fprintf(r.out, "  %s/%s: %s\n", pod.Namespace, pod.Pod, pod.Reason)
+	}
+}
@@ -0,0 +1,301 @@
+package resize
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/kubernetes"
+
+	"pod-limit-checker/pkg/analyzer"
+)
+
+// Subresource is the pod subresource that changes container resources
+// without recreating the pod.
+const Subresource = "resize"
+
+// Plan lists the in-place resizes that would apply the recommendations to
+// running pods.
+type Plan struct {
+	// Supported is set when the API server serves pods/resize
+	Supported  bool              `json:"supported" yaml:"supported"`
+	Containers []ContainerResize `json:"containers" yaml:"containers"`
+}
+
+// ContainerResize is the resize of one container. Skipped explains why it
+// cannot be resized in place; Restart is set when its resizePolicy makes
+// the kubelet restart the container.
+type ContainerResize struct {
+	Namespace string    `json:"namespace" yaml:"namespace"`
+	Pod       string    `json:"pod" yaml:"pod"`
+	Container string    `json:"container" yaml:"container"`
+	Current   Resources `json:"current" yaml:"current"`
+	Target    Resources `json:"target" yaml:"target"`
+	Restart   bool      `json:"restart" yaml:"restart"`
+	Skipped   string    `json:"skipped,omitempty" yaml:"skipped,omitempty"`
+	Applied   bool      `json:"applied" yaml:"applied"`
+	Error     string    `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+// Resources holds CPU and memory quantities as canonical strings.
+type Resources struct {
+	CPULimit      string `json:"cpuLimit,omitempty" yaml:"cpuLimit,omitempty"`
+	CPURequest    string `json:"cpuRequest,omitempty" yaml:"cpuRequest,omitempty"`
+	MemoryLimit   string `json:"memoryLimit,omitempty" yaml:"memoryLimit,omitempty"`
+	MemoryRequest string `json:"memoryRequest,omitempty" yaml:"memoryRequest,omitempty"`
+}
+
+// Supported reports whether the API server serves the pods/resize
+// subresource.
+func Supported(client discovery.DiscoveryInterface) (bool, error) {
+	resources, err := client.ServerResourcesForGroupVersion("v1")
+	if err != nil {
+		return false, err
+	}
+	for _, r := range resources.APIResources {
+		if r.Name == "pods/"+Subresource {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// PlanResize builds the resize for every container with a usage-based
+// recommendation that differs from its current resources.
+func PlanResize(pods []v1.Pod, results []analyzer.PodAnalysis, supported bool) *Plan {
+	podsByKey := map[string]v1.Pod{}
+	for _, pod := range pods {
+		podsByKey[pod.Namespace+"/"+pod.Name] = pod
+	}
+
+	plan := &Plan{Supported: supported, Containers: []ContainerResize{}}
+	// Resizes not skipped so far, by pod, with their target resources
+	candidates := map[string][]int{}
+	targets := map[int]v1.ResourceRequirements{}
+	for _, result := range results {
+		if result.RecommendedCPULimit == "" || result.RecommendedMemoryLimit == "" {
+			continue
+		}
+		pod, ok := podsByKey[result.Namespace+"/"+result.PodName]
+		if !ok {
+			continue
+		}
+		container, ok := findContainer(pod, result.ContainerName)
+		if !ok {
+			continue
+		}
+
+		target := v1.ResourceList{
+			v1.ResourceCPU:    resource.MustParse(result.RecommendedCPULimit),
+			v1.ResourceMemory: resource.MustParse(result.RecommendedMemoryLimit),
+		}
+		targetRequests := v1.ResourceList{
+			v1.ResourceCPU:    resource.MustParse(result.RecommendedCPURequest),
+			v1.ResourceMemory: resource.MustParse(result.RecommendedMemoryRequest),
+		}
+		cpuChanged := changed(container.Resources, target, targetRequests, v1.ResourceCPU)
+		memChanged := changed(container.Resources, target, targetRequests, v1.ResourceMemory)
+		if !cpuChanged && !memChanged {
+			continue
+		}
+
+		resize := ContainerResize{
+			Namespace: result.Namespace,
+			Pod:       result.PodName,
+			Container: result.ContainerName,
+			Current:   resourcesOf(container.Resources.Limits, container.Resources.Requests),
+			Target:    resourcesOf(target, targetRequests),
+			Restart: cpuChanged && restartPolicy(container, v1.ResourceCPU) == v1.RestartContainer ||
+				memChanged && restartPolicy(container, v1.ResourceMemory) == v1.RestartContainer,
+			Skipped: skipReason(pod, container, target, supported),
+		}
+		if resize.Skipped == "" {
+			key := pod.Namespace + "/" + pod.Name
+			candidates[key] = append(candidates[key], len(plan.Containers))
+			targets[len(plan.Containers)] = v1.ResourceRequirements{Limits: target, Requests: targetRequests}
+		}
+		plan.Containers = append(plan.Containers, resize)
+	}
+
+	for _, pod := range pods {
+		preserveQoS(pod, plan, candidates[pod.Namespace+"/"+pod.Name], targets)
+	}
+
+	sort.SliceStable(plan.Containers, func(i, j int) bool {
+		a, b := plan.Containers[i], plan.Containers[j]
+		if a.Namespace != b.Namespace {
+			return a.Namespace < b.Namespace
+		}
+		return a.Pod < b.Pod
+	})
+	return plan
+}
+
+// skipReason returns why a container cannot be resized in place, or "".
+func skipReason(pod v1.Pod, container v1.Container, target v1.ResourceList, supported bool) string {
+	if !supported {
+		return "cluster does not serve pods/resize, roll the workload instead"
+	}
+	if pod.Status.Phase != v1.PodRunning {
+		return fmt.Sprintf("pod is %s", pod.Status.Phase)
+	}
+	// Shrinking a memory limit without a restart could OOM-kill the container
+	if limit, ok := container.Resources.Limits[v1.ResourceMemory]; ok {
+		newLimit := target[v1.ResourceMemory]
+		if newLimit.Cmp(limit) < 0 && restartPolicy(container, v1.ResourceMemory) != v1.RestartContainer {
+			return "memory limit decrease requires resizePolicy RestartContainer"
+		}
+	}
+	return ""
+}
+
+// preserveQoS skips the resizes that would change the pod's QoS class,
+// which the API server rejects. Containers are checked in order, each with
+// the resizes accepted before it, so the pod keeps its class whichever of
+// its containers end up resized.
+func preserveQoS(pod v1.Pod, plan *Plan, indexes []int, targets map[int]v1.ResourceRequirements) {
+	if len(indexes) == 0 {
+		return
+	}
+	resources := map[string]v1.ResourceRequirements{}
+	for _, container := range pod.Spec.Containers {
+		resources[container.Name] = container.Resources
+	}
+	class := func() v1.PodQOSClass {
+		var all []v1.ResourceRequirements
+		for _, container := range pod.Spec.InitContainers {
+			all = append(all, container.Resources)
+		}
+		for _, container := range pod.Spec.Containers {
+			all = append(all, resources[container.Name])
+		}
+		return analyzer.PodQOSClass(all)
+	}
+
+	current := class()
+	for _, i := range indexes {
+		resize := &plan.Containers[i]
+		previous := resources[resize.Container]
+		resources[resize.Container] = targets[i]
+		if projected := class(); projected != current {
+			resources[resize.Container] = previous
+			resize.Skipped = fmt.Sprintf("would change the pod's QoS class from %s to %s, which is immutable", current, projected)
+		}
+	}
+}
+
+func findContainer(pod v1.Pod, name string) (v1.Container, bool) {
+	for _, container := range pod.Spec.Containers {
+		if container.Name == name {
+			return container, true
+		}
+	}
+	return v1.Container{}, false
+}
+
+func changed(current v1.ResourceRequirements, limits, requests v1.ResourceList, name v1.ResourceName) bool {
+	differs := func(list v1.ResourceList, want resource.Quantity) bool {
+		have, ok := list[name]
+		return !ok || have.Cmp(want) != 0
+	}
+	return differs(current.Limits, limits[name]) || differs(current.Requests, requests[name])
+}
+
+// restartPolicy returns the container's resize restart policy for a
+// resource; NotRequired is the API default.
+func restartPolicy(container v1.Container, name v1.ResourceName) v1.ResourceResizeRestartPolicy {
+	for _, policy := range container.ResizePolicy {
+		if policy.ResourceName == name {
+			return policy.RestartPolicy
+		}
+	}
+	return v1.NotRequired
+}
+
+func resourcesOf(limits, requests v1.ResourceList) Resources {
+	quantity := func(list v1.ResourceList, name v1.ResourceName) string {
+		if q, ok := list[name]; ok {
+			return q.String()
+		}
+		return ""
+	}
+	return Resources{
+		CPULimit:      quantity(limits, v1.ResourceCPU),
+		CPURequest:    quantity(requests, v1.ResourceCPU),
+		MemoryLimit:   quantity(limits, v1.ResourceMemory),
+		MemoryRequest: quantity(requests, v1.ResourceMemory),
+	}
+}
+
+type resizePatch struct {
+	Spec struct {
+		Containers []containerPatch `json:"containers"`
+	} `json:"spec"`
+}
+
+type containerPatch struct {
+	Name      string `json:"name"`
+	Resources struct {
+		Limits   map[string]string `json:"limits"`
+		Requests map[string]string `json:"requests"`
+	} `json:"resources"`
+}
+
+// Apply patches the resize subresource once per pod for every container in
+// the plan that is not skipped, recording the outcome on the plan.
+func Apply(ctx context.Context, client kubernetes.Interface, plan *Plan) error {
+	byPod := map[string][]int{}
+	var order []string
+	for i, c := range plan.Containers {
+		if c.Skipped != "" {
+			continue
+		}
+		key := c.Namespace + "/" + c.Pod
+		if _, ok := byPod[key]; !ok {
+			order = append(order, key)
+		}
+		byPod[key] = append(byPod[key], i)
+	}
+
+	var failed int
+	for _, key := range order {
+		indexes := byPod[key]
+		first := plan.Containers[indexes[0]]
+
+		var patch resizePatch
+		for _, i := range indexes {
+			c := plan.Containers[i]
+			var cp containerPatch
+			cp.Name = c.Container
+			cp.Resources.Limits = map[string]string{"cpu": c.Target.CPULimit, "memory": c.Target.MemoryLimit}
+			cp.Resources.Requests = map[string]string{"cpu": c.Target.CPURequest, "memory": c.Target.MemoryRequest}
+			patch.Spec.Containers = append(patch.Spec.Containers, cp)
+		}
+		data, err := json.Marshal(patch)
+		if err == nil {
+			_, err = client.CoreV1().Pods(first.Namespace).Patch(ctx, first.Pod, types.StrategicMergePatchType, data, metav1.PatchOptions{}, Subresource)
+		}
+
+		for _, i := range indexes {
+			if err != nil {
+				plan.Containers[i].Error = err.Error()
+			} else {
+				plan.Containers[i].Applied = true
+			}
+		}
+		if err != nil {
+			failed++
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d pod(s) could not be resized", failed)
+	}
+	return nil
+}
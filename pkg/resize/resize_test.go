@@ -0,0 +1,327 @@
+package resize
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	"k8s.io/client-go/kubernetes/fake"
+	clienttesting "k8s.io/client-go/testing"
+	metricsv1beta1 "k8s.io/metrics/pkg/apis/metrics/v1beta1"
+
+	"pod-limit-checker/pkg/analyzer"
+	"pod-limit-checker/pkg/kubernetes"
+)
+
+func resourceList(cpu, memory string) v1.ResourceList {
+	return v1.ResourceList{v1.ResourceCPU: resource.MustParse(cpu), v1.ResourceMemory: resource.MustParse(memory)}
+}
+
+func container(name string, requests, limits v1.ResourceList) v1.Container {
+	return v1.Container{Name: name, Resources: v1.ResourceRequirements{Requests: requests, Limits: limits}}
+}
+
+func testPod(name string, containers ...v1.Container) v1.Pod {
+	return v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "web", Name: name},
+		Spec:       v1.PodSpec{Containers: containers},
+		Status:     v1.PodStatus{Phase: v1.PodRunning},
+	}
+}
+
+// recommend is a usage-based recommendation for one container.
+func recommend(pod, container, cpuLimit, cpuRequest, memLimit, memRequest string) analyzer.PodAnalysis {
+	return analyzer.PodAnalysis{
+		Namespace:                "web",
+		PodName:                  pod,
+		ContainerName:            container,
+		RecommendedCPULimit:      cpuLimit,
+		RecommendedCPURequest:    cpuRequest,
+		RecommendedMemoryLimit:   memLimit,
+		RecommendedMemoryRequest: memRequest,
+	}
+}
+
+func TestSupported(t *testing.T) {
+	for _, tt := range []struct {
+		name      string
+		resources []metav1.APIResource
+		want      bool
+	}{
+		{"resize served", []metav1.APIResource{{Name: "pods"}, {Name: "pods/status"}, {Name: "pods/resize"}}, true},
+		{"resize not served", []metav1.APIResource{{Name: "pods"}, {Name: "pods/status"}}, false},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			client := fake.NewSimpleClientset()
+			discovery := client.Discovery().(*fakediscovery.FakeDiscovery)
+			discovery.Resources = []*metav1.APIResourceList{{GroupVersion: "v1", APIResources: tt.resources}}
+
+			got, err := Supported(discovery)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("Supported = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPlanResize(t *testing.T) {
+	pending := testPod("pending", container("app", resourceList("100m", "128Mi"), resourceList("200m", "256Mi")))
+	pending.Status.Phase = v1.PodPending
+	restarts := testPod("restarts", container("app", resourceList("100m", "128Mi"), resourceList("200m", "256Mi")))
+	restarts.Spec.Containers[0].ResizePolicy = []v1.ContainerResizePolicy{
+		{ResourceName: v1.ResourceMemory, RestartPolicy: v1.RestartContainer},
+	}
+
+	tests := []struct {
+		name      string
+		pod       v1.Pod
+		results   []analyzer.PodAnalysis
+		supported bool
+		// want maps container to its skip reason; containers missing from
+		// it are not in the plan
+		want    map[string]string
+		restart bool
+	}{
+		{
+			name:      "grow in place",
+			pod:       testPod("api", container("app", resourceList("100m", "128Mi"), resourceList("200m", "256Mi"))),
+			results:   []analyzer.PodAnalysis{recommend("api", "app", "500m", "240m", "640Mi", "307Mi")},
+			supported: true,
+			want:      map[string]string{"app": ""},
+		},
+		{
+			name:      "unchanged",
+			pod:       testPod("api", container("app", resourceList("240m", "307Mi"), resourceList("500m", "640Mi"))),
+			results:   []analyzer.PodAnalysis{recommend("api", "app", "500m", "240m", "640Mi", "307Mi")},
+			supported: true,
+			want:      map[string]string{},
+		},
+		{
+			name:      "no recommendation",
+			pod:       testPod("api", container("app", nil, nil)),
+			results:   []analyzer.PodAnalysis{{Namespace: "web", PodName: "api", ContainerName: "app"}},
+			supported: true,
+			want:      map[string]string{},
+		},
+		{
+			name:    "not supported",
+			pod:     testPod("api", container("app", resourceList("100m", "128Mi"), resourceList("200m", "256Mi"))),
+			results: []analyzer.PodAnalysis{recommend("api", "app", "500m", "240m", "640Mi", "307Mi")},
+			want:    map[string]string{"app": "cluster does not serve pods/resize, roll the workload instead"},
+		},
+		{
+			name:      "pending",
+			pod:       pending,
+			results:   []analyzer.PodAnalysis{recommend("pending", "app", "500m", "240m", "640Mi", "307Mi")},
+			supported: true,
+			want:      map[string]string{"app": "pod is Pending"},
+		},
+		{
+			name:      "memory limit shrinks",
+			pod:       testPod("api", container("app", resourceList("100m", "128Mi"), resourceList("200m", "1Gi"))),
+			results:   []analyzer.PodAnalysis{recommend("api", "app", "500m", "240m", "640Mi", "307Mi")},
+			supported: true,
+			want:      map[string]string{"app": "memory limit decrease requires resizePolicy RestartContainer"},
+		},
+		{
+			name:      "memory limit shrinks with restart",
+			pod:       restarts,
+			results:   []analyzer.PodAnalysis{recommend("restarts", "app", "500m", "240m", "128Mi", "128Mi")},
+			supported: true,
+			want:      map[string]string{"app": ""},
+			restart:   true,
+		},
+		{
+			name: "guaranteed pod stays guaranteed",
+			pod: testPod("api",
+				container("app", resourceList("200m", "256Mi"), resourceList("200m", "256Mi")),
+				container("proxy", resourceList("100m", "64Mi"), resourceList("100m", "64Mi")),
+			),
+			results: []analyzer.PodAnalysis{
+				recommend("api", "app", "500m", "500m", "640Mi", "640Mi"),
+				// Unequal requests would make the pod Burstable
+				recommend("api", "proxy", "250m", "120m", "128Mi", "64Mi"),
+			},
+			supported: true,
+			want: map[string]string{
+				"app":   "",
+				"proxy": "would change the pod's QoS class from Guaranteed to Burstable, which is immutable",
+			},
+		},
+		{
+			name: "burstable pod stays burstable",
+			pod: testPod("api",
+				container("app", resourceList("200m", "256Mi"), resourceList("400m", "512Mi")),
+				container("proxy", resourceList("100m", "64Mi"), resourceList("200m", "128Mi")),
+			),
+			results: []analyzer.PodAnalysis{
+				recommend("api", "app", "500m", "500m", "640Mi", "640Mi"),
+				recommend("api", "proxy", "250m", "250m", "128Mi", "128Mi"),
+			},
+			supported: true,
+			// Either resize alone keeps the pod Burstable, both make it
+			// Guaranteed; the later container is skipped
+			want: map[string]string{
+				"app":   "",
+				"proxy": "would change the pod's QoS class from Burstable to Guaranteed, which is immutable",
+			},
+		},
+		{
+			name:      "best effort pod",
+			pod:       testPod("api", container("app", nil, nil)),
+			results:   []analyzer.PodAnalysis{recommend("api", "app", "500m", "240m", "640Mi", "307Mi")},
+			supported: true,
+			want:      map[string]string{"app": "would change the pod's QoS class from BestEffort to Burstable, which is immutable"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan := PlanResize([]v1.Pod{tt.pod}, tt.results, tt.supported)
+			if plan.Supported != tt.supported {
+				t.Errorf("Supported = %v, want %v", plan.Supported, tt.supported)
+			}
+			got := map[string]string{}
+			for _, c := range plan.Containers {
+				got[c.Container] = c.Skipped
+				if c.Restart != tt.restart {
+					t.Errorf("%s: Restart = %v, want %v", c.Container, c.Restart, tt.restart)
+				}
+			}
+			if len(got) != len(tt.want) {
+				t.Errorf("planned %v, want %v", got, tt.want)
+			}
+			for name, skipped := range tt.want {
+				if reason, ok := got[name]; !ok || reason != skipped {
+					t.Errorf("%s skipped = %q (planned %v), want %q", name, reason, ok, skipped)
+				}
+			}
+		})
+	}
+}
+
+// TestPlanResizeFollowsPolicies checks that the targets carry the check
+// policies, as resize configures the analyzer like check does.
+func TestPlanResizeFollowsPolicies(t *testing.T) {
+	pod := testPod("api", container("app", resourceList("100m", "128Mi"), resourceList("200m", "256Mi")))
+	metrics := metricsv1beta1.PodMetrics{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "web", Name: "api"},
+		Containers: []metricsv1beta1.ContainerMetrics{{Name: "app", Usage: resourceList("200m", "256Mi")}},
+	}
+	podAnalyzer := analyzer.NewPodAnalyzer(&kubernetes.Client{Clientset: fake.NewSimpleClientset()})
+	podAnalyzer.SetBoundsPolicy(analyzer.BoundsPolicy{Max: resourceList("300m", "512Mi")})
+	podAnalyzer.SetRatioPolicy(analyzer.RatioPolicy{MaxCPU: 1.2, MaxMemory: 1})
+
+	results := podAnalyzer.AnalyzePods([]v1.Pod{pod}, []metricsv1beta1.PodMetrics{metrics}, 0.8)
+	plan := PlanResize([]v1.Pod{pod}, results, true)
+	if len(plan.Containers) != 1 {
+		t.Fatalf("planned %d containers, want 1", len(plan.Containers))
+	}
+	want := Resources{CPULimit: "300m", CPURequest: "250m", MemoryLimit: "512Mi", MemoryRequest: "512Mi"}
+	if target := plan.Containers[0].Target; target != want {
+		t.Errorf("target = %+v, want %+v", target, want)
+	}
+}
+
+func TestApply(t *testing.T) {
+	pods := []v1.Pod{
+		testPod("api",
+			container("app", resourceList("100m", "128Mi"), resourceList("200m", "256Mi")),
+			container("proxy", resourceList("50m", "32Mi"), resourceList("100m", "64Mi")),
+		),
+		testPod("worker", container("app", resourceList("100m", "128Mi"), resourceList("200m", "1Gi"))),
+	}
+	plan := PlanResize(pods, []analyzer.PodAnalysis{
+		recommend("api", "app", "500m", "240m", "640Mi", "307Mi"),
+		recommend("api", "proxy", "250m", "120m", "128Mi", "64Mi"),
+		// Skipped: the memory limit would shrink
+		recommend("worker", "app", "500m", "240m", "640Mi", "307Mi"),
+	}, true)
+
+	client := fake.NewSimpleClientset(&pods[0], &pods[1])
+	type patch struct {
+		pod   string
+		patch resizePatch
+	}
+	var patches []patch
+	client.PrependReactor("patch", "pods", func(action clienttesting.Action) (bool, runtime.Object, error) {
+		p := action.(clienttesting.PatchAction)
+		if p.GetSubresource() != Subresource {
+			t.Errorf("patched subresource %q, want %q", p.GetSubresource(), Subresource)
+		}
+		if p.GetPatchType() != types.StrategicMergePatchType {
+			t.Errorf("patch type = %s", p.GetPatchType())
+		}
+		var body resizePatch
+		if err := json.Unmarshal(p.GetPatch(), &body); err != nil {
+			t.Fatal(err)
+		}
+		patches = append(patches, patch{p.GetName(), body})
+		return true, &v1.Pod{}, nil
+	})
+
+	if err := Apply(context.Background(), client, plan); err != nil {
+		t.Fatal(err)
+	}
+
+	// One patch for api with both containers, none for the skipped worker
+	if len(patches) != 1 || patches[0].pod != "api" || len(patches[0].patch.Spec.Containers) != 2 {
+		t.Fatalf("patches = %+v, want one for api with two containers", patches)
+	}
+	app := patches[0].patch.Spec.Containers[0]
+	if app.Name != "app" || app.Resources.Limits["cpu"] != "500m" || app.Resources.Requests["memory"] != "307Mi" {
+		t.Errorf("app patch = %+v", app)
+	}
+	for _, c := range plan.Containers {
+		if c.Applied != (c.Pod == "api") || c.Error != "" {
+			t.Errorf("%s/%s: applied %v, error %q", c.Pod, c.Container, c.Applied, c.Error)
+		}
+	}
+}
+
+func TestApplyError(t *testing.T) {
+	pods := []v1.Pod{
+		testPod("api", container("app", resourceList("100m", "128Mi"), resourceList("200m", "256Mi"))),
+		testPod("web", container("app", resourceList("100m", "128Mi"), resourceList("200m", "256Mi"))),
+	}
+	plan := PlanResize(pods, []analyzer.PodAnalysis{
+		recommend("api", "app", "500m", "240m", "640Mi", "307Mi"),
+		recommend("web", "app", "500m", "240m", "640Mi", "307Mi"),
+	}, true)
+
+	client := fake.NewSimpleClientset(&pods[0], &pods[1])
+	client.PrependReactor("patch", "pods", func(action clienttesting.Action) (bool, runtime.Object, error) {
+		if action.(clienttesting.PatchAction).GetName() == "api" {
+			return true, nil, errors.New("pods \"api\" is forbidden: resize is infeasible")
+		}
+		return true, &v1.Pod{}, nil
+	})
+
+	err := Apply(context.Background(), client, plan)
+	if err == nil || !strings.Contains(err.Error(), "1 pod(s) could not be resized") {
+		t.Fatalf("Apply error = %v", err)
+	}
+	for _, c := range plan.Containers {
+		switch c.Pod {
+		case "api":
+			if c.Applied || !strings.Contains(c.Error, "infeasible") {
+				t.Errorf("api: applied %v, error %q", c.Applied, c.Error)
+			}
+		case "web":
+			if !c.Applied || c.Error != "" {
+				t.Errorf("web: applied %v, error %q", c.Applied, c.Error)
+			}
+		}
+	}
+}
@@ -0,0 +1,382 @@
+package simulator
+
+import (
+	"fmt"
+	"sort"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+
+	"pod-limit-checker/pkg/analyzer"
+)
+
+// Result compares packing the cluster's pods with their current requests
+// against packing them with the recommended requests.
+type Result struct {
+	NodesAvailable int `json:"nodesAvailable" yaml:"nodesAvailable"`
+	PodsSimulated  int `json:"podsSimulated" yaml:"podsSimulated"`
+	// Current and Recommended are the outcomes of the two packing runs.
+	Current     Outcome `json:"current" yaml:"current"`
+	Recommended Outcome `json:"recommended" yaml:"recommended"`
+	// NewlyUnschedulable lists pods that fit today but not after applying
+	// the recommendations.
+	NewlyUnschedulable []UnschedulablePod `json:"newlyUnschedulable" yaml:"newlyUnschedulable"`
+}
+
+// Outcome is the result of one first-fit-decreasing packing run.
+type Outcome struct {
+	NodesNeeded    int                `json:"nodesNeeded" yaml:"nodesNeeded"`
+	RequestsCPU    string             `json:"requestsCPU" yaml:"requestsCPU"`
+	RequestsMemory string             `json:"requestsMemory" yaml:"requestsMemory"`
+	Unschedulable  []UnschedulablePod `json:"unschedulable" yaml:"unschedulable"`
+}
+
+// UnschedulablePod is a pod that did not fit on any node, with the reason
+// reported for the last node considered.
+type UnschedulablePod struct {
+	Namespace string `json:"namespace" yaml:"namespace"`
+	Pod       string `json:"pod" yaml:"pod"`
+	Reason    string `json:"reason" yaml:"reason"`
+}
+
+type podRequest struct {
+	pod      v1.Pod
+	cpuMilli int64
+	memBytes int64
+}
+
+type nodeState struct {
+	node     v1.Node
+	freeCPU  int64
+	freeMem  int64
+	freePods int64
+	pods     []v1.Pod
+	// hasWorkload is set once a non-DaemonSet pod lands on the node
+	hasWorkload bool
+}
+
+// Simulate packs every non-terminal pod, including pending ones, onto the
+// schedulable nodes twice: once with current requests and once with the
+// recommended requests from results. Pods are placed first-fit in decreasing order of
+// their dominant share of node capacity, honouring nodeSelector,
+// NoSchedule/NoExecute taints and required pod anti-affinity in both
+// directions. DaemonSet pods stay on their current node. Node affinity and
+// preferred terms are not modelled.
+func Simulate(pods []v1.Pod, nodes []v1.Node, results []analyzer.PodAnalysis) *Result {
+	overrides := recommendedRequests(results)
+
+	var candidates []v1.Pod
+	for _, pod := range pods {
+		if pod.Status.Phase == v1.PodSucceeded || pod.Status.Phase == v1.PodFailed {
+			continue
+		}
+		candidates = append(candidates, pod)
+	}
+
+	var schedulable []v1.Node
+	for _, node := range nodes {
+		if !node.Spec.Unschedulable {
+			schedulable = append(schedulable, node)
+		}
+	}
+	sort.Slice(schedulable, func(i, j int) bool { return schedulable[i].Name < schedulable[j].Name })
+
+	result := &Result{
+		NodesAvailable: len(schedulable),
+		PodsSimulated:  len(candidates),
+		Current:        pack(candidates, schedulable, nil),
+		Recommended:    pack(candidates, schedulable, overrides),
+	}
+
+	fitsToday := map[string]bool{}
+	for _, pod := range candidates {
+		fitsToday[podKey(pod.Namespace, pod.Name)] = true
+	}
+	for _, u := range result.Current.Unschedulable {
+		fitsToday[podKey(u.Namespace, u.Pod)] = false
+	}
+	result.NewlyUnschedulable = []UnschedulablePod{}
+	for _, u := range result.Recommended.Unschedulable {
+		if fitsToday[podKey(u.Namespace, u.Pod)] {
+			result.NewlyUnschedulable = append(result.NewlyUnschedulable, u)
+		}
+	}
+
+	return result
+}
+
+func pack(pods []v1.Pod, nodes []v1.Node, overrides map[string]v1.ResourceList) Outcome {
+	states := make([]*nodeState, 0, len(nodes))
+	byName := map[string]*nodeState{}
+	var maxCPU, maxMem int64
+	for _, node := range nodes {
+		state := &nodeState{
+			node:     node,
+			freeCPU:  node.Status.Allocatable.Cpu().MilliValue(),
+			freeMem:  node.Status.Allocatable.Memory().Value(),
+			freePods: node.Status.Allocatable.Pods().Value(),
+		}
+		if state.freePods == 0 {
+			state.freePods = 110
+		}
+		states = append(states, state)
+		byName[node.Name] = state
+		maxCPU = max(maxCPU, state.freeCPU)
+		maxMem = max(maxMem, state.freeMem)
+	}
+
+	outcome := Outcome{Unschedulable: []UnschedulablePod{}}
+	var totalCPU, totalMem int64
+	var workloads []podRequest
+
+	for _, pod := range pods {
+		req := effectiveRequests(pod, overrides)
+		totalCPU += req.cpuMilli
+		totalMem += req.memBytes
+
+		if !isDaemonSetPod(pod) {
+			workloads = append(workloads, req)
+			continue
+		}
+		// DaemonSet pods are pinned to their node and never move
+		if state, ok := byName[pod.Spec.NodeName]; ok {
+			state.place(req, false)
+		}
+	}
+
+	dominant := func(req podRequest) float64 {
+		cpu, mem := 0.0, 0.0
+		if maxCPU > 0 {
+			cpu = float64(req.cpuMilli) / float64(maxCPU)
+		}
+		if maxMem > 0 {
+			mem = float64(req.memBytes) / float64(maxMem)
+		}
+		if cpu > mem {
+			return cpu
+		}
+		return mem
+	}
+	sort.SliceStable(workloads, func(i, j int) bool {
+		return dominant(workloads[i]) > dominant(workloads[j])
+	})
+
+	for _, req := range workloads {
+		reason := "no schedulable nodes"
+		placed := false
+		for _, state := range states {
+			if why := state.rejects(req, states); why != "" {
+				reason = why
+				continue
+			}
+			state.place(req, true)
+			placed = true
+			break
+		}
+		if !placed {
+			outcome.Unschedulable = append(outcome.Unschedulable, UnschedulablePod{
+				Namespace: req.pod.Namespace,
+				Pod:       req.pod.Name,
+				Reason:    reason,
+			})
+		}
+	}
+
+	for _, state := range states {
+		if state.hasWorkload {
+			outcome.NodesNeeded++
+		}
+	}
+	outcome.RequestsCPU = resource.NewMilliQuantity(totalCPU, resource.DecimalSI).String()
+	outcome.RequestsMemory = resource.NewQuantity(totalMem, resource.BinarySI).String()
+
+	return outcome
+}
+
+func (s *nodeState) place(req podRequest, workload bool) {
+	s.freeCPU -= req.cpuMilli
+	s.freeMem -= req.memBytes
+	s.freePods--
+	s.pods = append(s.pods, req.pod)
+	if workload {
+		s.hasWorkload = true
+	}
+}
+
+// rejects returns why the pod cannot be placed on the node, or "" if it fits.
+func (s *nodeState) rejects(req podRequest, all []*nodeState) string {
+	for key, value := range req.pod.Spec.NodeSelector {
+		if s.node.Labels[key] != value {
+			return fmt.Sprintf("node selector %s=%s not matched", key, value)
+		}
+	}
+
+	for i := range s.node.Spec.Taints {
+		taint := &s.node.Spec.Taints[i]
+		if taint.Effect == v1.TaintEffectPreferNoSchedule {
+			continue
+		}
+		if !toleratesTaint(req.pod.Spec.Tolerations, taint) {
+			return fmt.Sprintf("untolerated taint %s", taint.ToString())
+		}
+	}
+
+	if s.freePods <= 0 {
+		return "too many pods"
+	}
+	if req.cpuMilli > s.freeCPU {
+		return "insufficient cpu"
+	}
+	if req.memBytes > s.freeMem {
+		return "insufficient memory"
+	}
+
+	if term, conflict := s.antiAffinityConflict(req.pod, all); conflict {
+		return fmt.Sprintf("pod anti-affinity on %s", term.TopologyKey)
+	}
+
+	return ""
+}
+
+func toleratesTaint(tolerations []v1.Toleration, taint *v1.Taint) bool {
+	for i := range tolerations {
+		if tolerations[i].ToleratesTaint(taint) {
+			return true
+		}
+	}
+	return false
+}
+
+// antiAffinityConflict checks the required anti-affinity terms of the pod
+// against pods already placed in the same topology domain as this node,
+// and the terms of those placed pods against the pod, as the scheduler
+// enforces both directions.
+func (s *nodeState) antiAffinityConflict(pod v1.Pod, all []*nodeState) (v1.PodAffinityTerm, bool) {
+	for _, term := range requiredAntiAffinity(pod) {
+		domain, ok := s.node.Labels[term.TopologyKey]
+		if !ok {
+			continue
+		}
+		for _, other := range all {
+			if other.node.Labels[term.TopologyKey] != domain {
+				continue
+			}
+			for _, placed := range other.pods {
+				if affinityTermMatches(term, pod, placed) {
+					return term, true
+				}
+			}
+		}
+	}
+
+	for _, other := range all {
+		for _, placed := range other.pods {
+			for _, term := range requiredAntiAffinity(placed) {
+				domain, ok := other.node.Labels[term.TopologyKey]
+				if !ok || s.node.Labels[term.TopologyKey] != domain {
+					continue
+				}
+				if affinityTermMatches(term, placed, pod) {
+					return term, true
+				}
+			}
+		}
+	}
+
+	return v1.PodAffinityTerm{}, false
+}
+
+func requiredAntiAffinity(pod v1.Pod) []v1.PodAffinityTerm {
+	if pod.Spec.Affinity == nil || pod.Spec.Affinity.PodAntiAffinity == nil {
+		return nil
+	}
+	return pod.Spec.Affinity.PodAntiAffinity.RequiredDuringSchedulingIgnoredDuringExecution
+}
+
+// affinityTermMatches reports whether target is selected by a term declared
+// on owner. Terms without namespaces select owner's namespace.
+func affinityTermMatches(term v1.PodAffinityTerm, owner, target v1.Pod) bool {
+	selector, err := metav1.LabelSelectorAsSelector(term.LabelSelector)
+	if err != nil {
+		return false
+	}
+	namespaces := term.Namespaces
+	if len(namespaces) == 0 {
+		namespaces = []string{owner.Namespace}
+	}
+	return containsString(namespaces, target.Namespace) && selector.Matches(labels.Set(target.Labels))
+}
+
+// effectiveRequests sums container requests, replacing them with overrides
+// keyed by namespace/pod/container, and accounts for init containers and
+// pod overhead the way the scheduler does.
+func effectiveRequests(pod v1.Pod, overrides map[string]v1.ResourceList) podRequest {
+	req := podRequest{pod: pod}
+
+	for _, container := range pod.Spec.Containers {
+		requests := container.Resources.Requests
+		if override, ok := overrides[containerKey(pod.Namespace, pod.Name, container.Name)]; ok {
+			requests = override
+		}
+		req.cpuMilli += requests.Cpu().MilliValue()
+		req.memBytes += requests.Memory().Value()
+	}
+
+	for _, container := range pod.Spec.InitContainers {
+		req.cpuMilli = max(req.cpuMilli, container.Resources.Requests.Cpu().MilliValue())
+		req.memBytes = max(req.memBytes, container.Resources.Requests.Memory().Value())
+	}
+
+	if pod.Spec.Overhead != nil {
+		req.cpuMilli += pod.Spec.Overhead.Cpu().MilliValue()
+		req.memBytes += pod.Spec.Overhead.Memory().Value()
+	}
+
+	return req
+}
+
+func recommendedRequests(results []analyzer.PodAnalysis) map[string]v1.ResourceList {
+	overrides := map[string]v1.ResourceList{}
+	for _, result := range results {
+		if result.RecommendedCPURequest == "" || result.RecommendedMemoryRequest == "" {
+			continue
+		}
+		cpu, err := resource.ParseQuantity(result.RecommendedCPURequest)
+		if err != nil {
+			continue
+		}
+		mem, err := resource.ParseQuantity(result.RecommendedMemoryRequest)
+		if err != nil {
+			continue
+		}
+		overrides[containerKey(result.Namespace, result.PodName, result.ContainerName)] = v1.ResourceList{
+			v1.ResourceCPU:    cpu,
+			v1.ResourceMemory: mem,
+		}
+	}
+	return overrides
+}
+
+func isDaemonSetPod(pod v1.Pod) bool {
+	ref := metav1.GetControllerOf(&pod)
+	return ref != nil && ref.Kind == "DaemonSet"
+}
+
+func podKey(namespace, name string) string {
+	return namespace + "/" + name
+}
+
+func containerKey(namespace, pod, container string) string {
+	return namespace + "/" + pod + "/" + container
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,180 @@
+package simulator
+
+import (
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"pod-limit-checker/pkg/analyzer"
+)
+
+func testNode(name string, cpu, mem string) v1.Node {
+	return v1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   name,
+			Labels: map[string]string{"kubernetes.io/hostname": name},
+		},
+		Status: v1.NodeStatus{Allocatable: v1.ResourceList{
+			v1.ResourceCPU:    resource.MustParse(cpu),
+			v1.ResourceMemory: resource.MustParse(mem),
+			v1.ResourcePods:   resource.MustParse("110"),
+		}},
+	}
+}
+
+func testPod(name string, app string, cpu, mem string) v1.Pod {
+	return v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: name, Labels: map[string]string{"app": app}},
+		Spec: v1.PodSpec{Containers: []v1.Container{{
+			Name: "app",
+			Resources: v1.ResourceRequirements{Requests: v1.ResourceList{
+				v1.ResourceCPU:    resource.MustParse(cpu),
+				v1.ResourceMemory: resource.MustParse(mem),
+			}},
+		}}},
+		Status: v1.PodStatus{Phase: v1.PodRunning},
+	}
+}
+
+// withAntiAffinity adds a required anti-affinity term against pods labelled
+// app=app on the same host.
+func withAntiAffinity(pod v1.Pod, app string) v1.Pod {
+	pod.Spec.Affinity = &v1.Affinity{PodAntiAffinity: &v1.PodAntiAffinity{
+		RequiredDuringSchedulingIgnoredDuringExecution: []v1.PodAffinityTerm{{
+			LabelSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": app}},
+			TopologyKey:   "kubernetes.io/hostname",
+		}},
+	}}
+	return pod
+}
+
+func TestSimulate(t *testing.T) {
+	nodes := []v1.Node{testNode("node-1", "2", "4Gi"), testNode("node-2", "2", "4Gi")}
+	pods := []v1.Pod{
+		testPod("api-0", "api", "1500m", "1Gi"),
+		testPod("api-1", "api", "1500m", "1Gi"),
+		testPod("done", "job", "4", "8Gi"),
+	}
+	pods[2].Status.Phase = v1.PodSucceeded
+	results := []analyzer.PodAnalysis{
+		{Namespace: "default", PodName: "api-0", ContainerName: "app", RecommendedCPURequest: "500m", RecommendedMemoryRequest: "512Mi"},
+		{Namespace: "default", PodName: "api-1", ContainerName: "app", RecommendedCPURequest: "500m", RecommendedMemoryRequest: "512Mi"},
+	}
+
+	result := Simulate(pods, nodes, results)
+	if result.PodsSimulated != 2 || result.NodesAvailable != 2 {
+		t.Fatalf("simulated %d pods on %d nodes, want 2 on 2", result.PodsSimulated, result.NodesAvailable)
+	}
+	if result.Current.NodesNeeded != 2 || result.Recommended.NodesNeeded != 1 {
+		t.Errorf("nodes needed = %d current, %d recommended, want 2 and 1", result.Current.NodesNeeded, result.Recommended.NodesNeeded)
+	}
+	if result.Current.RequestsCPU != "3" || result.Recommended.RequestsCPU != "1" {
+		t.Errorf("requests cpu = %s current, %s recommended, want 3 and 1", result.Current.RequestsCPU, result.Recommended.RequestsCPU)
+	}
+	if len(result.NewlyUnschedulable) != 0 {
+		t.Errorf("newly unschedulable = %v, want none", result.NewlyUnschedulable)
+	}
+}
+
+func TestSimulateNewlyUnschedulable(t *testing.T) {
+	nodes := []v1.Node{testNode("node-1", "2", "4Gi")}
+	pods := []v1.Pod{testPod("api-0", "api", "1", "1Gi"), testPod("api-1", "api", "500m", "1Gi")}
+	results := []analyzer.PodAnalysis{
+		{Namespace: "default", PodName: "api-0", ContainerName: "app", RecommendedCPURequest: "1800m", RecommendedMemoryRequest: "1Gi"},
+	}
+
+	result := Simulate(pods, nodes, results)
+	if len(result.Current.Unschedulable) != 0 {
+		t.Errorf("current unschedulable = %v, want none", result.Current.Unschedulable)
+	}
+	want := UnschedulablePod{Namespace: "default", Pod: "api-1", Reason: "insufficient cpu"}
+	if len(result.NewlyUnschedulable) != 1 || result.NewlyUnschedulable[0] != want {
+		t.Errorf("newly unschedulable = %v, want [%v]", result.NewlyUnschedulable, want)
+	}
+}
+
+// TestSimulateAntiAffinity checks both directions of required
+// anti-affinity: the incoming pod's terms and the terms of pods already
+// placed. Pods are placed largest first, so "big" always lands first.
+func TestSimulateAntiAffinity(t *testing.T) {
+	tests := []struct {
+		name          string
+		big, small    v1.Pod
+		unschedulable bool
+	}{
+		{
+			name:  "no anti-affinity",
+			big:   testPod("big", "db", "1", "1Gi"),
+			small: testPod("small", "cache", "100m", "128Mi"),
+		},
+		{
+			name:          "incoming pod avoids placed pod",
+			big:           testPod("big", "db", "1", "1Gi"),
+			small:         withAntiAffinity(testPod("small", "cache", "100m", "128Mi"), "db"),
+			unschedulable: true,
+		},
+		{
+			name:          "placed pod avoids incoming pod",
+			big:           withAntiAffinity(testPod("big", "db", "1", "1Gi"), "cache"),
+			small:         testPod("small", "cache", "100m", "128Mi"),
+			unschedulable: true,
+		},
+		{
+			name: "placed pod term in another namespace",
+			big:  withAntiAffinity(testPod("big", "db", "1", "1Gi"), "cache"),
+			small: func() v1.Pod {
+				pod := testPod("small", "cache", "100m", "128Mi")
+				pod.Namespace = "other"
+				return pod
+			}(),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			nodes := []v1.Node{testNode("node-1", "4", "8Gi")}
+			result := Simulate([]v1.Pod{tt.small, tt.big}, nodes, nil)
+
+			unschedulable := result.Current.Unschedulable
+			if !tt.unschedulable {
+				if len(unschedulable) != 0 {
+					t.Errorf("unschedulable = %v, want none", unschedulable)
+				}
+				return
+			}
+			want := UnschedulablePod{Namespace: "default", Pod: "small", Reason: "pod anti-affinity on kubernetes.io/hostname"}
+			if len(unschedulable) != 1 || unschedulable[0] != want {
+				t.Errorf("unschedulable = %v, want [%v]", unschedulable, want)
+			}
+		})
+	}
+
+	// With a second node both pods fit, one per host
+	nodes := []v1.Node{testNode("node-1", "4", "8Gi"), testNode("node-2", "4", "8Gi")}
+	big := withAntiAffinity(testPod("big", "db", "1", "1Gi"), "cache")
+	result := Simulate([]v1.Pod{testPod("small", "cache", "100m", "128Mi"), big}, nodes, nil)
+	if len(result.Current.Unschedulable) != 0 || result.Current.NodesNeeded != 2 {
+		t.Errorf("unschedulable = %v on %d nodes, want none on 2", result.Current.Unschedulable, result.Current.NodesNeeded)
+	}
+}
+
+func TestSimulateDaemonSetPinned(t *testing.T) {
+	nodes := []v1.Node{testNode("node-1", "2", "4Gi"), testNode("node-2", "2", "4Gi")}
+	daemon := testPod("agent-x", "agent", "1800m", "128Mi")
+	daemon.Spec.NodeName = "node-1"
+	daemon.OwnerReferences = []metav1.OwnerReference{{Kind: "DaemonSet", Name: "agent", Controller: boolPtr(true)}}
+
+	result := Simulate([]v1.Pod{daemon, testPod("api-0", "api", "1", "1Gi")}, nodes, nil)
+	if len(result.Current.Unschedulable) != 0 {
+		t.Fatalf("unschedulable = %v, want none", result.Current.Unschedulable)
+	}
+	// node-1 only carries the DaemonSet pod, so it does not count
+	if result.Current.NodesNeeded != 1 {
+		t.Errorf("nodes needed = %d, want 1", result.Current.NodesNeeded)
+	}
+}
+
+func boolPtr(b bool) *bool {
+	return &b
+}
@@ -0,0 +1,250 @@
+package webhook
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+	v1 "k8s.io/api/core/v1"
+
+	"pod-limit-checker/pkg/analyzer"
+)
+
+// PolicyRules are the rules enforced by the validating webhook, for
+// translation into ValidatingAdmissionPolicies.
+type PolicyRules struct {
+	Ratio    analyzer.RatioPolicy
+	Bounds   analyzer.BoundsPolicy
+	DenyRisk string
+	WarnRisk string
+	// NamespaceLabels selects the namespaces the bindings apply to; empty
+	// binds every namespace
+	NamespaceLabels map[string]string
+}
+
+// containersExpression selects the containers of a Pod or of a workload's
+// pod template.
+const containersExpression = "object.kind == 'Pod' ? object.spec.containers : " +
+	"object.kind == 'CronJob' ? object.spec.jobTemplate.spec.template.spec.containers : " +
+	"object.spec.template.spec.containers"
+
+// celValidation is a CEL expression with the risk level the analyzer
+// assigns to containers that fail it.
+type celValidation struct {
+	Risk       string
+	Expression string
+	Message    string
+}
+
+// validations translates the rules into CEL, one expression per rule. The
+// expressions use the Kubernetes quantity library (Kubernetes 1.29+).
+func (rules PolicyRules) validations() []celValidation {
+	validations := []celValidation{
+		{
+			Risk:       "HIGH",
+			Expression: "variables.containers.all(c, has(c.resources) && has(c.resources.limits) && size(c.resources.limits) > 0)",
+			Message:    "every container must set resource limits",
+		},
+		{
+			Risk:       "MEDIUM",
+			Expression: "variables.containers.all(c, has(c.resources) && has(c.resources.limits) && 'cpu' in c.resources.limits && 'memory' in c.resources.limits)",
+			Message:    "every container must set CPU and memory limits",
+		},
+	}
+
+	ratio := func(name v1.ResourceName, max float64) {
+		if max <= 0 {
+			return
+		}
+		message := fmt.Sprintf("%s limit must be at most %gx the %s request", name, max, name)
+		if max == 1 {
+			message = fmt.Sprintf("%s limit must equal the %s request", name, name)
+		}
+		// As in the analyzer, a missing request defaults to the limit and a
+		// zero request has no meaningful ratio
+		validations = append(validations, celValidation{
+			Risk: "MEDIUM",
+			Expression: fmt.Sprintf("variables.containers.all(c, !(%s) || !(%s) || %s == 0.0 || %s <= %s * %s)",
+				hasResource("limits", name), hasResource("requests", name),
+				approximate("requests", name), approximate("limits", name), celDouble(max), approximate("requests", name)),
+			Message: message,
+		})
+	}
+	ratio(v1.ResourceCPU, rules.Ratio.MaxCPU)
+	ratio(v1.ResourceMemory, rules.Ratio.MaxMemory)
+
+	bound := func(name v1.ResourceName, list v1.ResourceList, operator, word string) {
+		value, ok := list[name]
+		if !ok {
+			return
+		}
+		var checks []string
+		for _, kind := range []string{"requests", "limits"} {
+			checks = append(checks, fmt.Sprintf("(!(%s) || quantity(c.resources.%s['%s']).compareTo(quantity('%s')) %s 0)",
+				hasResource(kind, name), kind, name, value.String(), operator))
+		}
+		validations = append(validations, celValidation{
+			Risk:       "MEDIUM",
+			Expression: fmt.Sprintf("variables.containers.all(c, %s)", strings.Join(checks, " && ")),
+			Message:    fmt.Sprintf("%s requests and limits must be at %s %s", name, word, value.String()),
+		})
+	}
+	for _, name := range []v1.ResourceName{v1.ResourceCPU, v1.ResourceMemory} {
+		bound(name, rules.Bounds.Min, ">=", "least")
+		bound(name, rules.Bounds.Max, "<=", "most")
+	}
+
+	return validations
+}
+
+func hasResource(kind string, name v1.ResourceName) string {
+	return fmt.Sprintf("has(c.resources) && has(c.resources.%s) && '%s' in c.resources.%s", kind, name, kind)
+}
+
+func approximate(kind string, name v1.ResourceName) string {
+	return fmt.Sprintf("quantity(c.resources.%s['%s']).asApproximateFloat()", kind, name)
+}
+
+// celDouble formats f as a CEL double literal; CEL does not convert ints.
+func celDouble(f float64) string {
+	s := strconv.FormatFloat(f, 'f', -1, 64)
+	if !strings.Contains(s, ".") {
+		s += ".0"
+	}
+	return s
+}
+
+type admissionPolicy struct {
+	APIVersion string `yaml:"apiVersion"`
+	Kind       string `yaml:"kind"`
+	Metadata   struct {
+		Name string `yaml:"name"`
+	} `yaml:"metadata"`
+	Spec struct {
+		FailurePolicy    string `yaml:"failurePolicy"`
+		MatchConstraints struct {
+			ResourceRules []resourceRule `yaml:"resourceRules"`
+		} `yaml:"matchConstraints"`
+		Variables   []policyVariable   `yaml:"variables"`
+		Validations []policyValidation `yaml:"validations"`
+	} `yaml:"spec"`
+}
+
+type policyVariable struct {
+	Name       string `yaml:"name"`
+	Expression string `yaml:"expression"`
+}
+
+type policyValidation struct {
+	Expression string `yaml:"expression"`
+	Message    string `yaml:"message"`
+}
+
+type resourceRule struct {
+	APIGroups   []string `yaml:"apiGroups"`
+	APIVersions []string `yaml:"apiVersions"`
+	Operations  []string `yaml:"operations"`
+	Resources   []string `yaml:"resources"`
+}
+
+type admissionPolicyBinding struct {
+	APIVersion string `yaml:"apiVersion"`
+	Kind       string `yaml:"kind"`
+	Metadata   struct {
+		Name string `yaml:"name"`
+	} `yaml:"metadata"`
+	Spec struct {
+		PolicyName        string   `yaml:"policyName"`
+		ValidationActions []string `yaml:"validationActions"`
+		MatchResources    struct {
+			NamespaceSelector struct {
+				MatchLabels map[string]string `yaml:"matchLabels,omitempty"`
+			} `yaml:"namespaceSelector"`
+		} `yaml:"matchResources"`
+	} `yaml:"spec"`
+}
+
+// policyResources are the objects the validating webhook checks.
+var policyResources = []resourceRule{
+	{APIGroups: []string{""}, APIVersions: []string{"v1"}, Resources: []string{"pods"}},
+	{APIGroups: []string{"apps"}, APIVersions: []string{"v1"}, Resources: []string{"deployments", "statefulsets", "daemonsets", "replicasets"}},
+	{APIGroups: []string{"batch"}, APIVersions: []string{"v1"}, Resources: []string{"jobs", "cronjobs"}},
+}
+
+// GenerateAdmissionPolicies renders the rules as ValidatingAdmissionPolicies
+// and bindings, as multi-document YAML, so they can be enforced without the
+// webhook. Rules at or above DenyRisk go into a policy bound with Deny, the
+// remaining rules at or above WarnRisk into one bound with Warn. apiVersion
+// is admissionregistration.k8s.io/v1 or v1beta1.
+func GenerateAdmissionPolicies(rules PolicyRules, apiVersion string) ([]byte, error) {
+	if apiVersion != "v1" && apiVersion != "v1beta1" {
+		return nil, fmt.Errorf("unsupported ValidatingAdmissionPolicy version %q (valid: v1, v1beta1)", apiVersion)
+	}
+
+	var deny, warn []celValidation
+	for _, validation := range rules.validations() {
+		rank := riskRank(validation.Risk)
+		switch {
+		case rules.DenyRisk != "" && rank >= riskRank(rules.DenyRisk):
+			deny = append(deny, validation)
+		case rules.WarnRisk != "" && rank >= riskRank(rules.WarnRisk):
+			warn = append(warn, validation)
+		}
+	}
+
+	var docs []string
+	for _, set := range []struct {
+		name        string
+		action      string
+		validations []celValidation
+	}{
+		{"pod-limit-checker-deny", "Deny", deny},
+		{"pod-limit-checker-warn", "Warn", warn},
+	} {
+		if len(set.validations) == 0 {
+			continue
+		}
+		policy, binding := newAdmissionPolicy(set.name, set.action, set.validations, rules.NamespaceLabels)
+		policy.APIVersion = "admissionregistration.k8s.io/" + apiVersion
+		binding.APIVersion = policy.APIVersion
+		for _, obj := range []interface{}{policy, binding} {
+			data, err := yaml.Marshal(obj)
+			if err != nil {
+				return nil, err
+			}
+			docs = append(docs, string(data))
+		}
+	}
+	return []byte(strings.Join(docs, "---\n")), nil
+}
+
+func newAdmissionPolicy(name, action string, validations []celValidation, namespaceLabels map[string]string) (admissionPolicy, admissionPolicyBinding) {
+	var policy admissionPolicy
+	policy.Kind = "ValidatingAdmissionPolicy"
+	policy.Metadata.Name = name
+	// Ignore keeps workloads deployable if an expression fails to evaluate
+	policy.Spec.FailurePolicy = "Ignore"
+	for _, rule := range policyResources {
+		rule.Operations = []string{"CREATE", "UPDATE"}
+		policy.Spec.MatchConstraints.ResourceRules = append(policy.Spec.MatchConstraints.ResourceRules, rule)
+	}
+	policy.Spec.Variables = []policyVariable{{Name: "containers", Expression: containersExpression}}
+
+	sort.SliceStable(validations, func(i, j int) bool {
+		return riskRank(validations[i].Risk) > riskRank(validations[j].Risk)
+	})
+	for _, validation := range validations {
+		policy.Spec.Validations = append(policy.Spec.Validations,
+			policyValidation{Expression: validation.Expression, Message: validation.Message})
+	}
+
+	var binding admissionPolicyBinding
+	binding.Kind = "ValidatingAdmissionPolicyBinding"
+	binding.Metadata.Name = name
+	binding.Spec.PolicyName = name
+	binding.Spec.ValidationActions = []string{action}
+	binding.Spec.MatchResources.NamespaceSelector.MatchLabels = namespaceLabels
+	return policy, binding
+}
@@ -0,0 +1,248 @@
+package webhook
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/google/cel-go/cel"
+	"gopkg.in/yaml.v2"
+	admissionv1 "k8s.io/api/admission/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/version"
+	"k8s.io/apiserver/pkg/cel/environment"
+
+	"pod-limit-checker/pkg/analyzer"
+)
+
+// compiledPolicy is a generated ValidatingAdmissionPolicy compiled with
+// the CEL environment of the API server.
+type compiledPolicy struct {
+	name        string
+	containers  cel.Program
+	validations []cel.Program
+	messages    []string
+}
+
+// compilePolicies generates the policies for rules and compiles their
+// expressions as a Kubernetes 1.29 API server would, with the quantity
+// library.
+func compilePolicies(t *testing.T, rules PolicyRules) []compiledPolicy {
+	t.Helper()
+	data, err := GenerateAdmissionPolicies(rules, "v1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	env, err := environment.MustBaseEnvSet(version.MajorMinor(1, 29)).NewExpressionsEnv().Extend(
+		cel.Variable("object", cel.DynType),
+		cel.Variable("variables", cel.MapType(cel.StringType, cel.DynType)),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	compile := func(expression string) cel.Program {
+		t.Helper()
+		ast, issues := env.Compile(expression)
+		if issues.Err() != nil {
+			t.Fatalf("compiling %s: %v", expression, issues.Err())
+		}
+		program, err := env.Program(ast)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return program
+	}
+
+	var policies []compiledPolicy
+	for _, doc := range strings.Split(string(data), "---\n") {
+		var policy admissionPolicy
+		if err := yaml.Unmarshal([]byte(doc), &policy); err != nil {
+			t.Fatal(err)
+		}
+		if policy.Kind != "ValidatingAdmissionPolicy" {
+			continue
+		}
+		compiled := compiledPolicy{name: policy.Metadata.Name}
+		for _, variable := range policy.Spec.Variables {
+			if variable.Name != "containers" {
+				t.Fatalf("unexpected variable %s", variable.Name)
+			}
+			compiled.containers = compile(variable.Expression)
+		}
+		for _, validation := range policy.Spec.Validations {
+			compiled.validations = append(compiled.validations, compile(validation.Expression))
+			compiled.messages = append(compiled.messages, validation.Message)
+		}
+		policies = append(policies, compiled)
+	}
+	return policies
+}
+
+// evaluate returns the messages of the validations obj fails, by policy.
+func evaluate(t *testing.T, policies []compiledPolicy, obj runtime.Object) map[string][]string {
+	t.Helper()
+	data, err := json.Marshal(obj)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var object map[string]interface{}
+	if err := json.Unmarshal(data, &object); err != nil {
+		t.Fatal(err)
+	}
+
+	failed := map[string][]string{}
+	for _, policy := range policies {
+		containers, _, err := policy.containers.Eval(map[string]interface{}{"object": object})
+		if err != nil {
+			t.Fatalf("%s: evaluating containers: %v", policy.name, err)
+		}
+		activation := map[string]interface{}{
+			"object":    object,
+			"variables": map[string]interface{}{"containers": containers},
+		}
+		for i, program := range policy.validations {
+			result, _, err := program.Eval(activation)
+			if err != nil {
+				// failurePolicy Ignore would let the object through unchecked
+				t.Fatalf("%s: evaluating %q: %v", policy.name, policy.messages[i], err)
+			}
+			if result.Value() != true {
+				failed[policy.name] = append(failed[policy.name], policy.messages[i])
+			}
+		}
+	}
+	return failed
+}
+
+func cronJob(containers ...v1.Container) *batchv1.CronJob {
+	return &batchv1.CronJob{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "batch/v1", Kind: "CronJob"},
+		ObjectMeta: metav1.ObjectMeta{Name: "report", Namespace: "batch"},
+		Spec: batchv1.CronJobSpec{
+			Schedule: "0 * * * *",
+			JobTemplate: batchv1.JobTemplateSpec{Spec: batchv1.JobSpec{Template: v1.PodTemplateSpec{
+				Spec: v1.PodSpec{Containers: containers, RestartPolicy: v1.RestartPolicyOnFailure},
+			}}},
+		},
+	}
+}
+
+// withRequests overrides a container's requests.
+func withRequests(c v1.Container, pairs ...string) v1.Container {
+	c.Resources.Requests = list(pairs...)
+	return c
+}
+
+func TestAdmissionPolicyExpressions(t *testing.T) {
+	rules := PolicyRules{
+		Ratio: analyzer.RatioPolicy{MaxCPU: 2, MaxMemory: 1},
+		Bounds: analyzer.BoundsPolicy{
+			Min: list("cpu", "50m"),
+			Max: list("memory", "2Gi"),
+		},
+		DenyRisk: "HIGH",
+		WarnRisk: "MEDIUM",
+	}
+	policies := compilePolicies(t, rules)
+	if len(policies) != 2 {
+		t.Fatalf("generated %d policies, want deny and warn", len(policies))
+	}
+
+	tests := []struct {
+		name string
+		obj  runtime.Object
+		deny []string
+		warn []string
+	}{
+		{
+			name: "compliant pod",
+			obj:  pod(container("app", "500m", "256Mi"), container("sidecar", "50m", "64Mi")),
+		},
+		{
+			name: "compliant deployment",
+			obj:  deployment(3, withRequests(container("app", "1", "1Gi"), "cpu", "500m", "memory", "1Gi")),
+		},
+		{
+			name: "compliant cronjob",
+			obj:  cronJob(container("job", "250m", "512Mi")),
+		},
+		{
+			name: "no limits",
+			obj:  pod(container("app", "500m", "256Mi"), container("proxy", "", "")),
+			deny: []string{"every container must set resource limits"},
+			warn: []string{"every container must set CPU and memory limits"},
+		},
+		{
+			name: "memory limit only",
+			obj:  deployment(1, container("app", "", "256Mi")),
+			warn: []string{"every container must set CPU and memory limits"},
+		},
+		{
+			name: "ratios exceeded",
+			obj:  cronJob(withRequests(container("job", "2", "1Gi"), "cpu", "500m", "memory", "512Mi")),
+			warn: []string{"cpu limit must be at most 2x the cpu request", "memory limit must equal the memory request"},
+		},
+		{
+			name: "missing request defaults to the limit",
+			obj:  pod(withRequests(container("app", "2", "1Gi"))),
+		},
+		{
+			name: "bounds exceeded",
+			obj:  pod(container("app", "10m", "4Gi")),
+			warn: []string{"cpu requests and limits must be at least 50m", "memory requests and limits must be at most 2Gi"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			failed := evaluate(t, policies, tt.obj)
+			for policy, want := range map[string][]string{"pod-limit-checker-deny": tt.deny, "pod-limit-checker-warn": tt.warn} {
+				got := failed[policy]
+				sort.Strings(got)
+				sort.Strings(want)
+				if strings.Join(got, "; ") != strings.Join(want, "; ") {
+					t.Errorf("%s failed %q, want %q", policy, got, want)
+				}
+			}
+		})
+	}
+}
+
+// TestAdmissionPolicyMatchesAnalyzer checks that the policies reject what
+// the webhook denies and warn about what it warns about.
+func TestAdmissionPolicyMatchesAnalyzer(t *testing.T) {
+	rules := PolicyRules{
+		Ratio:    analyzer.RatioPolicy{MaxCPU: 4},
+		Bounds:   analyzer.BoundsPolicy{Max: list("cpu", "2")},
+		DenyRisk: "HIGH",
+		WarnRisk: "MEDIUM",
+	}
+	policies := compilePolicies(t, rules)
+	validator := newValidator()
+	validator.Analyzer.SetRatioPolicy(rules.Ratio)
+	validator.Analyzer.SetBoundsPolicy(rules.Bounds)
+
+	for _, c := range []v1.Container{
+		container("app", "500m", "256Mi"),
+		container("app", "", ""),
+		container("app", "1", ""),
+		withRequests(container("app", "1", "256Mi"), "cpu", "100m", "memory", "256Mi"),
+		container("app", "4", "256Mi"),
+	} {
+		obj := pod(c)
+		failed := evaluate(t, policies, obj)
+		response := post(t, validator, admissionReview(t, admissionv1.Create, obj, nil))
+
+		if denied := !response.Allowed; denied != (len(failed["pod-limit-checker-deny"]) > 0) {
+			t.Errorf("%v: webhook denied %v, policy failed %q", c.Resources, denied, failed["pod-limit-checker-deny"])
+		}
+		// A denied container is not also warned about
+		if warned := len(response.Warnings) > 0; response.Allowed && warned != (len(failed["pod-limit-checker-warn"]) > 0) {
+			t.Errorf("%v: webhook warned %q, policy failed %q", c.Resources, response.Warnings, failed["pod-limit-checker-warn"])
+		}
+	}
+}
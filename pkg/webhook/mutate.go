@@ -0,0 +1,229 @@
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	"pod-limit-checker/pkg/analyzer"
+	"pod-limit-checker/pkg/reporter"
+)
+
+// MutatePath is where the mutating webhook is served.
+const MutatePath = "/mutate"
+
+// InjectedAnnotation records on the pod which resources were injected and
+// where they came from.
+const InjectedAnnotation = "pod-limit-checker.io/injected-resources"
+
+// Sources of injected values.
+const (
+	SourceRecommendation = "recommendation"
+	SourceDefault        = "default"
+)
+
+// Defaults are the policy values injected for missing CPU and memory
+// requests and limits. A resource absent from both lists is never injected.
+type Defaults struct {
+	Limits   v1.ResourceList
+	Requests v1.ResourceList
+}
+
+// Mutator is a MutatingAdmissionWebhook that fills in missing CPU and
+// memory requests and limits on pods. Values come from a previous run's
+// recommendation for the same workload and container when there is one,
+// and from Defaults otherwise. Values already set are never changed.
+type Mutator struct {
+	Defaults Defaults
+	// Recommendations by workloadKey/container
+	Recommendations map[string]reporter.Recommendation
+}
+
+// SetRecommendations indexes the recommendations of a previous report by
+// workload and container.
+func (m *Mutator) SetRecommendations(report *reporter.Report) {
+	m.Recommendations = map[string]reporter.Recommendation{}
+	for _, finding := range report.Findings {
+		if finding.Recommendation == nil || finding.WorkloadKind == "" {
+			continue
+		}
+		key := recommendationKey(finding.Namespace, finding.WorkloadKind, finding.WorkloadName, finding.Container)
+		m.Recommendations[key] = *finding.Recommendation
+	}
+}
+
+func recommendationKey(namespace, kind, name, container string) string {
+	return namespace + "/" + kind + "/" + name + "/" + container
+}
+
+func (m *Mutator) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	review, err := readReview(r)
+	if err != nil {
+		reviewError(w, err)
+		return
+	}
+	writeReview(w, review, m.Review(review.Request))
+}
+
+// Injection is what was added to one container.
+type Injection struct {
+	Source   string            `json:"source"`
+	Limits   map[string]string `json:"limits,omitempty"`
+	Requests map[string]string `json:"requests,omitempty"`
+}
+
+// patchOperation is one RFC 6902 JSON Patch operation.
+type patchOperation struct {
+	Op    string      `json:"op"`
+	Path  string      `json:"path"`
+	Value interface{} `json:"value,omitempty"`
+}
+
+// Review returns a JSON Patch adding the missing resources to a pod being
+// created. Other requests are allowed unchanged.
+func (m *Mutator) Review(req *admissionv1.AdmissionRequest) *admissionv1.AdmissionResponse {
+	response := &admissionv1.AdmissionResponse{Allowed: true}
+	if req.SubResource != "" || req.Operation != admissionv1.Create || req.Kind.Kind != "Pod" {
+		return response
+	}
+
+	var pod v1.Pod
+	if err := json.Unmarshal(req.Object.Raw, &pod); err != nil {
+		// Never block a pod because it could not be defaulted
+		response.Warnings = []string{fmt.Sprintf("pod-limit-checker: failed to decode pod: %v", err)}
+		return response
+	}
+	if pod.Namespace == "" {
+		pod.Namespace = req.Namespace
+	}
+	kind, name := analyzer.WorkloadOf(pod)
+
+	var patch []patchOperation
+	injected := map[string]Injection{}
+	for i, container := range pod.Spec.Containers {
+		source, limits, requests := SourceDefault, m.Defaults.Limits, m.Defaults.Requests
+		if rec, ok := m.Recommendations[recommendationKey(pod.Namespace, kind, name, container.Name)]; ok {
+			source, limits, requests = SourceRecommendation, resourceList(rec.Limits), resourceList(rec.Requests)
+		}
+
+		addLimits, addRequests := missing(container.Resources, limits, requests)
+		if len(addLimits) == 0 && len(addRequests) == 0 {
+			continue
+		}
+
+		path := fmt.Sprintf("/spec/containers/%d/resources", i)
+		if len(container.Resources.Limits) == 0 && len(container.Resources.Requests) == 0 {
+			resources := map[string]map[string]string{}
+			if len(addLimits) > 0 {
+				resources["limits"] = addLimits
+			}
+			if len(addRequests) > 0 {
+				resources["requests"] = addRequests
+			}
+			patch = append(patch, patchOperation{Op: "add", Path: path, Value: resources})
+		} else {
+			patch = append(patch, addValues(path+"/limits", container.Resources.Limits, addLimits)...)
+			patch = append(patch, addValues(path+"/requests", container.Resources.Requests, addRequests)...)
+		}
+		injected[container.Name] = Injection{Source: source, Limits: addLimits, Requests: addRequests}
+	}
+
+	if len(patch) == 0 {
+		return response
+	}
+
+	annotation, err := json.Marshal(injected)
+	if err != nil {
+		response.Warnings = []string{fmt.Sprintf("pod-limit-checker: %v", err)}
+		return response
+	}
+	if pod.Annotations == nil {
+		patch = append(patch, patchOperation{Op: "add", Path: "/metadata/annotations",
+			Value: map[string]string{InjectedAnnotation: string(annotation)}})
+	} else {
+		patch = append(patch, patchOperation{Op: "add", Path: "/metadata/annotations/" + escapePointer(InjectedAnnotation),
+			Value: string(annotation)})
+	}
+
+	data, err := json.Marshal(patch)
+	if err != nil {
+		response.Warnings = []string{fmt.Sprintf("pod-limit-checker: %v", err)}
+		return response
+	}
+	patchType := admissionv1.PatchTypeJSONPatch
+	response.Patch = data
+	response.PatchType = &patchType
+	return response
+}
+
+// missing returns the limits and requests to add to a container: the CPU
+// and memory values it lacks. An added request never exceeds the limit
+// and an added limit is never below the request, so the result is valid.
+func missing(current v1.ResourceRequirements, limits, requests v1.ResourceList) (addLimits, addRequests map[string]string) {
+	addLimits, addRequests = map[string]string{}, map[string]string{}
+	for _, name := range []v1.ResourceName{v1.ResourceCPU, v1.ResourceMemory} {
+		limit, hasLimit := current.Limits[name]
+		request, hasRequest := current.Requests[name]
+
+		if newLimit, ok := limits[name]; ok && !hasLimit {
+			if hasRequest && newLimit.Cmp(request) < 0 {
+				newLimit = request
+			}
+			addLimits[string(name)] = newLimit.String()
+			limit, hasLimit = newLimit, true
+		}
+		if newRequest, ok := requests[name]; ok && !hasRequest {
+			if hasLimit && newRequest.Cmp(limit) > 0 {
+				newRequest = limit
+			}
+			addRequests[string(name)] = newRequest.String()
+		}
+	}
+	return addLimits, addRequests
+}
+
+// addValues patches individual entries into a resource list, or the whole
+// list when the container has none.
+func addValues(path string, current v1.ResourceList, values map[string]string) []patchOperation {
+	if len(values) == 0 {
+		return nil
+	}
+	if len(current) == 0 {
+		return []patchOperation{{Op: "add", Path: path, Value: values}}
+	}
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	ops := make([]patchOperation, 0, len(names))
+	for _, name := range names {
+		ops = append(ops, patchOperation{Op: "add", Path: path + "/" + escapePointer(name), Value: values[name]})
+	}
+	return ops
+}
+
+// resourceList parses the CPU and memory of a report's resource values,
+// skipping values that do not parse.
+func resourceList(values reporter.ResourceValues) v1.ResourceList {
+	list := v1.ResourceList{}
+	if q, err := resource.ParseQuantity(values.CPU); err == nil {
+		list[v1.ResourceCPU] = q
+	}
+	if q, err := resource.ParseQuantity(values.Memory); err == nil {
+		list[v1.ResourceMemory] = q
+	}
+	return list
+}
+
+// escapePointer escapes a key for use in a JSON Pointer (RFC 6901).
+func escapePointer(key string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(key)
+}
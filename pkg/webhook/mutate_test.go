@@ -0,0 +1,227 @@
+package webhook
+
+import (
+	"encoding/json"
+	"testing"
+
+	jsonpatch "github.com/evanphx/json-patch"
+	admissionv1 "k8s.io/api/admission/v1"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"pod-limit-checker/pkg/reporter"
+)
+
+// list builds a resource list from name, value pairs.
+func list(pairs ...string) v1.ResourceList {
+	l := v1.ResourceList{}
+	for i := 0; i+1 < len(pairs); i += 2 {
+		l[v1.ResourceName(pairs[i])] = resource.MustParse(pairs[i+1])
+	}
+	return l
+}
+
+func newMutator() *Mutator {
+	return &Mutator{Defaults: Defaults{
+		Limits:   list("cpu", "500m", "memory", "512Mi"),
+		Requests: list("cpu", "100m", "memory", "128Mi"),
+	}}
+}
+
+// mutate reviews the creation of p and returns the pod with the response's
+// patch applied.
+func mutate(t *testing.T, m *Mutator, p *v1.Pod) (*v1.Pod, *admissionv1.AdmissionResponse) {
+	t.Helper()
+	original, err := json.Marshal(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	response := m.Review(&admissionv1.AdmissionRequest{
+		Kind:      metav1.GroupVersionKind{Version: "v1", Kind: "Pod"},
+		Namespace: "web",
+		Operation: admissionv1.Create,
+		Object:    runtime.RawExtension{Raw: original},
+	})
+	if !response.Allowed {
+		t.Fatalf("mutation denied: %+v", response.Result)
+	}
+	if response.Patch == nil {
+		return p, response
+	}
+	if response.PatchType == nil || *response.PatchType != admissionv1.PatchTypeJSONPatch {
+		t.Fatalf("patch type = %v, want JSONPatch", response.PatchType)
+	}
+
+	patch, err := jsonpatch.DecodePatch(response.Patch)
+	if err != nil {
+		t.Fatalf("invalid JSON Patch %s: %v", response.Patch, err)
+	}
+	patched, err := patch.Apply(original)
+	if err != nil {
+		t.Fatalf("applying %s: %v", response.Patch, err)
+	}
+	var out v1.Pod
+	if err := json.Unmarshal(patched, &out); err != nil {
+		t.Fatal(err)
+	}
+	return &out, response
+}
+
+// assertResources compares a resource list with name, value pairs.
+func assertResources(t *testing.T, what string, got v1.ResourceList, want ...string) {
+	t.Helper()
+	expected := list(want...)
+	if len(got) != len(expected) {
+		t.Errorf("%s = %v, want %v", what, got, expected)
+		return
+	}
+	for name, q := range expected {
+		if value, ok := got[name]; !ok || value.Cmp(q) != 0 || value.String() != q.String() {
+			t.Errorf("%s %s = %s, want %s", what, name, value.String(), q.String())
+		}
+	}
+}
+
+func TestMutatorPatch(t *testing.T) {
+	tests := []struct {
+		name          string
+		resources     v1.ResourceRequirements
+		annotations   map[string]string
+		wantLimits    []string
+		wantRequests  []string
+		wantInjection Injection
+	}{
+		{
+			name:         "no resources",
+			wantLimits:   []string{"cpu", "500m", "memory", "512Mi"},
+			wantRequests: []string{"cpu", "100m", "memory", "128Mi"},
+			wantInjection: Injection{Source: SourceDefault,
+				Limits:   map[string]string{"cpu": "500m", "memory": "512Mi"},
+				Requests: map[string]string{"cpu": "100m", "memory": "128Mi"}},
+		},
+		{
+			name: "partial resources",
+			resources: v1.ResourceRequirements{
+				Limits:   list("cpu", "2"),
+				Requests: list("memory", "1Gi", "nvidia.com/gpu", "1"),
+			},
+			annotations: map[string]string{"team": "web"},
+			// The memory limit is raised to the request already set
+			wantLimits:   []string{"cpu", "2", "memory", "1Gi"},
+			wantRequests: []string{"cpu", "100m", "memory", "1Gi", "nvidia.com/gpu", "1"},
+			wantInjection: Injection{Source: SourceDefault,
+				Limits:   map[string]string{"memory": "1Gi"},
+				Requests: map[string]string{"cpu": "100m"}},
+		},
+		{
+			name:      "limits only",
+			resources: v1.ResourceRequirements{Limits: list("cpu", "50m", "memory", "1Gi")},
+			// The CPU request is lowered to the limit already set
+			wantLimits:   []string{"cpu", "50m", "memory", "1Gi"},
+			wantRequests: []string{"cpu", "50m", "memory", "128Mi"},
+			wantInjection: Injection{Source: SourceDefault,
+				Requests: map[string]string{"cpu": "50m", "memory": "128Mi"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := v1.Container{Name: "app", Image: "example/app", Resources: tt.resources}
+			p := pod(c, container("sidecar", "100m", "64Mi"))
+			p.Annotations = tt.annotations
+
+			out, _ := mutate(t, newMutator(), p)
+
+			app := out.Spec.Containers[0]
+			assertResources(t, "app limits", app.Resources.Limits, tt.wantLimits...)
+			assertResources(t, "app requests", app.Resources.Requests, tt.wantRequests...)
+			// A complete container is left untouched
+			sidecar := out.Spec.Containers[1]
+			assertResources(t, "sidecar limits", sidecar.Resources.Limits, "cpu", "100m", "memory", "64Mi")
+			assertResources(t, "sidecar requests", sidecar.Resources.Requests, "cpu", "100m", "memory", "64Mi")
+
+			for key, value := range tt.annotations {
+				if out.Annotations[key] != value {
+					t.Errorf("annotation %s = %q, want %q", key, out.Annotations[key], value)
+				}
+			}
+			var injected map[string]Injection
+			if err := json.Unmarshal([]byte(out.Annotations[InjectedAnnotation]), &injected); err != nil {
+				t.Fatalf("annotation %s: %v", InjectedAnnotation, err)
+			}
+			if len(injected) != 1 {
+				t.Errorf("injected into %v, want app only", injected)
+			}
+			got, _ := json.Marshal(injected["app"])
+			want, _ := json.Marshal(tt.wantInjection)
+			if string(got) != string(want) {
+				t.Errorf("injection = %s, want %s", got, want)
+			}
+		})
+	}
+}
+
+func TestMutatorRecommendation(t *testing.T) {
+	m := newMutator()
+	m.SetRecommendations(&reporter.Report{Findings: []reporter.Finding{{
+		Namespace:    "web",
+		WorkloadKind: "Deployment",
+		WorkloadName: "api",
+		Container:    "app",
+		Recommendation: &reporter.Recommendation{
+			Limits:   reporter.ResourceValues{CPU: "750m", Memory: "768Mi"},
+			Requests: reporter.ResourceValues{CPU: "250m", Memory: "384Mi"},
+		},
+	}}})
+
+	controller := true
+	p := pod(v1.Container{Name: "app", Image: "example/app"}, v1.Container{Name: "proxy", Image: "example/proxy"})
+	p.Labels = map[string]string{"pod-template-hash": "6d4f8b9c7"}
+	p.OwnerReferences = []metav1.OwnerReference{{APIVersion: "apps/v1", Kind: "ReplicaSet", Name: "api-6d4f8b9c7", Controller: &controller}}
+
+	out, _ := mutate(t, m, p)
+	assertResources(t, "app limits", out.Spec.Containers[0].Resources.Limits, "cpu", "750m", "memory", "768Mi")
+	assertResources(t, "app requests", out.Spec.Containers[0].Resources.Requests, "cpu", "250m", "memory", "384Mi")
+	// Containers without a recommendation fall back to the defaults
+	assertResources(t, "proxy limits", out.Spec.Containers[1].Resources.Limits, "cpu", "500m", "memory", "512Mi")
+
+	var injected map[string]Injection
+	if err := json.Unmarshal([]byte(out.Annotations[InjectedAnnotation]), &injected); err != nil {
+		t.Fatal(err)
+	}
+	if injected["app"].Source != SourceRecommendation || injected["proxy"].Source != SourceDefault {
+		t.Errorf("sources = %s, %s, want %s, %s", injected["app"].Source, injected["proxy"].Source, SourceRecommendation, SourceDefault)
+	}
+}
+
+func TestMutatorNoPatch(t *testing.T) {
+	complete := pod(container("app", "500m", "256Mi"))
+	if _, response := mutate(t, newMutator(), complete); response.Patch != nil {
+		t.Errorf("patched a complete pod: %s", response.Patch)
+	}
+
+	// Only pod creation is mutated
+	data, err := json.Marshal(pod(v1.Container{Name: "app"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	response := newMutator().Review(&admissionv1.AdmissionRequest{
+		Kind:      metav1.GroupVersionKind{Version: "v1", Kind: "Pod"},
+		Operation: admissionv1.Update,
+		Object:    runtime.RawExtension{Raw: data},
+	})
+	if !response.Allowed || response.Patch != nil {
+		t.Errorf("update response = %+v, want allowed without a patch", response)
+	}
+
+	// A pod that does not decode is allowed with a warning
+	response = newMutator().Review(&admissionv1.AdmissionRequest{
+		Kind:      metav1.GroupVersionKind{Version: "v1", Kind: "Pod"},
+		Operation: admissionv1.Create,
+		Object:    runtime.RawExtension{Raw: []byte(`{"spec":{"containers":"app"}}`)},
+	})
+	if !response.Allowed || response.Patch != nil || len(response.Warnings) != 1 {
+		t.Errorf("malformed pod response = %+v, want allowed with a warning", response)
+	}
+}
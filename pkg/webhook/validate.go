@@ -0,0 +1,147 @@
+package webhook
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"pod-limit-checker/pkg/analyzer"
+)
+
+// ValidatePath is where the validating webhook is served.
+const ValidatePath = "/validate"
+
+// Validator is a ValidatingAdmissionWebhook that runs the analyzer's rules
+// on incoming pods and workload templates. Containers at or above DenyRisk
+// reject the object; those at or above WarnRisk add admission warnings.
+// An empty level disables that action.
+type Validator struct {
+	Analyzer *analyzer.PodAnalyzer
+	DenyRisk string
+	WarnRisk string
+}
+
+func (v *Validator) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	review, err := readReview(r)
+	if err != nil {
+		reviewError(w, err)
+		return
+	}
+	writeReview(w, review, v.Review(review.Request))
+}
+
+// Review decides an admission request. Only creates and updates of objects
+// with a pod template are checked; everything else is allowed. An update
+// is only denied for containers whose resources it adds or changes, so
+// objects admitted before the policy can still be scaled or relabelled;
+// their findings are returned as warnings instead.
+func (v *Validator) Review(req *admissionv1.AdmissionRequest) *admissionv1.AdmissionResponse {
+	allowed := &admissionv1.AdmissionResponse{Allowed: true}
+	if req.SubResource != "" || req.Operation != admissionv1.Create && req.Operation != admissionv1.Update {
+		return allowed
+	}
+
+	pod, ok, err := podOf(req)
+	if err != nil {
+		return &admissionv1.AdmissionResponse{
+			Result: &metav1.Status{
+				Status:  metav1.StatusFailure,
+				Code:    http.StatusBadRequest,
+				Reason:  metav1.StatusReasonBadRequest,
+				Message: fmt.Sprintf("pod-limit-checker: failed to decode %s: %v", req.Kind.Kind, err),
+			},
+		}
+	}
+	if !ok {
+		return allowed
+	}
+
+	var changed map[string]bool
+	if req.Operation == admissionv1.Update {
+		if changed, err = changedContainers(req, pod); err != nil {
+			return &admissionv1.AdmissionResponse{
+				Result: &metav1.Status{
+					Status:  metav1.StatusFailure,
+					Code:    http.StatusBadRequest,
+					Reason:  metav1.StatusReasonBadRequest,
+					Message: fmt.Sprintf("pod-limit-checker: failed to decode old %s: %v", req.Kind.Kind, err),
+				},
+			}
+		}
+	}
+
+	// Admission happens before the pod runs, so there is no usage and the
+	// usage threshold does not matter
+	results := v.Analyzer.AnalyzePods([]v1.Pod{pod}, nil, 0)
+
+	var denials []string
+	for _, result := range results {
+		rank := riskRank(result.RiskLevel)
+		message := fmt.Sprintf("container %s (%s risk): %s", result.ContainerName, result.RiskLevel, strings.Join(reasons(result), "; "))
+		deny := v.DenyRisk != "" && rank >= riskRank(v.DenyRisk)
+		switch {
+		case deny && (changed == nil || changed[result.ContainerName]):
+			denials = append(denials, message)
+		case deny || v.WarnRisk != "" && rank >= riskRank(v.WarnRisk):
+			allowed.Warnings = append(allowed.Warnings, message)
+		}
+	}
+
+	if len(denials) == 0 {
+		return allowed
+	}
+	return &admissionv1.AdmissionResponse{
+		Allowed:  false,
+		Warnings: allowed.Warnings,
+		Result: &metav1.Status{
+			Status:  metav1.StatusFailure,
+			Code:    http.StatusForbidden,
+			Reason:  metav1.StatusReasonForbidden,
+			Message: fmt.Sprintf("pod-limit-checker denied %s %s: %s", req.Kind.Kind, pod.Name, strings.Join(denials, " | ")),
+		},
+	}
+}
+
+// changedContainers returns the containers of an update whose resources
+// differ from the old object's, including containers the update adds.
+func changedContainers(req *admissionv1.AdmissionRequest, pod v1.Pod) (map[string]bool, error) {
+	oldReq := *req
+	oldReq.Object = req.OldObject
+	old, _, err := podOf(&oldReq)
+	if err != nil {
+		return nil, err
+	}
+	oldResources := map[string]v1.ResourceRequirements{}
+	for _, container := range append(old.Spec.InitContainers, old.Spec.Containers...) {
+		oldResources[container.Name] = container.Resources
+	}
+
+	changed := map[string]bool{}
+	for _, container := range append(pod.Spec.InitContainers, pod.Spec.Containers...) {
+		previous, ok := oldResources[container.Name]
+		if !ok || !equality.Semantic.DeepEqual(previous, container.Resources) {
+			changed[container.Name] = true
+		}
+	}
+	return changed, nil
+}
+
+// reasons returns the suggestions explaining a container's risk. The
+// analyzer rates a partial set of limits MEDIUM without a suggestion, so
+// the missing limits are named here.
+func reasons(result analyzer.PodAnalysis) []string {
+	reasons := result.Suggestions
+	if result.HasLimits {
+		for _, name := range []v1.ResourceName{v1.ResourceCPU, v1.ResourceMemory} {
+			if _, ok := result.CurrentLimits[name]; !ok {
+				reasons = append(reasons, fmt.Sprintf("❌ No %s limit set", name))
+			}
+		}
+	}
+	return reasons
+}
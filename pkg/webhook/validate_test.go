@@ -0,0 +1,263 @@
+package webhook
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"pod-limit-checker/pkg/analyzer"
+	"pod-limit-checker/pkg/kubernetes"
+)
+
+// container returns a container with the given CPU and memory limits and
+// matching requests; "" leaves a resource unset.
+func container(name, cpu, memory string) v1.Container {
+	c := v1.Container{Name: name, Image: "example/" + name}
+	for resourceName, value := range map[v1.ResourceName]string{v1.ResourceCPU: cpu, v1.ResourceMemory: memory} {
+		if value == "" {
+			continue
+		}
+		if c.Resources.Limits == nil {
+			c.Resources.Limits, c.Resources.Requests = v1.ResourceList{}, v1.ResourceList{}
+		}
+		c.Resources.Limits[resourceName] = resource.MustParse(value)
+		c.Resources.Requests[resourceName] = resource.MustParse(value)
+	}
+	return c
+}
+
+func pod(containers ...v1.Container) *v1.Pod {
+	return &v1.Pod{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Pod"},
+		ObjectMeta: metav1.ObjectMeta{Name: "api", Namespace: "web"},
+		Spec:       v1.PodSpec{Containers: containers},
+	}
+}
+
+func deployment(replicas int32, containers ...v1.Container) *appsv1.Deployment {
+	return &appsv1.Deployment{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "apps/v1", Kind: "Deployment"},
+		ObjectMeta: metav1.ObjectMeta{Name: "api", Namespace: "web"},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: &replicas,
+			Template: v1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"app": "api"}},
+				Spec:       v1.PodSpec{Containers: containers},
+			},
+		},
+	}
+}
+
+// admissionReview encodes an AdmissionReview for obj, and for old on
+// updates.
+func admissionReview(t *testing.T, operation admissionv1.Operation, obj, old runtime.Object) []byte {
+	t.Helper()
+	raw := func(obj runtime.Object) runtime.RawExtension {
+		if obj == nil {
+			return runtime.RawExtension{}
+		}
+		data, err := json.Marshal(obj)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return runtime.RawExtension{Raw: data}
+	}
+	gvk := obj.GetObjectKind().GroupVersionKind()
+	review := admissionv1.AdmissionReview{
+		TypeMeta: metav1.TypeMeta{APIVersion: "admission.k8s.io/v1", Kind: "AdmissionReview"},
+		Request: &admissionv1.AdmissionRequest{
+			UID:       "0f6a1d7e-3c1b-4a39-9d55-2b3c6c1e8f00",
+			Kind:      metav1.GroupVersionKind{Group: gvk.Group, Version: gvk.Version, Kind: gvk.Kind},
+			Namespace: "web",
+			Operation: operation,
+			Object:    raw(obj),
+			OldObject: raw(old),
+		},
+	}
+	data, err := json.Marshal(review)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+// post sends body to a TLS test server and decodes the AdmissionReview it
+// returns.
+func post(t *testing.T, handler http.Handler, body []byte) *admissionv1.AdmissionResponse {
+	t.Helper()
+	server := httptest.NewTLSServer(handler)
+	defer server.Close()
+
+	resp, err := server.Client().Post(server.URL+ValidatePath, "application/json", bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %s, want 200", resp.Status)
+	}
+	var review admissionv1.AdmissionReview
+	if err := json.NewDecoder(resp.Body).Decode(&review); err != nil {
+		t.Fatal(err)
+	}
+	if review.Response == nil {
+		t.Fatal("AdmissionReview has no response")
+	}
+	if review.Response.UID != "0f6a1d7e-3c1b-4a39-9d55-2b3c6c1e8f00" {
+		t.Errorf("response UID = %q, want the request's", review.Response.UID)
+	}
+	return review.Response
+}
+
+func newValidator() *Validator {
+	return &Validator{
+		Analyzer: analyzer.NewPodAnalyzer(&kubernetes.Client{Clientset: fake.NewSimpleClientset()}),
+		DenyRisk: "HIGH",
+		WarnRisk: "MEDIUM",
+	}
+}
+
+func TestValidator(t *testing.T) {
+	tests := []struct {
+		name      string
+		operation admissionv1.Operation
+		obj, old  runtime.Object
+		allowed   bool
+		// denied and warned are containers named in the denial message
+		// and the warnings
+		denied []string
+		warned []string
+	}{
+		{
+			name:      "create with limits",
+			operation: admissionv1.Create,
+			obj:       pod(container("app", "500m", "256Mi")),
+			allowed:   true,
+		},
+		{
+			name:      "create without limits",
+			operation: admissionv1.Create,
+			obj:       pod(container("app", "500m", "256Mi"), container("proxy", "", "")),
+			denied:    []string{"proxy"},
+		},
+		{
+			name:      "create of a workload template",
+			operation: admissionv1.Create,
+			obj:       deployment(2, container("app", "", "")),
+			denied:    []string{"app"},
+		},
+		{
+			name:      "partial limits warn",
+			operation: admissionv1.Create,
+			obj:       pod(container("app", "", "256Mi")),
+			allowed:   true,
+			warned:    []string{"app"},
+		},
+		{
+			name:      "scaling a legacy workload",
+			operation: admissionv1.Update,
+			obj:       deployment(5, container("app", "", "")),
+			old:       deployment(2, container("app", "", "")),
+			allowed:   true,
+			warned:    []string{"app"},
+		},
+		{
+			name:      "update adding a container without limits",
+			operation: admissionv1.Update,
+			obj:       deployment(2, container("app", "", ""), container("proxy", "", "")),
+			old:       deployment(2, container("app", "", "")),
+			denied:    []string{"proxy"},
+			warned:    []string{"app"},
+		},
+		{
+			name:      "update removing limits",
+			operation: admissionv1.Update,
+			obj:       deployment(2, container("app", "", "")),
+			old:       deployment(2, container("app", "500m", "256Mi")),
+			denied:    []string{"app"},
+		},
+		{
+			name:      "delete",
+			operation: admissionv1.Delete,
+			obj:       pod(container("app", "", "")),
+			allowed:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response := post(t, newValidator(), admissionReview(t, tt.operation, tt.obj, tt.old))
+
+			if response.Allowed != tt.allowed {
+				t.Fatalf("allowed = %v, want %v (result %+v)", response.Allowed, tt.allowed, response.Result)
+			}
+			if tt.allowed {
+				if response.Result != nil && response.Result.Code >= 400 {
+					t.Errorf("allowed with result %+v", response.Result)
+				}
+			} else {
+				if response.Result == nil || response.Result.Code != http.StatusForbidden {
+					t.Fatalf("result = %+v, want 403", response.Result)
+				}
+				for _, name := range tt.denied {
+					if !strings.Contains(response.Result.Message, "container "+name+" ") {
+						t.Errorf("denial %q does not name container %s", response.Result.Message, name)
+					}
+				}
+			}
+			if len(response.Warnings) != len(tt.warned) {
+				t.Errorf("warnings = %q, want %d", response.Warnings, len(tt.warned))
+			}
+			for i, name := range tt.warned {
+				if i < len(response.Warnings) && !strings.HasPrefix(response.Warnings[i], "container "+name+" ") {
+					t.Errorf("warning %q does not name container %s", response.Warnings[i], name)
+				}
+			}
+		})
+	}
+}
+
+func TestValidatorMalformedReview(t *testing.T) {
+	server := httptest.NewTLSServer(newValidator())
+	defer server.Close()
+
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+	}{
+		{"not JSON", "application/json", "{"},
+		{"no request", "application/json", `{"apiVersion":"admission.k8s.io/v1","kind":"AdmissionReview"}`},
+		{"wrong content type", "text/plain", "{}"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := server.Client().Post(server.URL+ValidatePath, tt.contentType, strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusBadRequest {
+				t.Errorf("status = %s, want 400", resp.Status)
+			}
+		})
+	}
+
+	// A review whose object does not decode is answered, not dropped
+	body := []byte(`{"apiVersion":"admission.k8s.io/v1","kind":"AdmissionReview","request":{"uid":"0f6a1d7e-3c1b-4a39-9d55-2b3c6c1e8f00",` +
+		`"kind":{"group":"","version":"v1","kind":"Pod"},"operation":"CREATE","object":{"spec":{"containers":"app"}}}}`)
+	response := post(t, newValidator(), body)
+	if response.Allowed || response.Result == nil || response.Result.Code != http.StatusBadRequest {
+		t.Errorf("response = %+v, want a 400 result", response)
+	}
+}
@@ -0,0 +1,156 @@
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// maxReviewBytes bounds the AdmissionReview body; the API server limits
+// objects to 3MiB.
+const maxReviewBytes = 3 * 1024 * 1024
+
+// riskRank orders the analyzer's risk levels; unknown levels rank 0.
+func riskRank(level string) int {
+	switch level {
+	case "LOW":
+		return 1
+	case "MEDIUM":
+		return 2
+	case "HIGH":
+		return 3
+	}
+	return 0
+}
+
+// ParseRisk validates a risk level for a policy flag. "" and "NONE"
+// disable the action.
+func ParseRisk(s string) (string, error) {
+	level := strings.ToUpper(s)
+	if level == "" || level == "NONE" {
+		return "", nil
+	}
+	if riskRank(level) == 0 {
+		return "", fmt.Errorf("unknown risk level %q (valid: LOW, MEDIUM, HIGH, NONE)", s)
+	}
+	return level, nil
+}
+
+// readReview decodes an AdmissionReview request body.
+func readReview(r *http.Request) (*admissionv1.AdmissionReview, error) {
+	if r.Method != http.MethodPost {
+		return nil, fmt.Errorf("method %s not allowed", r.Method)
+	}
+	if contentType := r.Header.Get("Content-Type"); !strings.HasPrefix(contentType, "application/json") {
+		return nil, fmt.Errorf("unsupported content type %q", contentType)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxReviewBytes))
+	if err != nil {
+		return nil, err
+	}
+	var review admissionv1.AdmissionReview
+	if err := json.Unmarshal(body, &review); err != nil {
+		return nil, fmt.Errorf("failed to decode AdmissionReview: %v", err)
+	}
+	if review.Request == nil {
+		return nil, fmt.Errorf("AdmissionReview has no request")
+	}
+	return &review, nil
+}
+
+// writeReview sends the response for a review, echoing its UID.
+func writeReview(w http.ResponseWriter, review *admissionv1.AdmissionReview, response *admissionv1.AdmissionResponse) {
+	response.UID = review.Request.UID
+	out := admissionv1.AdmissionReview{
+		TypeMeta: metav1.TypeMeta{APIVersion: "admission.k8s.io/v1", Kind: "AdmissionReview"},
+		Response: response,
+	}
+	data, err := json.Marshal(out)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(data)
+}
+
+// reviewError rejects a malformed request before it becomes a review.
+func reviewError(w http.ResponseWriter, err error) {
+	http.Error(w, err.Error(), http.StatusBadRequest)
+}
+
+// podOf returns the pod described by an admission request: the pod itself,
+// or a pod built from a workload's template that carries a controller
+// reference to the workload, so the analyzer attributes it correctly. ok is
+// false for kinds without a pod template.
+func podOf(req *admissionv1.AdmissionRequest) (pod v1.Pod, ok bool, err error) {
+	raw := req.Object.Raw
+	var meta metav1.ObjectMeta
+	var template v1.PodTemplateSpec
+
+	switch req.Kind.Kind {
+	case "Pod":
+		if err := json.Unmarshal(raw, &pod); err != nil {
+			return pod, false, err
+		}
+		if pod.Namespace == "" {
+			pod.Namespace = req.Namespace
+		}
+		if pod.Name == "" {
+			pod.Name = pod.GenerateName
+		}
+		return pod, true, nil
+	case "Deployment", "StatefulSet", "DaemonSet", "ReplicaSet":
+		// The pod template is at spec.template in every apps/v1 workload
+		var obj appsv1.Deployment
+		if err := json.Unmarshal(raw, &obj); err != nil {
+			return pod, false, err
+		}
+		meta, template = obj.ObjectMeta, obj.Spec.Template
+	case "Job":
+		var obj batchv1.Job
+		if err := json.Unmarshal(raw, &obj); err != nil {
+			return pod, false, err
+		}
+		meta, template = obj.ObjectMeta, obj.Spec.Template
+	case "CronJob":
+		var obj batchv1.CronJob
+		if err := json.Unmarshal(raw, &obj); err != nil {
+			return pod, false, err
+		}
+		meta, template = obj.ObjectMeta, obj.Spec.JobTemplate.Spec.Template
+	default:
+		return pod, false, nil
+	}
+
+	name := meta.Name
+	if name == "" {
+		name = meta.GenerateName
+	}
+	controller := true
+	pod = v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        name,
+			Namespace:   req.Namespace,
+			Labels:      template.Labels,
+			Annotations: template.Annotations,
+			OwnerReferences: []metav1.OwnerReference{{
+				APIVersion: req.Kind.Group + "/" + req.Kind.Version,
+				Kind:       req.Kind.Kind,
+				Name:       name,
+				Controller: &controller,
+			}},
+		},
+		Spec: template.Spec,
+	}
+	return pod, true, nil
+}